@@ -0,0 +1,234 @@
+// Package main implements the Terminal.FM client wrapper, which plays the
+// streams chosen in a remote Terminal.FM session on the local machine.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/creack/pty"
+	"github.com/fulgidus/terminal-fm/internal/config"
+	"github.com/fulgidus/terminal-fm/pkg/client"
+	"github.com/fulgidus/terminal-fm/pkg/protocol"
+	"github.com/fulgidus/terminal-fm/pkg/services/player"
+	"golang.org/x/term"
+)
+
+var (
+	configPath = flag.String("config", "", "Path to the config file (default ~/.terminal-fm/config.yaml)")
+	overrides  = config.BindFlags(flag.CommandLine)
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] command [args...]\n\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Runs command, usually ssh to a Terminal.FM server, and plays its stations locally.\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Example: %s ssh -p 2222 terminal.fm\n\nFlags:\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load configuration: file < TERMINAL_FM_* environment < flags
+	path, required := *configPath, true
+	if path == "" {
+		path, required = config.DefaultPath(), false
+	}
+
+	cfg, err := config.Load(path, required, os.LookupEnv, overrides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize audio player
+	var audioPlayer player.Player
+	if cfg.Player.DefaultPlayer == "mpv" {
+		audioPlayer = player.NewMpvPlayer(cfg.Player.MpvPath)
+	} else {
+		ffplay := player.NewFFplayPlayer(cfg.Player.FFplayPath)
+		ffplay.SetMaxRetries(cfg.Player.MaxRetries)
+		audioPlayer = ffplay
+	}
+
+	// Servers that stream the audio themselves are decoded with ffplay
+	receiver := client.NewReceiver(ffplayDecoder(cfg.Player.FFplayPath),
+		protocol.CodecOpus, protocol.CodecMP3, protocol.CodecPCM)
+
+	// Playback errors are reported after the session ends so they don't
+	// garble the remote TUI
+	var playErrs []error
+	var playMu sync.Mutex
+	onError := func(err error) {
+		playMu.Lock()
+		playErrs = append(playErrs, err)
+		playMu.Unlock()
+	}
+
+	code, err := run(flag.Args(), audioPlayer, receiver, onError)
+
+	receiver.Stop()
+	_ = audioPlayer.Stop()
+	if cleaner, ok := audioPlayer.(interface{ Cleanup() error }); ok {
+		_ = cleaner.Cleanup()
+	}
+
+	playMu.Lock()
+	for _, playErr := range playErrs {
+		fmt.Fprintf(os.Stderr, "Playback error: %v\n", playErr)
+	}
+	playMu.Unlock()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
+
+// run starts args in a pseudo-terminal connected to ours, carrying out the
+// player commands in its output on audioPlayer, playing any audio streamed
+// through receiver, and returns its exit code.
+func run(args []string, audioPlayer player.Player, receiver *client.Receiver, onError func(error)) (int, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+	defer ptmx.Close()
+
+	// Replies to the server share the child's input with our keys
+	input := &syncWriter{w: ptmx}
+
+	controller := client.NewController(audioPlayer, input)
+	controller.StreamTo(receiver)
+	stopReports := make(chan struct{})
+	defer close(stopReports)
+	go controller.ReportEvents(audioPlayer.Events(), stopReports)
+
+	// Keep the child's terminal size in sync with ours
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer func() {
+		signal.Stop(resize)
+		close(resize)
+	}()
+	go func() {
+		for range resize {
+			_ = pty.InheritSize(os.Stdin, ptmx)
+		}
+	}()
+	resize <- syscall.SIGWINCH
+
+	// Pass keys through untouched; the child's terminal does the processing
+	stdin := int(os.Stdin.Fd())
+	if term.IsTerminal(stdin) {
+		oldState, err := term.MakeRaw(stdin)
+		if err != nil {
+			return 0, fmt.Errorf("failed to set raw mode: %w", err)
+		}
+		defer term.Restore(stdin, oldState)
+	}
+
+	go func() { _, _ = io.Copy(input, os.Stdin) }()
+
+	// Reading fails with EIO once the child exits
+	filter := protocol.NewFilter(os.Stdout, func(payload string) {
+		if err := controller.Handle(payload); err != nil {
+			onError(err)
+		}
+	})
+	_, _ = io.Copy(filter, ptmx)
+	_ = filter.Flush()
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, fmt.Errorf("failed to wait for %s: %w", args[0], err)
+	}
+
+	return 0, nil
+}
+
+// syncWriter serializes writes to w.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write implements io.Writer.
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// ffplayDecoder returns a function starting ffplay to play audio of a given
+// format from its standard input.
+func ffplayDecoder(ffplayPath string) func(protocol.Format) (io.WriteCloser, error) {
+	return func(format protocol.Format) (io.WriteCloser, error) {
+		args := []string{"-nodisp", "-autoexit", "-loglevel", "error"}
+		switch format.Codec {
+		case protocol.CodecOpus:
+			args = append(args, "-f", "ogg")
+		case protocol.CodecMP3:
+			args = append(args, "-f", "mp3")
+		case protocol.CodecPCM:
+			args = append(args, "-f", "s16le",
+				"-ar", fmt.Sprint(format.SampleRate),
+				"-ch_layout", fmt.Sprintf("%dc", format.Channels))
+		default:
+			return nil, fmt.Errorf("unsupported codec %s", format.Codec)
+		}
+		args = append(args, "-i", "pipe:0")
+
+		cmd := exec.Command(ffplayPath, args...)
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ffplay pipe: %w", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start ffplay: %w", err)
+		}
+
+		return &decoder{cmd: cmd, stdin: stdin}, nil
+	}
+}
+
+// decoder is a running ffplay process playing what is written to it.
+type decoder struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// Write implements io.Writer.
+func (d *decoder) Write(p []byte) (int, error) {
+	return d.stdin.Write(p)
+}
+
+// Close stops ffplay right away, rather than letting it play what it has
+// buffered.
+func (d *decoder) Close() error {
+	_ = d.stdin.Close()
+	_ = d.cmd.Process.Kill()
+	_ = d.cmd.Wait()
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package main implements the Terminal.FM SSH server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fulgidus/terminal-fm/internal/config"
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+	"github.com/fulgidus/terminal-fm/pkg/services/storage"
+	sshserver "github.com/fulgidus/terminal-fm/pkg/ssh"
+	"github.com/muesli/termenv"
+)
+
+var (
+	defaults = sshserver.DefaultConfig()
+
+	devMode       = flag.Bool("dev", false, "Run in development mode with mock data")
+	configPath    = flag.String("config", "", "Path to the config file (default ~/.terminal-fm/config.yaml)")
+	address       = flag.String("addr", defaults.Address, "Address to listen on")
+	hostKeyPath   = flag.String("host-key", defaults.HostKeyPath, "Path to the SSH host key (created if missing)")
+	maxSessions   = flag.Int("max-sessions", defaults.MaxSessions, "Maximum concurrent sessions (0 for no limit)")
+	maxPerIP      = flag.Int("max-sessions-per-ip", defaults.MaxSessionsPerIP, "Maximum concurrent sessions per client address (0 for no limit)")
+	idleTimeout   = flag.Duration("idle-timeout", defaults.IdleTimeout, "Disconnect idle sessions after this long (0 to disable)")
+	streamAudio   = flag.Bool("stream", false, "Stream the audio to clients instead of having them play the stations (needs ffmpeg)")
+	ffmpegPath    = flag.String("ffmpeg", "", "Path to the ffmpeg used with --stream (default: found in PATH)")
+	streamTitles  = flag.Bool("stream-titles", false, "Show the title playing, read from each listener's stream by the server")
+	shutdownGrace = flag.Duration("shutdown-timeout", 10*time.Second, "How long to wait for sessions to end on shutdown")
+	overrides     = config.BindFlags(flag.CommandLine)
+)
+
+func main() {
+	flag.Parse()
+
+	// Load configuration: file < TERMINAL_FM_* environment < flags
+	path, required := *configPath, true
+	if path == "" {
+		path, required = config.DefaultPath(), false
+	}
+
+	cfg, err := config.Load(path, required, os.LookupEnv, overrides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.DevMode = *devMode
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := cfg.EnsureDataDir(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create data directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize Radio Browser API client, shared by all sessions
+	var radioClient radiobrowser.Client
+	if cfg.DevMode {
+		radioClient = radiobrowser.NewMockClient()
+	} else {
+		apiClient, err := radiobrowser.NewAPIClient(slog.Default())
+		if err != nil {
+			log.Fatalf("Failed to initialize Radio Browser API: %v", err)
+		}
+		radioClient, err = radiobrowser.NewCachedClient(apiClient, cfg.Storage.CachePath)
+		if err != nil {
+			log.Fatalf("Failed to initialize cache: %v", err)
+		}
+	}
+
+	// Initialize storage
+	store, err := storage.NewStore(cfg.Storage.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer store.Close()
+
+	store.SetBackupDir(cfg.Storage.BackupPath)
+	stopBackups := store.StartBackups(cfg.Storage.BackupInterval, cfg.Storage.BackupKeepDays, func(err error) {
+		log.Printf("Database backup failed: %v", err)
+	})
+	defer stopBackups()
+
+	// The UI styles use the default renderer, which would otherwise detect
+	// the server's own (usually absent) terminal and drop all colors
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	lipgloss.SetHasDarkBackground(true)
+
+	server, err := sshserver.NewServer(sshserver.Config{
+		Address:          *address,
+		HostKeyPath:      *hostKeyPath,
+		MaxSessions:      *maxSessions,
+		MaxSessionsPerIP: *maxPerIP,
+		IdleTimeout:      *idleTimeout,
+		Locale:           cfg.I18n.DefaultLocale,
+		Columns:          cfg.UI.Columns,
+		StreamAudio:      *streamAudio,
+		FFmpegPath:       *ffmpegPath,
+		StreamTitles:     *streamTitles,
+	}, radioClient, store)
+	if err != nil {
+		log.Fatalf("Failed to create server: %v", err)
+	}
+
+	// Shut down gracefully on Ctrl+C or SIGTERM
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+
+		log.Printf("Shutting down, waiting up to %s for %d sessions", *shutdownGrace, server.ActiveSessions())
+		ctx, cancel := context.WithTimeout(context.Background(), *shutdownGrace)
+		defer cancel()
+
+		if err := server.Shutdown(ctx); err != nil {
+			_ = server.Close()
+		}
+	}()
+
+	if err := server.ListenAndServe(); err != nil {
+		log.Printf("Server error: %v", err)
+		stopBackups()
+		store.Close()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/fulgidus/terminal-fm/internal/config"
+	"github.com/fulgidus/terminal-fm/pkg/playlist"
+	"github.com/fulgidus/terminal-fm/pkg/services/storage"
+)
+
+// commandsUsage describes the subcommands accepted after the global flags.
+const commandsUsage = `Commands:
+  bookmarks export [-format f] <file>     Export bookmarks to a file ("-" for stdout)
+  bookmarks import [-format f] [-policy p] <file>
+                                          Import bookmarks from a file ("-" for stdin)
+                                          f is json, m3u, pls or xspf (default: from the
+                                          file extension, otherwise json)
+                                          p is skip (default), overwrite or newest
+  config show                             Print the effective configuration and where
+                                          each value comes from
+  restore                                 List database backups
+  restore <backup>                        Replace the database with a backup (a path or a
+                                          file name in the backups directory); the current
+                                          database is backed up first
+`
+
+// runCommand runs a non-interactive subcommand and returns the exit code.
+func runCommand(cfg *config.Config, args []string) int {
+	switch args[0] {
+	case "bookmarks":
+		return runBookmarksCommand(cfg, args[1:])
+	case "config":
+		return runConfigCommand(cfg, args[1:])
+	case "restore":
+		return runRestoreCommand(cfg, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n%s", args[0], commandsUsage)
+		return 2
+	}
+}
+
+// runBookmarksCommand handles "bookmarks export|import".
+func runBookmarksCommand(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, commandsUsage)
+		return 2
+	}
+
+	store, err := storage.NewStore(cfg.Storage.DBPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize storage: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	switch args[0] {
+	case "export":
+		fs := flag.NewFlagSet("bookmarks export", flag.ContinueOnError)
+		formatName := fs.String("format", "", "File format: json, m3u, pls or xspf")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: terminal-fm bookmarks export [-format json|m3u|pls|xspf] <file>")
+			return 2
+		}
+		path := fs.Arg(0)
+
+		format, isPlaylist, err := bookmarksFormat(*formatName, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 2
+		}
+
+		var data []byte
+		if isPlaylist {
+			var buf bytes.Buffer
+			err = store.ExportPlaylist(&buf, format)
+			data = buf.Bytes()
+		} else {
+			data, err = store.ExportBookmarks()
+			data = append(data, '\n')
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to export bookmarks: %v\n", err)
+			return 1
+		}
+
+		if err := writeFileOrStdout(path, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", path, err)
+			return 1
+		}
+
+		return 0
+
+	case "import":
+		fs := flag.NewFlagSet("bookmarks import", flag.ContinueOnError)
+		formatName := fs.String("format", "", "File format: json, m3u, pls or xspf")
+		policyName := fs.String("policy", "skip", "What to do with existing bookmarks: skip, overwrite or newest")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: terminal-fm bookmarks import [-format json|m3u|pls|xspf] [-policy skip|overwrite|newest] <file>")
+			return 2
+		}
+
+		format, isPlaylist, err := bookmarksFormat(*formatName, fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 2
+		}
+
+		policy, err := storage.ParseMergePolicy(*policyName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 2
+		}
+
+		data, err := readFileOrStdin(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", fs.Arg(0), err)
+			return 1
+		}
+
+		var result storage.ImportResult
+		if isPlaylist {
+			result, err = store.ImportPlaylist(bytes.NewReader(data), format, policy)
+		} else {
+			result, err = store.ImportBookmarks(data, policy)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to import bookmarks: %v\n", err)
+			return 1
+		}
+
+		fmt.Printf("Imported bookmarks: %d added, %d updated, %d skipped\n",
+			result.Added, result.Updated, result.Skipped)
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown bookmarks command: %s\n\n%s", args[0], commandsUsage)
+		return 2
+	}
+}
+
+// runConfigCommand handles "config show".
+func runConfigCommand(cfg *config.Config, args []string) int {
+	if len(args) != 1 || args[0] != "show" {
+		fmt.Fprintln(os.Stderr, "Usage: terminal-fm config show")
+		return 2
+	}
+
+	if file := cfg.File(); file != "" {
+		fmt.Printf("Config file: %s\n\n", file)
+	} else {
+		fmt.Printf("Config file: none (looked for %s)\n\n", config.DefaultPath())
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE\tENV")
+	for _, setting := range cfg.Settings() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", setting.Key, setting.Value, setting.Source, setting.Env)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write config: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// runRestoreCommand handles "restore [backup]".
+func runRestoreCommand(cfg *config.Config, args []string) int {
+	if len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "Usage: terminal-fm restore [backup]")
+		return 2
+	}
+
+	store, err := storage.NewStore(cfg.Storage.DBPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize storage: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+	store.SetBackupDir(cfg.Storage.BackupPath)
+
+	if len(args) == 0 {
+		backups, err := store.ListBackups()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list backups: %v\n", err)
+			return 1
+		}
+		if len(backups) == 0 {
+			fmt.Printf("No backups in %s\n", cfg.Storage.BackupPath)
+			return 0
+		}
+		for _, backup := range backups {
+			fmt.Printf("%s  %s\n", backup.CreatedAt.Local().Format("2006-01-02 15:04:05"), filepath.Base(backup.Path))
+		}
+		return 0
+	}
+
+	backupPath := args[0]
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) && filepath.Base(backupPath) == backupPath {
+		backupPath = filepath.Join(cfg.Storage.BackupPath, backupPath)
+	}
+	if _, err := os.Stat(backupPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to restore %s: %v\n", args[0], err)
+		return 1
+	}
+
+	// Keep the current database so the restore can be undone
+	current, err := store.Backup()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to back up current database: %v\n", err)
+		return 1
+	}
+	store.Close()
+
+	if err := storage.RestoreBackup(backupPath, cfg.Storage.DBPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to restore %s: %v\n", args[0], err)
+		return 1
+	}
+
+	fmt.Printf("Restored %s (previous database saved as %s)\n", backupPath, current)
+	return 0
+}
+
+// bookmarksFormat picks the import/export format from the -format flag, or
+// from the file extension when the flag is empty. It reports whether the
+// format is a playlist rather than the JSON export document.
+func bookmarksFormat(name, path string) (playlist.Format, bool, error) {
+	if name == "" {
+		format, ok := playlist.FormatFromPath(path)
+		return format, ok, nil
+	}
+
+	if name == "json" {
+		return 0, false, nil
+	}
+
+	format, err := playlist.ParseFormat(name)
+	if err != nil {
+		return 0, false, fmt.Errorf("unsupported format: %s (must be 'json', 'm3u', 'pls' or 'xspf')", name)
+	}
+	return format, true, nil
+}
+
+// writeFileOrStdout writes data to path, or to stdout if path is "-".
+func writeFileOrStdout(path string, data []byte) error {
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// readFileOrStdin reads path, or stdin if path is "-".
+func readFileOrStdin(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
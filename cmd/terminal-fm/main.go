@@ -4,7 +4,10 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"sync"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/fulgidus/terminal-fm/internal/config"
@@ -16,13 +19,19 @@ import (
 )
 
 var (
-	version = "1.0.0"
-	devMode = flag.Bool("dev", false, "Run in development mode with mock data")
-	locale  = flag.String("locale", "", "Set locale (en or it)")
-	showVer = flag.Bool("version", false, "Show version information")
+	version    = "1.0.0"
+	devMode    = flag.Bool("dev", false, "Run in development mode with mock data")
+	configPath = flag.String("config", "", "Path to the config file (default ~/.terminal-fm/config.yaml)")
+	showVer    = flag.Bool("version", false, "Show version information")
+	overrides  = config.BindFlags(flag.CommandLine)
 )
 
 func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: terminal-fm [flags] [command]\n\nFlags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "\n%s", commandsUsage)
+	}
 	flag.Parse()
 
 	// Show version and exit
@@ -31,14 +40,18 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Load configuration
-	cfg := config.New()
-	cfg.DevMode = *devMode
+	// Load configuration: file < TERMINAL_FM_* environment < flags
+	path, required := *configPath, true
+	if path == "" {
+		path, required = config.DefaultPath(), false
+	}
 
-	// Set locale if provided
-	if *locale != "" {
-		cfg.I18n.DefaultLocale = *locale
+	cfg, err := config.Load(path, required, os.LookupEnv, overrides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		os.Exit(1)
 	}
+	cfg.DevMode = *devMode
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
@@ -52,20 +65,39 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Run non-interactive subcommands without starting the TUI
+	if args := flag.Args(); len(args) > 0 {
+		os.Exit(runCommand(cfg, args))
+	}
+
 	// Initialize Radio Browser API client
 	var radioClient radiobrowser.Client
-	var err error
 
 	if cfg.DevMode {
 		// Use mock client in development mode
 		radioClient = radiobrowser.NewMockClient()
 	} else {
-		// Use real API client
-		radioClient, err = radiobrowser.NewAPIClient()
+		// Use real API client, logging to a file so the TUI stays intact
+		logPath := filepath.Join(filepath.Dir(cfg.Storage.DBPath), "terminal-fm.log")
+		logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open log file: %v\n", err)
+			os.Exit(1)
+		}
+		defer logFile.Close()
+
+		apiClient, err := radiobrowser.NewAPIClient(slog.New(slog.NewTextHandler(logFile, nil)))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to initialize Radio Browser API: %v\n", err)
 			os.Exit(1)
 		}
+
+		// Cache responses so the last station list shows at once, even offline
+		radioClient, err = radiobrowser.NewCachedClient(apiClient, cfg.Storage.CachePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to initialize cache: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	// Initialize storage
@@ -76,17 +108,34 @@ func main() {
 	}
 	defer store.Close()
 
+	// Snapshot the database now and periodically while the app runs.
+	// Errors are reported after the TUI exits so they don't garble it.
+	var backupErrs []error
+	var backupMu sync.Mutex
+	store.SetBackupDir(cfg.Storage.BackupPath)
+	stopBackups := store.StartBackups(cfg.Storage.BackupInterval, cfg.Storage.BackupKeepDays, func(err error) {
+		backupMu.Lock()
+		backupErrs = append(backupErrs, err)
+		backupMu.Unlock()
+	})
+	defer stopBackups()
+
 	// Initialize audio player
 	var audioPlayer player.Player
 	if cfg.Player.DefaultPlayer == "mpv" {
-		// TODO: Implement MpvPlayer when ready
-		audioPlayer = player.NewFFplayPlayer(cfg.Player.FFplayPath)
+		audioPlayer = player.NewMpvPlayer(cfg.Player.MpvPath)
 	} else {
-		audioPlayer = player.NewFFplayPlayer(cfg.Player.FFplayPath)
+		ffplay := player.NewFFplayPlayer(cfg.Player.FFplayPath)
+		ffplay.SetMaxRetries(cfg.Player.MaxRetries)
+		audioPlayer = ffplay
 	}
 
 	// Create the TUI model
-	model := ui.NewModel(radioClient, audioPlayer, store, cfg.I18n.DefaultLocale)
+	model := ui.NewModel(radioClient, audioPlayer, store.Local(), cfg.I18n.DefaultLocale)
+	if err := model.SetColumns(cfg.UI.Columns); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Initialize translator for startup messages
 	tr := i18n.NewSimpleTranslator(cfg.I18n.DefaultLocale)
@@ -110,6 +159,11 @@ func main() {
 		m.Cleanup()
 	}
 
+	stopBackups()
+	for _, err := range backupErrs {
+		fmt.Fprintf(os.Stderr, "Warning: database backup failed: %v\n", err)
+	}
+
 	// Show goodbye message
 	fmt.Println(tr.T("goodbye"))
 }
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 )
 
 // Config holds all application configuration.
@@ -12,7 +15,13 @@ type Config struct {
 	Player  PlayerConfig
 	Storage StorageConfig
 	I18n    I18nConfig
+	UI      UIConfig
 	DevMode bool
+
+	// file is the config file that was loaded, if any.
+	file string
+	// sources records where each non-default setting came from.
+	sources map[string]Source
 }
 
 // PlayerConfig contains audio player settings.
@@ -26,8 +35,11 @@ type PlayerConfig struct {
 
 // StorageConfig contains database settings.
 type StorageConfig struct {
-	DBPath     string
-	BackupPath string
+	DBPath         string
+	BackupPath     string
+	BackupInterval time.Duration // how often to snapshot the database
+	BackupKeepDays int           // backups older than this are removed
+	CachePath      string        // Radio Browser responses
 }
 
 // I18nConfig contains internationalization settings.
@@ -36,6 +48,16 @@ type I18nConfig struct {
 	LocalesPath   string
 }
 
+// ColumnNames are the columns the station table can show, in their default
+// order.
+var ColumnNames = []string{"name", "country", "codec", "bitrate", "votes", "clicks", "tags"}
+
+// UIConfig contains settings of the terminal interface.
+type UIConfig struct {
+	// Columns are the columns of the station table, in order.
+	Columns []string
+}
+
 // New creates a new Config with default values.
 func New() *Config {
 	homeDir, _ := os.UserHomeDir()
@@ -50,13 +72,19 @@ func New() *Config {
 			MaxRetries:    3,
 		},
 		Storage: StorageConfig{
-			DBPath:     filepath.Join(dataDir, "terminal-fm.db"),
-			BackupPath: filepath.Join(dataDir, "backups"),
+			DBPath:         filepath.Join(dataDir, "terminal-fm.db"),
+			BackupPath:     filepath.Join(dataDir, "backups"),
+			BackupInterval: 24 * time.Hour,
+			BackupKeepDays: 7,
+			CachePath:      filepath.Join(dataDir, "cache"),
 		},
 		I18n: I18nConfig{
 			DefaultLocale: "en",
 			LocalesPath:   "pkg/i18n/locales",
 		},
+		UI: UIConfig{
+			Columns: slices.Clone(ColumnNames),
+		},
 		DevMode: false,
 	}
 }
@@ -71,6 +99,41 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("unsupported locale: %s (must be 'en' or 'it')", c.I18n.DefaultLocale)
 	}
 
+	if c.Player.BufferSize < 0 {
+		return fmt.Errorf("invalid buffer size: %d (must not be negative)", c.Player.BufferSize)
+	}
+
+	if c.Player.MaxRetries < 0 {
+		return fmt.Errorf("invalid max retries: %d (must not be negative)", c.Player.MaxRetries)
+	}
+
+	if c.Storage.BackupKeepDays < 1 {
+		return fmt.Errorf("invalid backup retention: %d days (must be at least 1)", c.Storage.BackupKeepDays)
+	}
+
+	if err := ValidateColumns(c.UI.Columns); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidateColumns checks a list of station table columns: each must be one
+// of ColumnNames, listed once, and name must be among them.
+func ValidateColumns(columns []string) error {
+	seen := make(map[string]bool)
+	for _, column := range columns {
+		if !slices.Contains(ColumnNames, column) {
+			return fmt.Errorf("invalid column: %s (must be one of %s)", column, strings.Join(ColumnNames, ", "))
+		}
+		if seen[column] {
+			return fmt.Errorf("invalid columns: %s is listed twice", column)
+		}
+		seen[column] = true
+	}
+	if !seen["name"] {
+		return fmt.Errorf("invalid columns: %s (must include name)", strings.Join(columns, ","))
+	}
 	return nil
 }
 
@@ -86,10 +149,20 @@ func (c *Config) EnsureDataDir() error {
 		return fmt.Errorf("failed to create data directory: %w", err)
 	}
 
+	// The database may live outside the data directory
+	if err := os.MkdirAll(filepath.Dir(c.Storage.DBPath), 0755); err != nil {
+		return fmt.Errorf("failed to create database directory: %w", err)
+	}
+
 	// Create backups directory
 	if err := os.MkdirAll(c.Storage.BackupPath, 0755); err != nil {
 		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
+	// Create cache directory
+	if err := os.MkdirAll(c.Storage.CachePath, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,311 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of environment variables that override settings.
+const EnvPrefix = "TERMINAL_FM_"
+
+// Source records where a configuration value came from.
+type Source string
+
+const (
+	// SourceDefault means the built-in default is in effect.
+	SourceDefault Source = "default"
+	// SourceFile means the value was read from the config file.
+	SourceFile Source = "file"
+	// SourceEnv means the value came from a TERMINAL_FM_* variable.
+	SourceEnv Source = "env"
+	// SourceFlag means the value was given on the command line.
+	SourceFlag Source = "flag"
+)
+
+// Setting is the effective value of a configuration key.
+type Setting struct {
+	Key    string
+	Env    string
+	Value  string
+	Source Source
+}
+
+// Overrides holds setting values given on the command line, keyed by
+// setting key.
+type Overrides map[string]string
+
+// setting describes a configurable value and how to read and write it.
+type setting struct {
+	key   string // config file key, "section.name"
+	env   string // environment variable, without EnvPrefix
+	flag  string // command-line flag name
+	usage string
+	get   func(c *Config) string
+	set   func(c *Config, value string) error
+}
+
+// settings lists every value that can be set from a file, the environment
+// or a flag, in the order "config show" prints them.
+var settings = []setting{
+	{
+		key: "player.default", env: "PLAYER", flag: "player",
+		usage: "Audio player to use (ffplay or mpv)",
+		get:   func(c *Config) string { return c.Player.DefaultPlayer },
+		set:   func(c *Config, v string) error { c.Player.DefaultPlayer = v; return nil },
+	},
+	{
+		key: "player.ffplay_path", env: "FFPLAY_PATH", flag: "ffplay-path",
+		usage: "Path to the ffplay binary",
+		get:   func(c *Config) string { return c.Player.FFplayPath },
+		set:   func(c *Config, v string) error { c.Player.FFplayPath = expandHome(v); return nil },
+	},
+	{
+		key: "player.mpv_path", env: "MPV_PATH", flag: "mpv-path",
+		usage: "Path to the mpv binary",
+		get:   func(c *Config) string { return c.Player.MpvPath },
+		set:   func(c *Config, v string) error { c.Player.MpvPath = expandHome(v); return nil },
+	},
+	{
+		key: "player.buffer_size", env: "BUFFER_SIZE", flag: "buffer-size",
+		usage: "Stream buffer size in seconds",
+		get:   func(c *Config) string { return strconv.Itoa(c.Player.BufferSize) },
+		set:   func(c *Config, v string) error { return parseInt(v, &c.Player.BufferSize) },
+	},
+	{
+		key: "player.max_retries", env: "MAX_RETRIES", flag: "max-retries",
+		usage: "How many times to reconnect a dropped stream",
+		get:   func(c *Config) string { return strconv.Itoa(c.Player.MaxRetries) },
+		set:   func(c *Config, v string) error { return parseInt(v, &c.Player.MaxRetries) },
+	},
+	{
+		key: "storage.db_path", env: "DB_PATH", flag: "db",
+		usage: "Path to the database file",
+		get:   func(c *Config) string { return c.Storage.DBPath },
+		set:   func(c *Config, v string) error { c.Storage.DBPath = expandHome(v); return nil },
+	},
+	{
+		key: "storage.backup_path", env: "BACKUP_PATH", flag: "backup-path",
+		usage: "Directory for database backups",
+		get:   func(c *Config) string { return c.Storage.BackupPath },
+		set:   func(c *Config, v string) error { c.Storage.BackupPath = expandHome(v); return nil },
+	},
+	{
+		key: "storage.backup_interval", env: "BACKUP_INTERVAL", flag: "backup-interval",
+		usage: "How often to back up the database (e.g. 24h)",
+		get:   func(c *Config) string { return c.Storage.BackupInterval.String() },
+		set: func(c *Config, v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid duration: %s", v)
+			}
+			c.Storage.BackupInterval = d
+			return nil
+		},
+	},
+	{
+		key: "storage.backup_keep_days", env: "BACKUP_KEEP_DAYS", flag: "backup-keep-days",
+		usage: "Days to keep database backups",
+		get:   func(c *Config) string { return strconv.Itoa(c.Storage.BackupKeepDays) },
+		set:   func(c *Config, v string) error { return parseInt(v, &c.Storage.BackupKeepDays) },
+	},
+	{
+		key: "storage.cache_path", env: "CACHE_PATH", flag: "cache-path",
+		usage: "Directory for cached Radio Browser responses",
+		get:   func(c *Config) string { return c.Storage.CachePath },
+		set:   func(c *Config, v string) error { c.Storage.CachePath = expandHome(v); return nil },
+	},
+	{
+		key: "i18n.locale", env: "LOCALE", flag: "locale",
+		usage: "Set locale (en or it)",
+		get:   func(c *Config) string { return c.I18n.DefaultLocale },
+		set:   func(c *Config, v string) error { c.I18n.DefaultLocale = v; return nil },
+	},
+	{
+		key: "ui.columns", env: "COLUMNS", flag: "columns",
+		usage: "Columns of the station table, in order (e.g. name,country,bitrate)",
+		get:   func(c *Config) string { return strings.Join(c.UI.Columns, ",") },
+		set: func(c *Config, v string) error {
+			// A comma-separated list, or a YAML list, in any case
+			c.UI.Columns = strings.FieldsFunc(strings.ToLower(v), func(r rune) bool {
+				return r == ',' || r == ' ' || r == '[' || r == ']'
+			})
+			return nil
+		},
+	},
+}
+
+// DefaultPath returns the default config file location,
+// ~/.terminal-fm/config.yaml.
+func DefaultPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".terminal-fm", "config.yaml")
+}
+
+// BindFlags registers a flag for every setting on fs. Values given on the
+// command line are collected in the returned Overrides for Load.
+func BindFlags(fs *flag.FlagSet) Overrides {
+	overrides := make(Overrides)
+
+	for _, s := range settings {
+		key := s.key
+		fs.Func(s.flag, s.usage, func(value string) error {
+			overrides[key] = value
+			return nil
+		})
+	}
+
+	return overrides
+}
+
+// Load builds the configuration from the defaults, the config file at path,
+// environment variables and command-line overrides, each taking precedence
+// over the previous one. A missing file is not an error unless required is
+// set. lookupEnv is usually os.LookupEnv.
+func Load(path string, required bool, lookupEnv func(string) (string, bool), flags Overrides) (*Config, error) {
+	c := New()
+	c.sources = make(map[string]Source)
+
+	if path != "" {
+		err := c.loadFile(path)
+		if err != nil && (required || !errors.Is(err, os.ErrNotExist)) {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		value, ok := lookupEnv(EnvPrefix + s.env)
+		if !ok {
+			continue
+		}
+		if err := c.apply(s, value, SourceEnv); err != nil {
+			return nil, fmt.Errorf("invalid %s%s: %w", EnvPrefix, s.env, err)
+		}
+	}
+
+	for _, s := range settings {
+		value, ok := flags[s.key]
+		if !ok {
+			continue
+		}
+		if err := c.apply(s, value, SourceFlag); err != nil {
+			return nil, fmt.Errorf("invalid -%s: %w", s.flag, err)
+		}
+	}
+
+	return c, nil
+}
+
+// File returns the config file that was loaded, or "" if there was none.
+func (c *Config) File() string {
+	return c.file
+}
+
+// Settings returns the effective value and source of every setting.
+func (c *Config) Settings() []Setting {
+	result := make([]Setting, 0, len(settings))
+	for _, s := range settings {
+		source, ok := c.sources[s.key]
+		if !ok {
+			source = SourceDefault
+		}
+		result = append(result, Setting{
+			Key:    s.key,
+			Env:    EnvPrefix + s.env,
+			Value:  s.get(c),
+			Source: source,
+		})
+	}
+	return result
+}
+
+// loadFile reads a YAML config file such as:
+//
+//	player:
+//	  default: mpv
+//	  buffer_size: 10
+//	storage:
+//	  db_path: ~/radio.db
+//	i18n:
+//	  locale: it
+//	ui:
+//	  columns: name,country,bitrate
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc map[string]map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	for section, entries := range doc {
+		for name, value := range entries {
+			values[section+"."+name] = fmt.Sprint(value)
+		}
+	}
+
+	for _, s := range settings {
+		value, ok := values[s.key]
+		if !ok {
+			continue
+		}
+		delete(values, s.key)
+
+		if err := c.apply(s, value, SourceFile); err != nil {
+			return fmt.Errorf("invalid %s in %s: %w", s.key, path, err)
+		}
+	}
+
+	if len(values) > 0 {
+		unknown := make([]string, 0, len(values))
+		for key := range values {
+			unknown = append(unknown, key)
+		}
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown settings in %s: %s", path, strings.Join(unknown, ", "))
+	}
+
+	c.file = path
+	return nil
+}
+
+// apply sets a value and records its source.
+func (c *Config) apply(s setting, value string, source Source) error {
+	if err := s.set(c, strings.TrimSpace(value)); err != nil {
+		return err
+	}
+	c.sources[s.key] = source
+	return nil
+}
+
+// parseInt parses a base 10 integer into dst.
+func parseInt(value string, dst *int) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("not a number: %s", value)
+	}
+	*dst = n
+	return nil
+}
+
+// expandHome replaces a leading "~/" with the user's home directory.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
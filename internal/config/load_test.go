@@ -0,0 +1,173 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file into a temporary directory.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+// env returns a lookup function backed by a map.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+player:
+  buffer_size: 10
+  max_retries: 4
+  ffplay_path: /opt/ffplay
+storage:
+  db_path: /data/radio.db
+i18n:
+  locale: it
+`)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	overrides := BindFlags(fs)
+	if err := fs.Parse([]string{"-buffer-size", "20"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	cfg, err := Load(path, true, env(map[string]string{
+		"TERMINAL_FM_BUFFER_SIZE": "15",
+		"TERMINAL_FM_MAX_RETRIES": "6",
+	}), overrides)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	tests := []struct {
+		key    string
+		value  string
+		source Source
+	}{
+		{"player.buffer_size", "20", SourceFlag},
+		{"player.max_retries", "6", SourceEnv},
+		{"player.ffplay_path", "/opt/ffplay", SourceFile},
+		{"player.mpv_path", "mpv", SourceDefault},
+		{"storage.db_path", "/data/radio.db", SourceFile},
+		{"i18n.locale", "it", SourceFile},
+	}
+
+	got := make(map[string]Setting)
+	for _, setting := range cfg.Settings() {
+		got[setting.Key] = setting
+	}
+
+	for _, tt := range tests {
+		setting := got[tt.key]
+		if setting.Value != tt.value || setting.Source != tt.source {
+			t.Errorf("%s: expected %q from %s, got %q from %s", tt.key, tt.value, tt.source, setting.Value, setting.Source)
+		}
+	}
+
+	if cfg.Player.BufferSize != 20 || cfg.Player.MaxRetries != 6 || cfg.Storage.DBPath != "/data/radio.db" {
+		t.Errorf("Expected settings to be applied to the config, got %+v", cfg)
+	}
+	if cfg.File() != path {
+		t.Errorf("Expected config file %q, got %q", path, cfg.File())
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+
+	cfg, err := Load(missing, false, env(nil), nil)
+	if err != nil {
+		t.Fatalf("Expected a missing default config to be ignored, got %v", err)
+	}
+	if cfg.File() != "" || cfg.Player.BufferSize != New().Player.BufferSize {
+		t.Errorf("Expected defaults without a config file")
+	}
+
+	if _, err := Load(missing, true, env(nil), nil); err == nil {
+		t.Errorf("Expected error for a missing required config file")
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		flags Overrides
+	}{
+		{name: "unknown key", file: "player:\n  bufer_size: 1\n"},
+		{name: "bad yaml", file: "player: [\n"},
+		{name: "bad file number", file: "player:\n  buffer_size: lots\n"},
+		{name: "bad env number", env: map[string]string{"TERMINAL_FM_MAX_RETRIES": "x"}},
+		{name: "bad flag duration", flags: Overrides{"storage.backup_interval": "daily"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ""
+			if tt.file != "" {
+				path = writeConfig(t, tt.file)
+			}
+			if _, err := Load(path, true, env(tt.env), tt.flags); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	if got := expandHome("~/radio.db"); got != filepath.Join(homeDir, "radio.db") {
+		t.Errorf("Expected ~ to expand to %s, got %s", homeDir, got)
+	}
+	if got := expandHome("/abs/~/radio.db"); got != "/abs/~/radio.db" {
+		t.Errorf("Expected absolute path to be unchanged, got %s", got)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	for _, file := range []string{
+		"ui:\n  columns: name, votes,tags\n",
+		"ui:\n  columns: [name, votes, tags]\n",
+		"ui:\n  columns: Name, Votes,TAGS\n",
+	} {
+		cfg, err := Load(writeConfig(t, file), true, env(nil), nil)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if got := strings.Join(cfg.UI.Columns, ","); got != "name,votes,tags" {
+			t.Errorf("Expected name,votes,tags from %q, got %s", file, got)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Expected valid columns, got %v", err)
+		}
+	}
+
+	for _, columns := range []string{"name,flag", "name,votes,name", "country,votes", ""} {
+		cfg, err := Load("", false, env(map[string]string{"TERMINAL_FM_COLUMNS": columns}), nil)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected columns %q to be invalid", columns)
+		}
+	}
+}
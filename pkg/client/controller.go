@@ -0,0 +1,237 @@
+// Package client implements the listener's side of Terminal.FM over SSH:
+// it carries out the player commands sent by the server on a local player
+// and reports back what the player is doing.
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+
+	"github.com/fulgidus/terminal-fm/pkg/protocol"
+	"github.com/fulgidus/terminal-fm/pkg/services/player"
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// clientCaps are the capabilities announced in the client's HELLO.
+var clientCaps = []string{protocol.CapPlay, protocol.CapPause, protocol.CapVolume, protocol.CapStatus}
+
+// Controller carries out the commands sent by player.RemotePlayer on a
+// local player, and sends status replies to the server.
+type Controller struct {
+	mu       sync.Mutex
+	player   player.Player
+	replies  *protocol.Encoder
+	receiver *Receiver
+
+	// greeted is set once the server's HELLO has been answered; before
+	// that the server may not speak the protocol, so nothing is sent.
+	greeted bool
+	// ack is the sequence number of the last message handled; the next
+	// one must have a higher number.
+	ack uint64
+}
+
+// NewController creates a Controller that drives p and writes replies to
+// the server through w.
+func NewController(p player.Player, w io.Writer) *Controller {
+	return &Controller{player: p, replies: protocol.NewEncoder(w)}
+}
+
+// StreamTo makes the controller offer the receiver's codecs in its HELLO,
+// and play the audio frames the server streams through it. Call it before
+// the handshake.
+func (c *Controller) StreamTo(r *Receiver) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.receiver = r
+}
+
+// Handle runs the message with the given payload. Only the first HELLO
+// is answered, and a message numbered no higher than the last is refused.
+func (c *Controller) Handle(payload string) error {
+	msg, err := protocol.Decode(payload)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The server numbers its messages in order, so one that does not
+	// follow the last was replayed or forged by something else writing
+	// to the terminal
+	if msg.Seq <= c.ack {
+		return fmt.Errorf("%w: sequence number %d after %d", protocol.ErrMalformed, msg.Seq, c.ack)
+	}
+
+	if msg.Type == protocol.TypeHello {
+		if c.greeted {
+			return fmt.Errorf("%w: repeated HELLO", protocol.ErrMalformed)
+		}
+		return c.helloLocked(msg)
+	}
+
+	c.ack = msg.Seq
+
+	if err := c.runLocked(msg); err != nil {
+		// Let the server know the command did not take
+		c.sendStatusLocked(err)
+		return err
+	}
+	return nil
+}
+
+// helloLocked answers the server's HELLO.
+func (c *Controller) helloLocked(msg protocol.Message) error {
+	versions, _, err := protocol.ParseHello(msg)
+	if err != nil {
+		return err
+	}
+
+	supported := false
+	for _, version := range versions {
+		supported = supported || version == protocol.Version
+	}
+	if !supported {
+		return fmt.Errorf("%w: server supports %v", protocol.ErrUnsupportedVersion, versions)
+	}
+
+	caps := clientCaps
+	if c.receiver != nil {
+		caps = append(append([]string{}, caps...), protocol.CodecCaps(c.receiver.Codecs()...)...)
+	}
+
+	if _, err := c.replies.Send(protocol.Hello(caps...)); err != nil {
+		return err
+	}
+	c.greeted = true
+	c.ack = msg.Seq
+	return nil
+}
+
+// runLocked applies a command to the player.
+func (c *Controller) runLocked(msg protocol.Message) error {
+	switch msg.Type {
+	case protocol.TypePlay:
+		url, volume, err := protocol.ParsePlay(msg)
+		if err != nil {
+			return err
+		}
+		if err := checkStreamURL(url); err != nil {
+			return err
+		}
+
+		// Stop first so that changing the volume does not restart the old
+		// stream on players that apply it by relaunching
+		_ = c.player.Stop()
+		if err := c.player.SetVolume(volume); err != nil {
+			return err
+		}
+
+		station := &radiobrowser.Station{Name: url, URL: url, URLResolved: url}
+		if err := c.player.Play(station); err != nil {
+			return fmt.Errorf("failed to play %s: %w", url, err)
+		}
+		return nil
+
+	case protocol.TypeStop:
+		if c.receiver != nil {
+			c.receiver.Stop()
+		}
+		return c.player.Stop()
+
+	case protocol.TypeFrame:
+		frame, err := protocol.ParseFrame(msg)
+		if err != nil {
+			return err
+		}
+		if c.receiver == nil {
+			return fmt.Errorf("audio streaming is not supported")
+		}
+		return c.receiver.Handle(frame)
+
+	case protocol.TypePause:
+		return c.player.Pause()
+
+	case protocol.TypeResume:
+		return c.player.Resume()
+
+	case protocol.TypeVolume:
+		volume, err := protocol.ParseVolume(msg)
+		if err != nil {
+			return err
+		}
+		return c.player.SetVolume(volume)
+
+	default:
+		return fmt.Errorf("unknown command: %s", msg.Type)
+	}
+}
+
+// checkStreamURL makes sure raw is an http or https URL. The server
+// picks what the client plays, and must not get it to open local files,
+// devices or anything else the player understands.
+func checkStreamURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid stream URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported stream URL: %s", raw)
+	}
+	return nil
+}
+
+// ReportEvents sends a status reply for every player event until stop or
+// events is closed, so the server hears about changes the player makes on
+// its own, such as a stream dropping.
+func (c *Controller) ReportEvents(events <-chan player.Event, stop <-chan struct{}) {
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			c.mu.Lock()
+			c.sendStatusLocked(event.Err)
+			c.mu.Unlock()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// sendStatusLocked reports the player's current state. It reads the state
+// rather than trusting an event's, which may predate the last command.
+func (c *Controller) sendStatusLocked(cause error) {
+	if !c.greeted {
+		return
+	}
+
+	status := protocol.Status{
+		Ack:    c.ack,
+		State:  stateName(c.player.GetState()),
+		Volume: c.player.GetVolume(),
+	}
+	if cause != nil {
+		status.Err = cause.Error()
+	}
+
+	_, _ = c.replies.Send(status.Message())
+}
+
+// stateName returns the protocol name of a player state.
+func stateName(state player.State) string {
+	switch state {
+	case player.StatePlaying:
+		return protocol.StatePlaying
+	case player.StatePaused:
+		return protocol.StatePaused
+	case player.StateBuffering:
+		return protocol.StateBuffering
+	default:
+		return protocol.StateStopped
+	}
+}
@@ -0,0 +1,345 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/protocol"
+	"github.com/fulgidus/terminal-fm/pkg/services/player"
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// fakePlayer is a player.Player that only records what it is told.
+type fakePlayer struct {
+	mu      sync.Mutex
+	state   player.State
+	station *radiobrowser.Station
+	volume  int
+	playErr error
+	events  chan player.Event
+}
+
+func newFakePlayer() *fakePlayer {
+	return &fakePlayer{volume: 70, events: make(chan player.Event, 16)}
+}
+
+func (p *fakePlayer) set(state player.State, station *radiobrowser.Station) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state, p.station = state, station
+}
+
+func (p *fakePlayer) Play(station *radiobrowser.Station) error {
+	if p.playErr != nil {
+		return p.playErr
+	}
+	p.set(player.StatePlaying, station)
+	return nil
+}
+
+func (p *fakePlayer) Stop() error {
+	p.set(player.StateStopped, nil)
+	return nil
+}
+
+func (p *fakePlayer) Pause() error {
+	if p.GetState() != player.StatePlaying {
+		return errors.New("nothing is playing")
+	}
+	p.set(player.StatePaused, p.GetCurrentStation())
+	return nil
+}
+
+func (p *fakePlayer) Resume() error {
+	if p.GetState() != player.StatePaused {
+		return errors.New("playback is not paused")
+	}
+	p.set(player.StatePlaying, p.GetCurrentStation())
+	return nil
+}
+
+func (p *fakePlayer) GetState() player.State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+func (p *fakePlayer) GetCurrentStation() *radiobrowser.Station {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.station
+}
+
+func (p *fakePlayer) SetVolume(volume int) error {
+	if volume < 0 || volume > 100 {
+		return errors.New("volume must be between 0 and 100")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.volume = volume
+	return nil
+}
+
+func (p *fakePlayer) GetVolume() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.volume
+}
+
+func (p *fakePlayer) Events() <-chan player.Event {
+	return p.events
+}
+
+func (p *fakePlayer) Unsubscribe(<-chan player.Event) {}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// take returns and clears the contents.
+func (b *lockedBuffer) take() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data := append([]byte(nil), b.buf.Bytes()...)
+	b.buf.Reset()
+	return data
+}
+
+// session connects a server RemotePlayer to a Controller, as an SSH session
+// through terminal-fm-client would.
+type session struct {
+	server  *player.RemotePlayer
+	local   *fakePlayer
+	replies lockedBuffer
+	errs    []error
+}
+
+func newSession(t *testing.T) *session {
+	t.Helper()
+
+	s := &session{local: newFakePlayer()}
+	controller := NewController(s.local, &s.replies)
+
+	output := protocol.NewFilter(&bytes.Buffer{}, func(payload string) {
+		if err := controller.Handle(payload); err != nil {
+			s.errs = append(s.errs, err)
+		}
+	})
+	s.server = player.NewRemotePlayer(output)
+
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	go controller.ReportEvents(s.local.events, stop)
+
+	return s
+}
+
+// deliver passes the client's pending replies to the server.
+func (s *session) deliver(t *testing.T) {
+	t.Helper()
+
+	input := protocol.NewFilter(&bytes.Buffer{}, func(payload string) {
+		if err := s.server.HandleReply(payload); err != nil {
+			t.Errorf("Server failed to handle %q: %v", payload, err)
+		}
+	})
+	_, _ = input.Write(s.replies.take())
+}
+
+func TestControllerHandshake(t *testing.T) {
+	s := newSession(t)
+
+	if err := s.server.Hello(); err != nil {
+		t.Fatalf("Failed to send HELLO: %v", err)
+	}
+	s.deliver(t)
+
+	if !s.server.Connected() {
+		t.Fatalf("Expected the server to see the client after the handshake")
+	}
+
+	station := &radiobrowser.Station{Name: "Jazz", URLResolved: "http://jazz/stream?a=1;b=2"}
+
+	steps := []struct {
+		name   string
+		do     func() error
+		state  player.State
+		volume int
+	}{
+		{"play", func() error { return s.server.Play(station) }, player.StatePlaying, 70},
+		{"volume", func() error { return s.server.SetVolume(40) }, player.StatePlaying, 40},
+		{"pause", s.server.Pause, player.StatePaused, 40},
+		{"resume", s.server.Resume, player.StatePlaying, 40},
+		{"stop", s.server.Stop, player.StateStopped, 40},
+	}
+
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatalf("Failed to %s: %v", step.name, err)
+		}
+		if s.local.GetState() != step.state {
+			t.Errorf("After %s: expected state %v, got %v", step.name, step.state, s.local.GetState())
+		}
+		if s.local.GetVolume() != step.volume {
+			t.Errorf("After %s: expected volume %d, got %d", step.name, step.volume, s.local.GetVolume())
+		}
+		if step.name == "play" {
+			if current := s.local.GetCurrentStation(); current == nil || current.URLResolved != station.URLResolved {
+				t.Errorf("Expected the URL to survive ';' in it, got %+v", current)
+			}
+		}
+	}
+
+	if len(s.errs) != 0 {
+		t.Errorf("Expected no errors, got %v", s.errs)
+	}
+}
+
+func TestControllerReportsFailures(t *testing.T) {
+	s := newSession(t)
+
+	if err := s.server.Hello(); err != nil {
+		t.Fatalf("Failed to send HELLO: %v", err)
+	}
+	s.deliver(t)
+
+	// The local player cannot start
+	s.local.playErr = errors.New("ffplay not found")
+	events := s.server.Events()
+
+	if err := s.server.Play(&radiobrowser.Station{Name: "Jazz", URLResolved: "http://jazz"}); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+	s.deliver(t)
+
+	if s.server.GetState() != player.StateStopped {
+		t.Errorf("Expected the server to learn playback failed, got %v", s.server.GetState())
+	}
+	if last := lastEvent(events); last.Err == nil {
+		t.Errorf("Expected an event carrying the client's error, got %+v", last)
+	}
+
+	// The stream drops later, on the client's own
+	s.local.playErr = nil
+	if err := s.server.Play(&radiobrowser.Station{Name: "Jazz", URLResolved: "http://jazz"}); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+	s.local.set(player.StateStopped, nil)
+	s.local.events <- player.Event{State: player.StateStopped, Err: errors.New("stream lost")}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.server.GetState() != player.StateStopped && time.Now().Before(deadline) {
+		s.deliver(t)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s.server.GetState() != player.StateStopped {
+		t.Errorf("Expected the server to learn the stream dropped, got %v", s.server.GetState())
+	}
+}
+
+func TestControllerWaitsForHello(t *testing.T) {
+	var replies bytes.Buffer
+	local := newFakePlayer()
+	controller := NewController(local, &replies)
+
+	// Without a HELLO the other side may be a plain terminal, so failures
+	// are not answered
+	if err := controller.Handle("1;1;PAUSE"); err == nil {
+		t.Errorf("Expected an error pausing a stopped player")
+	}
+	if replies.Len() != 0 {
+		t.Errorf("Expected no replies before the handshake, got %q", replies.String())
+	}
+
+	// A server that only speaks a newer version is turned down
+	if err := controller.Handle("1;2;HELLO;2;play"); !errors.Is(err, protocol.ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion, got %v", err)
+	}
+	if replies.Len() != 0 {
+		t.Errorf("Expected no reply to an unsupported HELLO, got %q", replies.String())
+	}
+}
+
+func TestControllerRejectsInvalidMessages(t *testing.T) {
+	controller := NewController(newFakePlayer(), &bytes.Buffer{})
+
+	for _, payload := range []string{
+		"", "PLAY;http://x;70", "1;1;JUMP", "1;2;PLAY", "1;3;PLAY;;70",
+		"1;4;PLAY;http://x;loud", "1;5;VOLUME", "1;6;VOLUME;101",
+	} {
+		if err := controller.Handle(payload); err == nil {
+			t.Errorf("Expected an error for %q", payload)
+		}
+	}
+}
+
+func TestControllerOnlyPlaysHTTP(t *testing.T) {
+	local := newFakePlayer()
+	controller := NewController(local, &bytes.Buffer{})
+
+	for i, url := range []string{
+		"file:///etc/passwd", "-o=/tmp/x", "av://dshow:audio", "/dev/dsp",
+	} {
+		if err := controller.Handle(fmt.Sprintf("1;%d;PLAY;%s;70", i+1, url)); err == nil {
+			t.Errorf("Expected an error playing %q", url)
+		}
+		if station := local.GetCurrentStation(); station != nil {
+			t.Errorf("Expected %q not to be played, got %+v", url, station)
+		}
+	}
+
+	if err := controller.Handle("1;5;PLAY;https://jazz/stream;70"); err != nil {
+		t.Errorf("Expected an https stream to play, got %v", err)
+	}
+}
+
+func TestControllerRejectsOutOfOrderMessages(t *testing.T) {
+	var replies bytes.Buffer
+	local := newFakePlayer()
+	controller := NewController(local, &replies)
+
+	if err := controller.Handle("1;1;HELLO;1;play"); err != nil {
+		t.Fatalf("Failed to handle HELLO: %v", err)
+	}
+	if err := controller.Handle("1;3;PLAY;http://jazz;70"); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+	replies.Reset()
+
+	// Something else writing to the terminal replays or makes up
+	// messages the server has already moved past
+	for _, payload := range []string{
+		"1;3;STOP", "1;2;PLAY;http://evil;70", "1;4;HELLO;1;play",
+	} {
+		if err := controller.Handle(payload); !errors.Is(err, protocol.ErrMalformed) {
+			t.Errorf("Expected %q to be rejected, got %v", payload, err)
+		}
+	}
+	if station := local.GetCurrentStation(); station == nil || station.URLResolved != "http://jazz" {
+		t.Errorf("Expected the first stream to keep playing, got %+v", station)
+	}
+	if replies.Len() != 0 {
+		t.Errorf("Expected no replies to rejected messages, got %q", replies.String())
+	}
+}
+
+// lastEvent drains events and returns the last one.
+func lastEvent(events <-chan player.Event) player.Event {
+	var last player.Event
+	for len(events) > 0 {
+		last = <-events
+	}
+	return last
+}
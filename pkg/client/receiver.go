@@ -0,0 +1,164 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/protocol"
+)
+
+// maxSilence caps the silence inserted for lost PCM frames.
+const maxSilence = time.Second
+
+// Receiver plays the audio frames streamed by player.StreamingPlayer,
+// starting a decoder for each stream.
+type Receiver struct {
+	mu     sync.Mutex
+	codecs []string
+	start  func(protocol.Format) (io.WriteCloser, error)
+
+	decoder io.WriteCloser
+	// format is the format of the current stream, which is kept after
+	// the decoder is closed so the rest of the stream can be told apart.
+	format protocol.Format
+	// next is the expected sequence number and position of the next frame.
+	next     uint64
+	nextTime time.Duration
+	lost     uint64
+}
+
+// NewReceiver creates a Receiver for the given codecs, in order of
+// preference. start is called with the format of each new stream and
+// returns a decoder that plays the audio written to it.
+func NewReceiver(start func(protocol.Format) (io.WriteCloser, error), codecs ...string) *Receiver {
+	return &Receiver{codecs: codecs, start: start}
+}
+
+// Codecs returns the codecs the receiver can decode.
+func (r *Receiver) Codecs() []string {
+	return r.codecs
+}
+
+// Handle plays a frame. A new stream starts at frame 0, with a change of
+// format, or when the numbers go back. MP3 and PCM streams can be joined
+// at any frame, since every frame repeats the format; an Opus stream
+// carries its headers in the first frame only, so one whose start was
+// missed is dropped.
+func (r *Receiver) Handle(frame protocol.Frame) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if frame.Seq == 0 || frame.Format != r.format || frame.Seq < r.next {
+		r.closeLocked()
+		r.format = frame.Format
+		r.next, r.nextTime = frame.Seq, frame.Timestamp
+
+		if frame.Seq == 0 || joinable(frame.Format.Codec) {
+			if !r.supports(frame.Format.Codec) {
+				return fmt.Errorf("cannot decode %s audio", frame.Format.Codec)
+			}
+
+			decoder, err := r.start(frame.Format)
+			if err != nil {
+				return fmt.Errorf("failed to start decoder for %s: %w", frame.Format, err)
+			}
+			r.decoder = decoder
+		}
+	}
+
+	if r.decoder == nil {
+		// Keep track of the dropped stream, so its later frames are not
+		// taken for a new one
+		r.next = frame.Seq + 1
+		return nil
+	}
+
+	if frame.Seq > r.next {
+		r.lost += frame.Seq - r.next
+		// Compressed streams resynchronize on their own; raw audio needs
+		// the gap filled to keep its timing
+		if r.format.Codec == protocol.CodecPCM {
+			if err := r.writeSilenceLocked(frame.Timestamp - r.nextTime); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := r.decoder.Write(frame.Data); err != nil {
+		r.closeLocked()
+		return fmt.Errorf("failed to decode audio: %w", err)
+	}
+
+	r.next = frame.Seq + 1
+	r.nextTime = frame.Timestamp
+	if r.format.Codec == protocol.CodecPCM {
+		r.nextTime += pcmDuration(r.format, len(frame.Data))
+	}
+	return nil
+}
+
+// Lost returns how many frames have been lost so far.
+func (r *Receiver) Lost() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lost
+}
+
+// Stop closes the decoder of the current stream. The rest of the stream
+// is dropped.
+func (r *Receiver) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeLocked()
+}
+
+// closeLocked closes the decoder, if any (internal use).
+func (r *Receiver) closeLocked() {
+	if r.decoder != nil {
+		_ = r.decoder.Close()
+	}
+	r.decoder = nil
+}
+
+// joinable reports whether a stream in codec can be decoded from any
+// frame: MP3 decoders find the next frame header, and PCM frames hold
+// whole samples.
+func joinable(codec string) bool {
+	return codec == protocol.CodecMP3 || codec == protocol.CodecPCM
+}
+
+// supports reports whether codec is one of the receiver's.
+func (r *Receiver) supports(codec string) bool {
+	for _, c := range r.codecs {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSilenceLocked writes d of PCM silence, up to maxSilence.
+func (r *Receiver) writeSilenceLocked(d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	if d > maxSilence {
+		d = maxSilence
+	}
+
+	frameBytes := 2 * r.format.Channels
+	samples := int(d * time.Duration(r.format.SampleRate) / time.Second)
+	if _, err := r.decoder.Write(make([]byte, samples*frameBytes)); err != nil {
+		r.closeLocked()
+		return fmt.Errorf("failed to decode audio: %w", err)
+	}
+	return nil
+}
+
+// pcmDuration returns how long n bytes of 16-bit PCM in format last.
+func pcmDuration(format protocol.Format, n int) time.Duration {
+	bytesPerSecond := 2 * format.Channels * format.SampleRate
+	return time.Duration(n) * time.Second / time.Duration(bytesPerSecond)
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/protocol"
+)
+
+// fakeDecoder records the audio written to it.
+type fakeDecoder struct {
+	format protocol.Format
+	bytes.Buffer
+	closed bool
+}
+
+func (d *fakeDecoder) Close() error {
+	d.closed = true
+	return nil
+}
+
+// newTestReceiver returns a receiver that records the decoders it starts.
+func newTestReceiver(decoders *[]*fakeDecoder) *Receiver {
+	return NewReceiver(func(format protocol.Format) (io.WriteCloser, error) {
+		d := &fakeDecoder{format: format}
+		*decoders = append(*decoders, d)
+		return d, nil
+	}, protocol.CodecMP3, protocol.CodecPCM)
+}
+
+var (
+	mp3Format = protocol.Format{Codec: protocol.CodecMP3, SampleRate: 44100, Channels: 2}
+	pcmFormat = protocol.Format{Codec: protocol.CodecPCM, SampleRate: 1000, Channels: 1}
+)
+
+func TestReceiverStartsDecoderPerStream(t *testing.T) {
+	var decoders []*fakeDecoder
+	r := newTestReceiver(&decoders)
+
+	frames := []protocol.Frame{
+		// The start of this stream was missed; MP3 can be joined anywhere
+		{Format: mp3Format, Seq: 5, Data: []byte("f")},
+		{Format: mp3Format, Seq: 6, Data: []byte("g")},
+		// A new stream, such as after changing station
+		{Format: mp3Format, Seq: 0, Data: []byte("a")},
+		{Format: mp3Format, Seq: 1, Data: []byte("b")},
+		// Compressed audio is passed on after a gap
+		{Format: mp3Format, Seq: 4, Data: []byte("e")},
+		// A new stream whose first frames were lost
+		{Format: mp3Format, Seq: 2, Data: []byte("x")},
+	}
+	for _, frame := range frames {
+		if err := r.Handle(frame); err != nil {
+			t.Fatalf("Failed to handle frame %d: %v", frame.Seq, err)
+		}
+	}
+
+	if len(decoders) != 3 {
+		t.Fatalf("Expected 3 decoders, got %d", len(decoders))
+	}
+	for i, want := range []string{"fg", "abe", "x"} {
+		last := i == len(decoders)-1
+		if got := decoders[i].String(); got != want || decoders[i].closed == last {
+			t.Errorf("Expected decoder %d to play %q, got %q (closed %v)", i, want, got, decoders[i].closed)
+		}
+	}
+	if r.Lost() != 2 {
+		t.Errorf("Expected 2 lost frames, got %d", r.Lost())
+	}
+
+	// After a stop, the rest of the old stream is not played
+	r.Stop()
+	for seq := uint64(3); seq < 5; seq++ {
+		if err := r.Handle(protocol.Frame{Format: mp3Format, Seq: seq, Data: []byte("y")}); err != nil {
+			t.Fatalf("Failed to handle frame: %v", err)
+		}
+	}
+	if !decoders[2].closed || decoders[2].String() != "x" || len(decoders) != 3 {
+		t.Errorf("Expected nothing to play after a stop")
+	}
+}
+
+func TestReceiverJoinsOpusAtStart(t *testing.T) {
+	var decoders []*fakeDecoder
+	opus := protocol.Format{Codec: protocol.CodecOpus, SampleRate: 48000, Channels: 2}
+	r := NewReceiver(func(format protocol.Format) (io.WriteCloser, error) {
+		d := &fakeDecoder{format: format}
+		decoders = append(decoders, d)
+		return d, nil
+	}, protocol.CodecOpus)
+
+	frames := []protocol.Frame{
+		// Without the Ogg headers in frame 0 nothing can be decoded
+		{Format: opus, Seq: 3, Data: []byte("late")},
+		{Format: opus, Seq: 4, Data: []byte("late")},
+		{Format: opus, Seq: 0, Data: []byte("head")},
+		{Format: opus, Seq: 1, Data: []byte("er")},
+	}
+	for _, frame := range frames {
+		if err := r.Handle(frame); err != nil {
+			t.Fatalf("Failed to handle frame %d: %v", frame.Seq, err)
+		}
+	}
+
+	if len(decoders) != 1 || decoders[0].String() != "header" {
+		t.Errorf("Expected only the stream from frame 0 to play, got %d decoders", len(decoders))
+	}
+}
+
+func TestReceiverFillsPCMGaps(t *testing.T) {
+	var decoders []*fakeDecoder
+	r := newTestReceiver(&decoders)
+
+	// 1000 samples a second of mono 16-bit audio: 2 bytes a millisecond
+	chunk := bytes.Repeat([]byte{1}, 20)
+	frames := []protocol.Frame{
+		{Format: pcmFormat, Seq: 0, Timestamp: 0, Data: chunk},
+		{Format: pcmFormat, Seq: 1, Timestamp: 10 * time.Millisecond, Data: chunk},
+		// Frames 2 and 3 were lost
+		{Format: pcmFormat, Seq: 4, Timestamp: 40 * time.Millisecond, Data: chunk},
+		// A long gap is capped
+		{Format: pcmFormat, Seq: 9, Timestamp: time.Hour, Data: chunk},
+	}
+	for _, frame := range frames {
+		if err := r.Handle(frame); err != nil {
+			t.Fatalf("Failed to handle frame %d: %v", frame.Seq, err)
+		}
+	}
+
+	want := string(chunk) + string(chunk) + strings.Repeat("\x00", 40) + string(chunk) +
+		strings.Repeat("\x00", 2000) + string(chunk)
+	if got := decoders[0].String(); got != want {
+		t.Errorf("Expected %d bytes with silence in the gaps, got %d", len(want), len(got))
+	}
+
+	// Joining a stream late starts without any silence
+	late := newTestReceiver(&decoders)
+	if err := late.Handle(protocol.Frame{Format: pcmFormat, Seq: 7, Timestamp: time.Minute, Data: chunk}); err != nil {
+		t.Fatalf("Failed to handle frame: %v", err)
+	}
+	if got := decoders[len(decoders)-1].String(); got != string(chunk) || late.Lost() != 0 {
+		t.Errorf("Expected the joined stream to play from the frame, got %d bytes and %d lost", len(got), late.Lost())
+	}
+}
+
+func TestReceiverErrors(t *testing.T) {
+	var decoders []*fakeDecoder
+	r := newTestReceiver(&decoders)
+
+	opus := protocol.Format{Codec: protocol.CodecOpus, SampleRate: 48000, Channels: 2}
+	if err := r.Handle(protocol.Frame{Format: opus}); err == nil {
+		t.Errorf("Expected an error for a codec that was not offered")
+	}
+
+	failing := NewReceiver(func(protocol.Format) (io.WriteCloser, error) {
+		return nil, errors.New("ffplay not found")
+	}, protocol.CodecMP3)
+	if err := failing.Handle(protocol.Frame{Format: mp3Format}); err == nil || !strings.Contains(err.Error(), "ffplay not found") {
+		t.Errorf("Expected the decoder's error, got %v", err)
+	}
+	// The rest of the stream is dropped quietly
+	if err := failing.Handle(protocol.Frame{Format: mp3Format, Seq: 1}); err != nil {
+		t.Errorf("Expected no error for the rest of the stream, got %v", err)
+	}
+}
+
+func TestControllerStreamsAudio(t *testing.T) {
+	var replies bytes.Buffer
+	var decoders []*fakeDecoder
+
+	controller := NewController(newFakePlayer(), &replies)
+	controller.StreamTo(newTestReceiver(&decoders))
+
+	hello := protocol.Hello()
+	hello.Seq = 1
+	if err := controller.Handle(hello.Payload()); err != nil {
+		t.Fatalf("Failed to handle HELLO: %v", err)
+	}
+	if !strings.Contains(replies.String(), ";codec:mp3;codec:pcm\a") {
+		t.Errorf("Expected the HELLO reply to offer the receiver's codecs, got %q", replies.String())
+	}
+
+	frame := protocol.Frame{Format: mp3Format, Data: []byte("audio")}.Message()
+	frame.Seq = 2
+	if err := controller.Handle(frame.Payload()); err != nil {
+		t.Fatalf("Failed to handle FRAME: %v", err)
+	}
+	if len(decoders) != 1 || decoders[0].String() != "audio" {
+		t.Fatalf("Expected the frame to be played")
+	}
+
+	stop := protocol.Stop()
+	stop.Seq = 3
+	if err := controller.Handle(stop.Payload()); err != nil {
+		t.Fatalf("Failed to handle STOP: %v", err)
+	}
+	if !decoders[0].closed {
+		t.Errorf("Expected STOP to close the decoder")
+	}
+
+	// Without a receiver, audio is refused
+	plain := NewController(newFakePlayer(), &bytes.Buffer{})
+	if err := plain.Handle(frame.Payload()); err == nil {
+		t.Errorf("Expected an error for audio without a receiver")
+	}
+}
@@ -0,0 +1,105 @@
+// Package fuzzy matches patterns against text the way fuzzy finders do: the
+// pattern's characters must appear in the text in order, though not
+// necessarily together, and matches that are together or start words score
+// higher.
+package fuzzy
+
+import (
+	"unicode"
+)
+
+// Scoring of a match.
+const (
+	// scoreMatch is scored for each character matched.
+	scoreMatch = 16
+	// bonusBoundary is added for a character starting a word.
+	bonusBoundary = 8
+	// bonusConsecutive is added for a character right after the one
+	// matched before it.
+	bonusConsecutive = 8
+	// penaltyGap is taken off for each character skipped between two
+	// matched ones.
+	penaltyGap = 1
+)
+
+// Match reports whether pattern matches text, ignoring case, how well, and
+// which runes of text matched, by index. An empty pattern matches anything
+// with a score of 0.
+func Match(pattern, text string) (score int, positions []int, ok bool) {
+	p := []rune(pattern)
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+	for i, r := range p {
+		p[i] = unicode.ToLower(r)
+	}
+
+	t := []rune(text)
+	lower := make([]rune, len(t))
+	for i, r := range t {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// Find where the first match ends...
+	end, k := -1, 0
+	for i, r := range lower {
+		if r == p[k] {
+			k++
+			if k == len(p) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	// ...then work back to the latest start, for the tightest match
+	start, k := end, len(p)-1
+	for i := end; i >= 0; i-- {
+		if lower[i] == p[k] {
+			k--
+			if k < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	positions = make([]int, 0, len(p))
+	k = 0
+	for i := start; i <= end && k < len(p); i++ {
+		if lower[i] == p[k] {
+			positions = append(positions, i)
+			k++
+		}
+	}
+
+	for i, pos := range positions {
+		score += scoreMatch
+		if pos == 0 || isBoundary(t[pos-1], t[pos]) {
+			score += bonusBoundary
+		}
+		if i > 0 {
+			if gap := pos - positions[i-1] - 1; gap == 0 {
+				score += bonusConsecutive
+			} else {
+				score -= gap * penaltyGap
+			}
+		}
+	}
+	return score, positions, true
+}
+
+// isBoundary reports whether cur starts a word, following prev.
+func isBoundary(prev, cur rune) bool {
+	if !isWord(prev) {
+		return isWord(cur)
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
+
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,68 @@
+package fuzzy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		text      string
+		ok        bool
+		positions []int
+	}{
+		{"", "Radio", true, nil},
+		{"jazz", "Smooth Jazz", true, []int{7, 8, 9, 10}},
+		{"sj", "Smooth Jazz", true, []int{0, 7}},
+		{"rd", "Radio Deejay", true, []int{0, 2}},
+		{"zaj", "Smooth Jazz", false, nil},
+		{"jazzz", "Jazz", false, nil},
+		{"münch", "Radio MÜNCHEN", true, []int{6, 7, 8, 9, 10}},
+		{"étf", "Radio Été FM", true, []int{6, 7, 10}},
+		// The tightest match, not the first
+		{"ab", "a--ab", true, []int{3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" in "+tt.text, func(t *testing.T) {
+			_, positions, ok := Match(tt.pattern, tt.text)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok %v, got %v", tt.ok, ok)
+			}
+			if !reflect.DeepEqual(positions, tt.positions) {
+				t.Errorf("Expected positions %v, got %v", tt.positions, positions)
+			}
+		})
+	}
+}
+
+func TestMatchRanking(t *testing.T) {
+	tests := []struct {
+		pattern string
+		better  string
+		worse   string
+	}{
+		{"jazz", "Jazz FM", "J a z z"},
+		{"rock", "Classic Rock", "Frockton"},
+		{"bbc", "BBC Radio", "Big Beat Club"},
+		{"rr", "Rock Radio", "Rxxxxxxxr"},
+		{"fm", "Radio FM", "Radio Fresh Mix"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			better, _, ok := Match(tt.pattern, tt.better)
+			if !ok {
+				t.Fatalf("Expected %q to match %q", tt.pattern, tt.better)
+			}
+			worse, _, ok := Match(tt.pattern, tt.worse)
+			if !ok {
+				t.Fatalf("Expected %q to match %q", tt.pattern, tt.worse)
+			}
+			if better <= worse {
+				t.Errorf("Expected %q (%d) to score above %q (%d)", tt.better, better, tt.worse, worse)
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ func GetEmbeddedTranslations(locale string) map[string]string {
 		"station.playing":    "♪ Now Playing: %s",
 		"station.volume":     "Vol: %d%%",
 		"station.no_results": "No stations found",
+		"station.reconnect":  "Reconnecting to %s...",
 		"bookmark.empty":     "No bookmarks yet",
 		"bookmark.hint":      "Press 'a' on any station to bookmark it",
 		"bookmark.count":     "%d bookmarked stations",
@@ -26,6 +27,7 @@ func GetEmbeddedTranslations(locale string) map[string]string {
 		"search.searching":   "Searching...",
 		"search.results":     "Found %d stations (Tab to navigate results)",
 		"error.play_failed":  "Failed to play: %v",
+		"error.stream_lost":  "Playback stopped: %v",
 		"key.up":             "↑/k up",
 		"key.down":           "↓/j down",
 		"key.play":           "enter play",
@@ -59,6 +61,7 @@ func GetEmbeddedTranslations(locale string) map[string]string {
 		"station.playing":    "♪ In Riproduzione: %s",
 		"station.volume":     "Vol: %d%%",
 		"station.no_results": "Nessuna stazione trovata",
+		"station.reconnect":  "Riconnessione a %s...",
 		"bookmark.empty":     "Nessun preferito",
 		"bookmark.hint":      "Premi 'a' su una stazione per aggiungerla ai preferiti",
 		"bookmark.count":     "%d stazioni nei preferiti",
@@ -72,6 +75,7 @@ func GetEmbeddedTranslations(locale string) map[string]string {
 		"search.searching":   "Ricerca in corso...",
 		"search.results":     "Trovate %d stazioni (Tab per navigare i risultati)",
 		"error.play_failed":  "Riproduzione fallita: %v",
+		"error.stream_lost":  "Riproduzione interrotta: %v",
 		"key.up":             "↑/k su",
 		"key.down":           "↓/j giù",
 		"key.play":           "invio riproduci",
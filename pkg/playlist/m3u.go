@@ -0,0 +1,113 @@
+package playlist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// m3uAttribute matches key="value" pairs in an #EXTINF line.
+var m3uAttribute = regexp.MustCompile(`([A-Za-z0-9_-]+)="([^"]*)"`)
+
+// parseM3U reads a plain or extended M3U playlist.
+func parseM3U(r io.Reader) ([]radiobrowser.Station, error) {
+	var stations []radiobrowser.Station
+	var pending radiobrowser.Station
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+
+		switch {
+		case line == "":
+			continue
+
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// #EXTINF:-1 key="value",Station Name
+			pending = radiobrowser.Station{}
+			info := strings.TrimPrefix(line, "#EXTINF:")
+
+			attrs := info
+			if comma := firstUnquotedComma(info); comma >= 0 {
+				attrs = info[:comma]
+				pending.Name = info[comma+1:]
+			}
+
+			for _, match := range m3uAttribute.FindAllStringSubmatch(attrs, -1) {
+				switch match[1] {
+				case "radio-browser-uuid":
+					pending.StationUUID = match[2]
+				case "tvg-logo":
+					pending.Favicon = match[2]
+				case "group-title":
+					pending.Tags = match[2]
+				}
+			}
+
+		case strings.HasPrefix(line, "#"):
+			// Header or unsupported directive
+			continue
+
+		default:
+			pending.URL = line
+			stations = append(stations, pending)
+			pending = radiobrowser.Station{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read M3U playlist: %w", err)
+	}
+
+	return stations, nil
+}
+
+// firstUnquotedComma returns the index of the first comma outside quoted
+// attribute values, which separates the #EXTINF attributes from the title;
+// the title itself may contain more commas. It returns -1 if there is none.
+func firstUnquotedComma(s string) int {
+	inQuotes := false
+	for i, r := range s {
+		switch r {
+		case '"':
+			inQuotes = !inQuotes
+		case ',':
+			if !inQuotes {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// writeM3U writes an extended M3U playlist. The Radio Browser UUID is kept
+// as an #EXTINF attribute, which other players ignore.
+func writeM3U(w io.Writer, stations []radiobrowser.Station) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "#EXTM3U")
+	for _, station := range stations {
+		attrs := fmt.Sprintf(` radio-browser-uuid="%s"`, m3uEscape(station.StationUUID))
+		if station.Favicon != "" {
+			attrs += fmt.Sprintf(` tvg-logo="%s"`, m3uEscape(station.Favicon))
+		}
+		if station.Tags != "" {
+			attrs += fmt.Sprintf(` group-title="%s"`, m3uEscape(station.Tags))
+		}
+
+		name := strings.ReplaceAll(station.Name, "\n", " ")
+		fmt.Fprintf(bw, "#EXTINF:-1%s,%s\n", attrs, name)
+		fmt.Fprintln(bw, streamURL(station))
+	}
+
+	return bw.Flush()
+}
+
+// m3uEscape makes a value safe to use inside a quoted attribute.
+func m3uEscape(s string) string {
+	return strings.NewReplacer(`"`, "'", "\n", " ").Replace(s)
+}
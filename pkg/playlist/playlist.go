@@ -0,0 +1,148 @@
+// Package playlist reads and writes M3U, PLS and XSPF playlists of radio stations.
+package playlist
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// Format identifies a playlist file format.
+type Format int
+
+const (
+	// FormatM3U is an extended M3U playlist (.m3u, .m3u8).
+	FormatM3U Format = iota
+	// FormatPLS is a Winamp/Shoutcast PLS playlist (.pls).
+	FormatPLS
+	// FormatXSPF is an XML Shareable Playlist Format playlist (.xspf).
+	FormatXSPF
+)
+
+// String returns the format name.
+func (f Format) String() string {
+	switch f {
+	case FormatM3U:
+		return "m3u"
+	case FormatPLS:
+		return "pls"
+	case FormatXSPF:
+		return "xspf"
+	default:
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+}
+
+// ParseFormat parses a format name such as "m3u", "pls" or "xspf".
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "m3u", "m3u8":
+		return FormatM3U, nil
+	case "pls":
+		return FormatPLS, nil
+	case "xspf":
+		return FormatXSPF, nil
+	default:
+		return 0, fmt.Errorf("unsupported playlist format: %s (must be 'm3u', 'pls' or 'xspf')", name)
+	}
+}
+
+// FormatFromPath guesses the format from a file extension.
+func FormatFromPath(path string) (Format, bool) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" {
+		return 0, false
+	}
+	format, err := ParseFormat(ext)
+	return format, err == nil
+}
+
+// Parse reads stations from a playlist. Entries without a Radio Browser
+// UUID get a synthetic one derived from their URL.
+func Parse(r io.Reader, format Format) ([]radiobrowser.Station, error) {
+	var stations []radiobrowser.Station
+	var err error
+
+	switch format {
+	case FormatM3U:
+		stations, err = parseM3U(r)
+	case FormatPLS:
+		stations, err = parsePLS(r)
+	case FormatXSPF:
+		stations, err = parseXSPF(r)
+	default:
+		return nil, fmt.Errorf("unsupported playlist format: %v", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range stations {
+		normalize(&stations[i])
+	}
+
+	return stations, nil
+}
+
+// Write writes stations as a playlist.
+func Write(w io.Writer, format Format, stations []radiobrowser.Station) error {
+	switch format {
+	case FormatM3U:
+		return writeM3U(w, stations)
+	case FormatPLS:
+		return writePLS(w, stations)
+	case FormatXSPF:
+		return writeXSPF(w, stations)
+	default:
+		return fmt.Errorf("unsupported playlist format: %v", format)
+	}
+}
+
+// urlNamespace is the RFC 4122 namespace for URLs.
+var urlNamespace = [16]byte{
+	0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1,
+	0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8,
+}
+
+// SyntheticUUID returns a stable name-based (version 5) UUID for a stream
+// URL, so that stations found outside Radio Browser can still be keyed by
+// UUID. The same URL always yields the same UUID.
+func SyntheticUUID(url string) string {
+	h := sha1.New()
+	h.Write(urlNamespace[:])
+	h.Write([]byte(strings.TrimSpace(url)))
+	sum := h.Sum(nil)
+
+	sum[6] = (sum[6] & 0x0f) | 0x50 // Version 5
+	sum[8] = (sum[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// normalize fills in fields that playlists commonly omit.
+func normalize(station *radiobrowser.Station) {
+	station.URL = strings.TrimSpace(station.URL)
+	station.Name = strings.TrimSpace(station.Name)
+
+	if station.URLResolved == "" {
+		station.URLResolved = station.URL
+	}
+	if station.Name == "" {
+		station.Name = station.URL
+	}
+	if station.StationUUID == "" {
+		station.StationUUID = SyntheticUUID(station.URL)
+	}
+}
+
+// streamURL returns the URL to write for a station.
+func streamURL(station radiobrowser.Station) string {
+	if station.URL != "" {
+		return station.URL
+	}
+	return station.URLResolved
+}
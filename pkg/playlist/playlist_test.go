@@ -0,0 +1,201 @@
+package playlist
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestSyntheticUUID(t *testing.T) {
+	a := SyntheticUUID("http://jazz.example/stream")
+	b := SyntheticUUID(" http://jazz.example/stream ")
+	c := SyntheticUUID("http://rock.example/stream")
+
+	if !uuidPattern.MatchString(a) {
+		t.Errorf("Expected a version 5 UUID, got %q", a)
+	}
+	if a != b {
+		t.Errorf("Expected the same URL to give the same UUID, got %q and %q", a, b)
+	}
+	if a == c {
+		t.Errorf("Expected different URLs to give different UUIDs")
+	}
+
+	// Matches uuid5(NAMESPACE_URL, ...) from other implementations
+	if got := SyntheticUUID("http://www.example.com/"); got != "fcde3c85-2270-590f-9e7c-ee003d65e0e2" {
+		t.Errorf("Unexpected UUID for example.com: %s", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		want   []radiobrowser.Station
+	}{
+		{
+			name:   "extended m3u",
+			format: FormatM3U,
+			input: "#EXTM3U\n" +
+				"#EXTINF:-1 radio-browser-uuid=\"jazz\" tvg-logo=\"http://jazz/icon.png\",Jazz, Blues & More\n" +
+				"http://jazz/stream\n" +
+				"\n" +
+				"#EXTINF:-1,Rock FM\n" +
+				"http://rock/stream\n",
+			want: []radiobrowser.Station{
+				{StationUUID: "jazz", Name: "Jazz, Blues & More", URL: "http://jazz/stream", URLResolved: "http://jazz/stream", Favicon: "http://jazz/icon.png"},
+				{StationUUID: SyntheticUUID("http://rock/stream"), Name: "Rock FM", URL: "http://rock/stream", URLResolved: "http://rock/stream"},
+			},
+		},
+		{
+			name:   "plain m3u",
+			format: FormatM3U,
+			input:  "http://plain/stream\r\n",
+			want: []radiobrowser.Station{
+				{StationUUID: SyntheticUUID("http://plain/stream"), Name: "http://plain/stream", URL: "http://plain/stream", URLResolved: "http://plain/stream"},
+			},
+		},
+		{
+			name:   "pls out of order",
+			format: FormatPLS,
+			input: "[playlist]\n" +
+				"Title2=Rock FM\n" +
+				"File2=http://rock/stream\n" +
+				"File1=http://jazz/stream\n" +
+				"Title1=Jazz Radio\n" +
+				"Length1=-1\n" +
+				"NumberOfEntries=2\n" +
+				"Version=2\n",
+			want: []radiobrowser.Station{
+				{StationUUID: SyntheticUUID("http://jazz/stream"), Name: "Jazz Radio", URL: "http://jazz/stream", URLResolved: "http://jazz/stream"},
+				{StationUUID: SyntheticUUID("http://rock/stream"), Name: "Rock FM", URL: "http://rock/stream", URLResolved: "http://rock/stream"},
+			},
+		},
+		{
+			name:   "xspf",
+			format: FormatXSPF,
+			input: `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <trackList>
+    <track>
+      <location>http://jazz/stream</location>
+      <title>Jazz Radio</title>
+      <identifier>urn:uuid:jazz</identifier>
+      <info>http://jazz</info>
+    </track>
+    <track>
+      <location>http://rock/stream</location>
+    </track>
+  </trackList>
+</playlist>`,
+			want: []radiobrowser.Station{
+				{StationUUID: "jazz", Name: "Jazz Radio", URL: "http://jazz/stream", URLResolved: "http://jazz/stream", Homepage: "http://jazz"},
+				{StationUUID: SyntheticUUID("http://rock/stream"), Name: "http://rock/stream", URL: "http://rock/stream", URLResolved: "http://rock/stream"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("Failed to parse playlist: %v", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d stations, got %d: %+v", len(tt.want), len(got), got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("Station %d: expected %+v, got %+v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidPlaylists(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{"pls without header", FormatPLS, "File1=http://jazz/stream\n"},
+		{"broken xspf", FormatXSPF, "<playlist><trackList>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.input), tt.format); err == nil {
+				t.Errorf("Expected error parsing %q", tt.input)
+			}
+		})
+	}
+}
+
+func TestWriteParseRoundTrip(t *testing.T) {
+	stations := []radiobrowser.Station{
+		{StationUUID: "jazz", Name: "Jazz \"Cool\" Radio", URL: "http://jazz/stream", URLResolved: "http://jazz/stream", Favicon: "http://jazz/icon.png"},
+		{StationUUID: "rock", Name: "Rock & Roll", URL: "http://rock/stream?a=1&b=2", URLResolved: "http://rock/stream?a=1&b=2"},
+	}
+
+	for _, format := range []Format{FormatM3U, FormatPLS, FormatXSPF} {
+		t.Run(format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, format, stations); err != nil {
+				t.Fatalf("Failed to write playlist: %v", err)
+			}
+
+			got, err := Parse(&buf, format)
+			if err != nil {
+				t.Fatalf("Failed to parse written playlist: %v", err)
+			}
+			if len(got) != len(stations) {
+				t.Fatalf("Expected %d stations, got %d", len(stations), len(got))
+			}
+
+			for i, want := range stations {
+				if got[i].Name != want.Name || got[i].URL != want.URL {
+					t.Errorf("Station %d: expected %q at %q, got %q at %q", i, want.Name, want.URL, got[i].Name, got[i].URL)
+				}
+
+				// PLS has nowhere to keep the UUID
+				wantUUID := want.StationUUID
+				if format == FormatPLS {
+					wantUUID = SyntheticUUID(want.URL)
+				}
+				if got[i].StationUUID != wantUUID {
+					t.Errorf("Station %d: expected UUID %q, got %q", i, wantUUID, got[i].StationUUID)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want Format
+		ok   bool
+	}{
+		{"radio.m3u", FormatM3U, true},
+		{"radio.M3U8", FormatM3U, true},
+		{"/tmp/radio.pls", FormatPLS, true},
+		{"radio.xspf", FormatXSPF, true},
+		{"bookmarks.json", 0, false},
+		{"-", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := FormatFromPath(tt.path)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("FormatFromPath(%q) = %v, %v; expected %v, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package playlist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// parsePLS reads a PLS playlist. Entries are keyed by their number, so
+// File2/Title2 pairs may appear in any order.
+func parsePLS(r io.Reader) ([]radiobrowser.Station, error) {
+	entries := make(map[int]*radiobrowser.Station)
+	sawHeader := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.EqualFold(line, "[playlist]") {
+			sawHeader = true
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		var field string
+		switch {
+		case strings.HasPrefix(key, "file"):
+			field = "file"
+		case strings.HasPrefix(key, "title"):
+			field = "title"
+		default:
+			// NumberOfEntries, Version, LengthN
+			continue
+		}
+
+		n, err := strconv.Atoi(strings.TrimPrefix(key, field))
+		if err != nil {
+			continue
+		}
+
+		entry, ok := entries[n]
+		if !ok {
+			entry = &radiobrowser.Station{}
+			entries[n] = entry
+		}
+
+		if field == "file" {
+			entry.URL = strings.TrimSpace(value)
+		} else {
+			entry.Name = strings.TrimSpace(value)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read PLS playlist: %w", err)
+	}
+
+	if !sawHeader {
+		return nil, fmt.Errorf("not a PLS playlist: missing [playlist] header")
+	}
+
+	numbers := make([]int, 0, len(entries))
+	for n := range entries {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	stations := make([]radiobrowser.Station, 0, len(numbers))
+	for _, n := range numbers {
+		if entries[n].URL == "" {
+			continue
+		}
+		stations = append(stations, *entries[n])
+	}
+
+	return stations, nil
+}
+
+// writePLS writes a version 2 PLS playlist.
+func writePLS(w io.Writer, stations []radiobrowser.Station) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "[playlist]")
+	for i, station := range stations {
+		n := i + 1
+		fmt.Fprintf(bw, "File%d=%s\n", n, streamURL(station))
+		fmt.Fprintf(bw, "Title%d=%s\n", n, strings.ReplaceAll(station.Name, "\n", " "))
+		fmt.Fprintf(bw, "Length%d=-1\n", n)
+	}
+	fmt.Fprintf(bw, "NumberOfEntries=%d\n", len(stations))
+	fmt.Fprintln(bw, "Version=2")
+
+	return bw.Flush()
+}
@@ -0,0 +1,99 @@
+package playlist
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// xspfNamespace is the XSPF version 1 XML namespace.
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+// xspfUUIDPrefix marks track identifiers holding a Radio Browser UUID.
+const xspfUUIDPrefix = "urn:uuid:"
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location   string `xml:"location"`
+	Title      string `xml:"title,omitempty"`
+	Identifier string `xml:"identifier,omitempty"`
+	Image      string `xml:"image,omitempty"`
+	Info       string `xml:"info,omitempty"`
+	Annotation string `xml:"annotation,omitempty"`
+}
+
+// parseXSPF reads an XSPF playlist.
+func parseXSPF(r io.Reader) ([]radiobrowser.Station, error) {
+	var doc xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to read XSPF playlist: %w", err)
+	}
+
+	stations := make([]radiobrowser.Station, 0, len(doc.Tracks))
+	for _, track := range doc.Tracks {
+		if strings.TrimSpace(track.Location) == "" {
+			continue
+		}
+
+		station := radiobrowser.Station{
+			URL:      track.Location,
+			Name:     track.Title,
+			Favicon:  track.Image,
+			Homepage: track.Info,
+			Tags:     track.Annotation,
+		}
+		if strings.HasPrefix(track.Identifier, xspfUUIDPrefix) {
+			station.StationUUID = strings.TrimPrefix(track.Identifier, xspfUUIDPrefix)
+		}
+
+		stations = append(stations, station)
+	}
+
+	return stations, nil
+}
+
+// writeXSPF writes an XSPF playlist.
+func writeXSPF(w io.Writer, stations []radiobrowser.Station) error {
+	doc := xspfPlaylist{
+		Version: "1",
+		Xmlns:   xspfNamespace,
+		Title:   "Terminal.FM",
+		Tracks:  make([]xspfTrack, 0, len(stations)),
+	}
+
+	for _, station := range stations {
+		track := xspfTrack{
+			Location:   streamURL(station),
+			Title:      station.Name,
+			Image:      station.Favicon,
+			Info:       station.Homepage,
+			Annotation: station.Tags,
+		}
+		if station.StationUUID != "" {
+			track.Identifier = xspfUUIDPrefix + station.StationUUID
+		}
+		doc.Tracks = append(doc.Tracks, track)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write XSPF playlist: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
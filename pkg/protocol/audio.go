@@ -0,0 +1,140 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TypeFrame carries a chunk of audio streamed by the server.
+const TypeFrame = "FRAME"
+
+// Audio codecs. A side announces each codec it can stream or decode as a
+// HELLO capability made of CapCodecPrefix and the codec name, in order of
+// preference.
+const (
+	// CodecOpus is Opus in an Ogg container.
+	CodecOpus = "opus"
+	// CodecMP3 is an MPEG-1 Layer III elementary stream.
+	CodecMP3 = "mp3"
+	// CodecPCM is raw signed 16-bit little-endian PCM.
+	CodecPCM = "pcm"
+)
+
+// CapCodecPrefix starts the capabilities naming audio codecs.
+const CapCodecPrefix = "codec:"
+
+// MaxFrameData is the largest chunk of audio a frame may carry, so that the
+// encoded frame fits in a Filter.
+const MaxFrameData = 4096
+
+// CodecCaps returns the HELLO capabilities announcing codecs.
+func CodecCaps(codecs ...string) []string {
+	caps := make([]string, len(codecs))
+	for i, codec := range codecs {
+		caps[i] = CapCodecPrefix + codec
+	}
+	return caps
+}
+
+// Codecs returns the codecs announced in caps, in order.
+func Codecs(caps []string) []string {
+	var codecs []string
+	for _, c := range caps {
+		if codec, ok := strings.CutPrefix(c, CapCodecPrefix); ok && codec != "" {
+			codecs = append(codecs, codec)
+		}
+	}
+	return codecs
+}
+
+// NegotiateCodec returns the first of ours, in our order of preference, that
+// the peer also offered in theirs.
+func NegotiateCodec(ours, theirs []string) (string, bool) {
+	for _, codec := range ours {
+		for _, other := range theirs {
+			if codec == other {
+				return codec, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Format describes the audio in a stream.
+type Format struct {
+	Codec      string
+	SampleRate int
+	Channels   int
+}
+
+// String returns the format as "codec rate/channels".
+func (f Format) String() string {
+	return fmt.Sprintf("%s %d/%d", f.Codec, f.SampleRate, f.Channels)
+}
+
+// Frame is a chunk of an audio stream. Every frame repeats the format, so a
+// receiver can start decoding at the beginning of any stream.
+type Frame struct {
+	Format Format
+	// Seq numbers the frames of a stream from 0; a new stream, such as
+	// after changing station, starts again from 0. A gap in the numbers
+	// means frames were lost.
+	Seq uint64
+	// Timestamp is the position in the stream of the first sample in Data.
+	Timestamp time.Duration
+	Data      []byte
+}
+
+// Message returns the FRAME message for f. The timestamp travels in
+// milliseconds and the data in base64, whose alphabet is left alone by
+// escaping.
+func (f Frame) Message() Message {
+	return Message{Type: TypeFrame, Args: []string{
+		f.Format.Codec,
+		strconv.Itoa(f.Format.SampleRate),
+		strconv.Itoa(f.Format.Channels),
+		strconv.FormatUint(f.Seq, 10),
+		strconv.FormatInt(f.Timestamp.Milliseconds(), 10),
+		base64.StdEncoding.EncodeToString(f.Data),
+	}}
+}
+
+// ParseFrame returns the frame in a FRAME message.
+func ParseFrame(msg Message) (Frame, error) {
+	if msg.Type != TypeFrame || len(msg.Args) != 6 || msg.Args[0] == "" {
+		return Frame{}, fmt.Errorf("%w: expected FRAME with 6 fields", ErrMalformed)
+	}
+
+	var numbers [4]int64
+	for i, field := range msg.Args[1:5] {
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || n < 0 {
+			return Frame{}, fmt.Errorf("%w: invalid frame field %q", ErrMalformed, field)
+		}
+		numbers[i] = n
+	}
+	if numbers[0] == 0 || numbers[1] == 0 || numbers[0] > 1<<20 || numbers[1] > 64 {
+		return Frame{}, fmt.Errorf("%w: invalid format %s/%s", ErrMalformed, msg.Args[1], msg.Args[2])
+	}
+	if numbers[3] > int64(time.Duration(1<<62)/time.Millisecond) {
+		return Frame{}, fmt.Errorf("%w: invalid timestamp %s", ErrMalformed, msg.Args[4])
+	}
+
+	data, err := base64.StdEncoding.DecodeString(msg.Args[5])
+	if err != nil {
+		return Frame{}, fmt.Errorf("%w: invalid frame data: %v", ErrMalformed, err)
+	}
+	if len(data) > MaxFrameData {
+		return Frame{}, fmt.Errorf("%w: frame of %d bytes", ErrMalformed, len(data))
+	}
+
+	return Frame{
+		Format:    Format{Codec: msg.Args[0], SampleRate: int(numbers[0]), Channels: int(numbers[1])},
+		Seq:       uint64(numbers[2]),
+		Timestamp: time.Duration(numbers[3]) * time.Millisecond,
+		Data:      data,
+	}, nil
+}
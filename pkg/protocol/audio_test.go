@@ -0,0 +1,113 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	data := make([]byte, MaxFrameData)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	frames := []Frame{
+		{Format: Format{Codec: CodecOpus, SampleRate: 48000, Channels: 2}, Seq: 0, Data: []byte("OggS")},
+		{Format: Format{Codec: CodecPCM, SampleRate: 44100, Channels: 1}, Seq: 41, Timestamp: 1500 * time.Millisecond, Data: data},
+		{Format: Format{Codec: CodecMP3, SampleRate: 44100, Channels: 2}, Seq: 7, Timestamp: time.Hour, Data: []byte{}},
+	}
+
+	var payloads []string
+	filter := NewFilter(&bytes.Buffer{}, func(payload string) {
+		payloads = append(payloads, payload)
+	})
+	for _, frame := range frames {
+		if _, err := filter.Write(Encode(frame.Message())); err != nil {
+			t.Fatalf("Failed to write frame: %v", err)
+		}
+	}
+
+	if len(payloads) != len(frames) {
+		t.Fatalf("Expected %d payloads, got %d", len(frames), len(payloads))
+	}
+
+	for i, payload := range payloads {
+		msg, err := Decode(payload)
+		if err != nil {
+			t.Fatalf("Failed to decode frame %d: %v", i, err)
+		}
+		frame, err := ParseFrame(msg)
+		if err != nil {
+			t.Fatalf("Failed to parse frame %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(frame, frames[i]) {
+			t.Errorf("Expected frame %d to be %+v, got %+v", i, frames[i].Format, frame.Format)
+		}
+	}
+}
+
+func TestParseFrameErrors(t *testing.T) {
+	valid := Frame{Format: Format{Codec: CodecPCM, SampleRate: 44100, Channels: 2}, Data: []byte("abcd")}.Message().Args
+
+	tests := []struct {
+		name  string
+		field int
+		value string
+	}{
+		{"empty codec", 0, ""},
+		{"zero rate", 1, "0"},
+		{"huge rate", 1, "99999999"},
+		{"zero channels", 2, "0"},
+		{"negative sequence", 3, "-1"},
+		{"text timestamp", 4, "soon"},
+		{"huge timestamp", 4, "9223372036854775807"},
+		{"invalid base64", 5, "%%%"},
+		{"oversized data", 5, Frame{Data: make([]byte, MaxFrameData+1)}.Message().Args[5]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{}, valid...)
+			args[tt.field] = tt.value
+
+			if _, err := ParseFrame(Message{Type: TypeFrame, Args: args}); !errors.Is(err, ErrMalformed) {
+				t.Errorf("Expected ErrMalformed, got %v", err)
+			}
+		})
+	}
+
+	if _, err := ParseFrame(Message{Type: TypeFrame, Args: valid[:5]}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Expected ErrMalformed for a missing field, got %v", err)
+	}
+	if _, err := ParseFrame(Stop()); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Expected ErrMalformed for a STOP, got %v", err)
+	}
+}
+
+func TestNegotiateCodec(t *testing.T) {
+	caps := append([]string{CapPlay, "codec:"}, CodecCaps(CodecMP3, CodecPCM)...)
+	if got := Codecs(caps); fmt.Sprint(got) != "[mp3 pcm]" {
+		t.Errorf("Expected codecs [mp3 pcm], got %v", got)
+	}
+
+	tests := []struct {
+		ours, theirs []string
+		want         string
+	}{
+		{[]string{CodecOpus, CodecMP3, CodecPCM}, []string{CodecPCM, CodecMP3}, CodecMP3},
+		{[]string{CodecOpus, CodecMP3, CodecPCM}, []string{CodecPCM, CodecOpus}, CodecOpus},
+		{[]string{CodecOpus}, []string{CodecMP3}, ""},
+		{[]string{CodecOpus}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := NegotiateCodec(tt.ours, tt.theirs)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("NegotiateCodec(%v, %v): expected %q, got %q (%v)", tt.ours, tt.theirs, tt.want, got, ok)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package protocol
+
+import (
+	"bytes"
+	"io"
+)
+
+// Messages are framed as ESC ] 8888 ; payload BEL. The string terminator
+// (ESC \) is accepted as well as BEL.
+const (
+	oscPrefix = "\033]8888;"
+	bel       = byte('\a')
+	esc       = byte('\033')
+)
+
+// maxPayloadLength bounds how much of an unterminated message is buffered.
+// Longer sequences are not ours and are passed through.
+const maxPayloadLength = 8192
+
+// Filter is an io.Writer that removes messages from a byte stream and
+// passes everything else through to out unchanged.
+//
+// Sequences may be split across writes at any byte; incomplete ones are
+// held back until the next Write or Flush.
+type Filter struct {
+	out    io.Writer
+	handle func(payload string)
+
+	// pending holds a possible message start (a prefix of oscPrefix) or a
+	// message that has not been terminated yet.
+	pending []byte
+}
+
+// NewFilter creates a Filter that writes to out and calls handle with the
+// payload of every message, in stream order. Payloads are not decoded, so
+// messages of any version are seen.
+func NewFilter(out io.Writer, handle func(payload string)) *Filter {
+	return &Filter{out: out, handle: handle}
+}
+
+// Write implements io.Writer. It reports len(p) on success even though
+// fewer bytes reach out.
+func (f *Filter) Write(p []byte) (int, error) {
+	n := len(p)
+	var passthrough []byte
+
+	for len(p) > 0 {
+		if len(f.pending) == 0 {
+			// Fast path: copy everything up to the next ESC
+			i := bytes.IndexByte(p, esc)
+			if i < 0 {
+				passthrough = append(passthrough, p...)
+				break
+			}
+			passthrough = append(passthrough, p[:i]...)
+			p = p[i:]
+		}
+
+		b := p[0]
+		p = p[1:]
+
+		if len(f.pending) < len(oscPrefix) {
+			// Matching the prefix
+			if b == oscPrefix[len(f.pending)] {
+				f.pending = append(f.pending, b)
+				continue
+			}
+
+			// Not ours: release what was held back and look at b again
+			passthrough = append(passthrough, f.pending...)
+			f.pending = f.pending[:0]
+			if b == esc {
+				f.pending = append(f.pending, b)
+			} else {
+				passthrough = append(passthrough, b)
+			}
+			continue
+		}
+
+		// Inside a message
+		f.pending = append(f.pending, b)
+
+		if end, ok := f.terminated(); ok {
+			payload := string(f.pending[len(oscPrefix):end])
+			f.pending = f.pending[:0]
+
+			// Keep output that preceded the message ahead of its effects
+			if err := f.writeOut(&passthrough); err != nil {
+				return 0, err
+			}
+			f.handle(payload)
+			continue
+		}
+
+		if len(f.pending) > maxPayloadLength {
+			passthrough = append(passthrough, f.pending...)
+			f.pending = f.pending[:0]
+		}
+	}
+
+	if err := f.writeOut(&passthrough); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// terminated reports whether pending ends with BEL or ESC \, and where the
+// payload ends.
+func (f *Filter) terminated() (int, bool) {
+	n := len(f.pending)
+	if f.pending[n-1] == bel {
+		return n - 1, true
+	}
+	if n >= len(oscPrefix)+2 && f.pending[n-2] == esc && f.pending[n-1] == '\\' {
+		return n - 2, true
+	}
+	return 0, false
+}
+
+// writeOut writes buf to out and empties it.
+func (f *Filter) writeOut(buf *[]byte) error {
+	if len(*buf) == 0 {
+		return nil
+	}
+	_, err := f.out.Write(*buf)
+	*buf = (*buf)[:0]
+	return err
+}
+
+// Flush passes any held back bytes through, for use when the stream ends
+// in the middle of a sequence.
+func (f *Filter) Flush() error {
+	err := f.writeOut(&f.pending)
+	f.pending = nil
+	return err
+}
+
+// flushPrefix passes held back bytes through unless they are part of a
+// message already known to be one.
+func (f *Filter) flushPrefix() error {
+	if len(f.pending) >= len(oscPrefix) {
+		return nil
+	}
+	return f.writeOut(&f.pending)
+}
+
+// reader is the io.Reader returned by NewReader.
+type reader struct {
+	r      io.Reader
+	filter *Filter
+	buf    bytes.Buffer
+	chunk  []byte
+	err    error
+}
+
+// NewReader returns a reader that yields the bytes of r with messages
+// removed, calling handle with each payload.
+//
+// Unlike a Filter, it does not hold back a lone ESC at the end of a read,
+// since on terminal input that is the Escape key. Writers must therefore
+// send each message's ESC ] 8888 ; introducer in a single write.
+func NewReader(r io.Reader, handle func(payload string)) io.Reader {
+	rd := &reader{r: r, chunk: make([]byte, 4096)}
+	rd.filter = NewFilter(&rd.buf, handle)
+	return rd
+}
+
+// Read implements io.Reader.
+func (r *reader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 && r.err == nil {
+		n, err := r.r.Read(r.chunk)
+		if _, ferr := r.filter.Write(r.chunk[:n]); ferr != nil {
+			return 0, ferr
+		}
+		_ = r.filter.flushPrefix()
+
+		if err != nil {
+			_ = r.filter.Flush()
+			r.err = err
+		}
+	}
+
+	if r.buf.Len() > 0 {
+		return r.buf.Read(p)
+	}
+	return 0, r.err
+}
@@ -0,0 +1,293 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// recorder collects a Filter's output and payloads.
+type recorder struct {
+	out      bytes.Buffer
+	commands []string
+}
+
+func (r *recorder) filter() *Filter {
+	return NewFilter(&r.out, func(payload string) {
+		r.commands = append(r.commands, payload)
+	})
+}
+
+var filterTests = []struct {
+	name     string
+	input    string
+	output   string
+	commands []string
+}{
+	{
+		name:   "plain text",
+		input:  "hello, world\r\n",
+		output: "hello, world\r\n",
+	},
+	{
+		name:     "single command",
+		input:    "\033]8888;STOP\a",
+		commands: []string{"STOP"},
+	},
+	{
+		name:     "commands between output",
+		input:    "before\033]8888;PLAY;http://jazz/stream;70\aafter\033]8888;VOLUME;50\aend",
+		output:   "beforeafterend",
+		commands: []string{"PLAY;http://jazz/stream;70", "VOLUME;50"},
+	},
+	{
+		name:     "string terminator",
+		input:    "a\033]8888;PAUSE\033\\b",
+		output:   "ab",
+		commands: []string{"PAUSE"},
+	},
+	{
+		name:   "other escape sequences",
+		input:  "\033[2J\033[H\033]0;Terminal.FM\a\033]8;;http://x\033\\link\033]8;;\033\\",
+		output: "\033[2J\033[H\033]0;Terminal.FM\a\033]8;;http://x\033\\link\033]8;;\033\\",
+	},
+	{
+		name:   "similar OSC number",
+		input:  "\033]88888;STOP\a\033]888;STOP\a",
+		output: "\033]88888;STOP\a\033]888;STOP\a",
+	},
+	{
+		name:     "escape right before a command",
+		input:    "\033\033]8888;RESUME\a\033",
+		output:   "\033\033",
+		commands: []string{"RESUME"},
+	},
+	{
+		name:     "empty command",
+		input:    "\033]8888;\a",
+		commands: []string{""},
+	},
+	{
+		name:   "unicode",
+		input:  "♫ Jazz Radio ♫\033]8888;PLAY;http://jäzz/♫;70\a✓",
+		output: "♫ Jazz Radio ♫✓",
+		commands: []string{
+			"PLAY;http://jäzz/♫;70",
+		},
+	},
+}
+
+func TestFilterWholeWrites(t *testing.T) {
+	for _, tt := range filterTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r recorder
+			f := r.filter()
+
+			n, err := f.Write([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Failed to write: %v", err)
+			}
+			if n != len(tt.input) {
+				t.Errorf("Expected Write to report %d bytes, got %d", len(tt.input), n)
+			}
+			if err := f.Flush(); err != nil {
+				t.Fatalf("Failed to flush: %v", err)
+			}
+
+			checkFiltered(t, &r, tt.output, tt.commands)
+		})
+	}
+}
+
+func TestFilterSplitWrites(t *testing.T) {
+	for _, tt := range filterTests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Every possible split into two reads
+			for i := 0; i <= len(tt.input); i++ {
+				var r recorder
+				f := r.filter()
+
+				_, _ = f.Write([]byte(tt.input[:i]))
+				_, _ = f.Write([]byte(tt.input[i:]))
+				_ = f.Flush()
+
+				if r.out.String() != tt.output || fmt.Sprint(r.commands) != fmt.Sprint(tt.commands) {
+					t.Fatalf("Split at %d: expected output %q and commands %q, got %q and %q",
+						i, tt.output, tt.commands, r.out.String(), r.commands)
+				}
+			}
+
+			// One byte at a time
+			var r recorder
+			f := r.filter()
+			for i := 0; i < len(tt.input); i++ {
+				_, _ = f.Write([]byte{tt.input[i]})
+			}
+			_ = f.Flush()
+
+			checkFiltered(t, &r, tt.output, tt.commands)
+		})
+	}
+}
+
+func TestFilterHoldsBackPartialSequences(t *testing.T) {
+	var r recorder
+	f := r.filter()
+
+	_, _ = f.Write([]byte("text\033]88"))
+	if r.out.String() != "text" {
+		t.Errorf("Expected a possible command start to be held back, got %q", r.out.String())
+	}
+
+	_, _ = f.Write([]byte("88;PLAY;http://x"))
+	if r.out.String() != "text" || len(r.commands) != 0 {
+		t.Errorf("Expected an unterminated command to be held back, got %q and %q", r.out.String(), r.commands)
+	}
+
+	_, _ = f.Write([]byte(";70\amore"))
+	checkFiltered(t, &r, "textmore", []string{"PLAY;http://x;70"})
+
+	// A stream ending mid-sequence releases the bytes on Flush
+	_, _ = f.Write([]byte("\033]8888;STO"))
+	if err := f.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	checkFiltered(t, &r, "textmore\033]8888;STO", []string{"PLAY;http://x;70"})
+}
+
+func TestFilterPassesThroughOverlongSequences(t *testing.T) {
+	var r recorder
+	f := r.filter()
+
+	long := "\033]8888;" + strings.Repeat("x", maxPayloadLength)
+	_, _ = f.Write([]byte(long))
+	_, _ = f.Write([]byte("\a"))
+
+	checkFiltered(t, &r, long+"\a", nil)
+}
+
+func checkFiltered(t *testing.T, r *recorder, output string, commands []string) {
+	t.Helper()
+
+	if r.out.String() != output {
+		t.Errorf("Expected output %q, got %q", output, r.out.String())
+	}
+	if fmt.Sprint(r.commands) != fmt.Sprint(commands) {
+		t.Errorf("Expected commands %q, got %q", commands, r.commands)
+	}
+}
+
+// chunkedReader returns its data in fixed chunks, one per Read.
+type chunkedReader struct {
+	chunks []string
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		chunks   []string
+		reads    []string
+		payloads []string
+	}{
+		{
+			name:   "keys",
+			chunks: []string{"q", "\x1b[A"},
+			reads:  []string{"q", "\x1b[A"},
+		},
+		{
+			name:   "escape key is not held back",
+			chunks: []string{"\x1b", "j"},
+			reads:  []string{"\x1b", "j"},
+		},
+		{
+			name:     "reply between keys",
+			chunks:   []string{"a\x1b]8888;1;1;HELLO;1\ab"},
+			reads:    []string{"ab"},
+			payloads: []string{"1;1;HELLO;1"},
+		},
+		{
+			name:     "reply split after the introducer",
+			chunks:   []string{"\x1b]8888;1;2;STA", "TUS;1;playing;70;\a", "x"},
+			reads:    []string{"x"},
+			payloads: []string{"1;2;STATUS;1;playing;70;"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payloads []string
+			r := NewReader(&chunkedReader{chunks: tt.chunks}, func(payload string) {
+				payloads = append(payloads, payload)
+			})
+
+			var reads []string
+			buf := make([]byte, 64)
+			for {
+				n, err := r.Read(buf)
+				if n > 0 {
+					reads = append(reads, string(buf[:n]))
+				}
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Failed to read: %v", err)
+				}
+			}
+
+			if fmt.Sprint(reads) != fmt.Sprint(tt.reads) {
+				t.Errorf("Expected reads %q, got %q", tt.reads, reads)
+			}
+			if fmt.Sprint(payloads) != fmt.Sprint(tt.payloads) {
+				t.Errorf("Expected payloads %q, got %q", tt.payloads, payloads)
+			}
+		})
+	}
+}
+
+func FuzzFilterSplit(f *testing.F) {
+	for _, tt := range filterTests {
+		f.Add(tt.input, len(tt.input)/2)
+	}
+
+	f.Fuzz(func(t *testing.T, input string, split int) {
+		if split < 0 || split > len(input) {
+			split = len(input) / 2
+		}
+
+		var whole, parts recorder
+		wf := whole.filter()
+		_, _ = wf.Write([]byte(input))
+		_ = wf.Flush()
+
+		pf := parts.filter()
+		_, _ = pf.Write([]byte(input[:split]))
+		_, _ = pf.Write([]byte(input[split:]))
+		_ = pf.Flush()
+
+		if whole.out.String() != parts.out.String() || fmt.Sprint(whole.commands) != fmt.Sprint(parts.commands) {
+			t.Errorf("Split at %d changed the result: %q %q vs %q %q",
+				split, whole.out.String(), whole.commands, parts.out.String(), parts.commands)
+		}
+
+		// Nothing is lost: output plus framed payloads account for every byte
+		framed := 0
+		for _, payload := range whole.commands {
+			framed += len(oscPrefix) + len(payload) + 1
+		}
+		if whole.out.Len()+framed > len(input) || whole.out.Len()+framed < len(input)-len(whole.commands) {
+			t.Errorf("Expected %d bytes accounted for, got %d output and %d framed", len(input), whole.out.Len(), framed)
+		}
+	})
+}
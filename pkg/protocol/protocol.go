@@ -0,0 +1,345 @@
+// Package protocol implements the control protocol spoken between a
+// Terminal.FM server and terminal-fm-client over an SSH session.
+//
+// Messages travel in-band as OSC 8888 escape sequences: the server writes
+// them into the terminal output and the client writes its replies into the
+// session input. Each payload has the form
+//
+//	<version>;<seq>;<TYPE>[;<arg>...]
+//
+// where seq increases with every message a side sends and each argument is
+// percent-escaped so it contains only printable ASCII other than ';'.
+//
+// A session starts with the server sending HELLO. A client replies with its
+// own HELLO; until it does, the server knows nothing will play. The client
+// then answers commands, and reports changes it makes on its own, with
+// STATUS messages that acknowledge the last command seen.
+//
+// A server that streams the audio itself, rather than asking the client to
+// play a URL, sends it in FRAME messages using a codec both sides offered in
+// their HELLO; see Frame.
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the protocol version implemented by this package.
+const Version = 1
+
+// Message types.
+const (
+	// TypeHello starts the handshake. Its first argument lists the
+	// supported versions, comma-separated; the rest are capabilities.
+	TypeHello = "HELLO"
+	// TypePlay plays a URL at a volume.
+	TypePlay = "PLAY"
+	// TypeStop stops playback.
+	TypeStop = "STOP"
+	// TypePause pauses playback.
+	TypePause = "PAUSE"
+	// TypeResume resumes paused playback.
+	TypeResume = "RESUME"
+	// TypeVolume changes the volume.
+	TypeVolume = "VOLUME"
+	// TypeStatus reports the client player's state.
+	TypeStatus = "STATUS"
+)
+
+// Capabilities announced in HELLO.
+const (
+	CapPlay   = "play"
+	CapPause  = "pause"
+	CapVolume = "volume"
+	CapStatus = "status"
+)
+
+// Player states reported in STATUS.
+const (
+	StateStopped   = "stopped"
+	StatePlaying   = "playing"
+	StatePaused    = "paused"
+	StateBuffering = "buffering"
+)
+
+var (
+	// ErrUnsupportedVersion is returned for messages of another version.
+	ErrUnsupportedVersion = errors.New("unsupported protocol version")
+	// ErrMalformed is returned for payloads that are not valid messages.
+	ErrMalformed = errors.New("malformed message")
+)
+
+// Message is a single protocol message.
+type Message struct {
+	// Seq is assigned by Encoder.Send.
+	Seq  uint64
+	Type string
+	Args []string
+}
+
+// Hello returns a HELLO message announcing caps.
+func Hello(caps ...string) Message {
+	return Message{Type: TypeHello, Args: append([]string{strconv.Itoa(Version)}, caps...)}
+}
+
+// Play returns a PLAY message.
+func Play(url string, volume int) Message {
+	return Message{Type: TypePlay, Args: []string{url, strconv.Itoa(volume)}}
+}
+
+// Stop returns a STOP message.
+func Stop() Message {
+	return Message{Type: TypeStop}
+}
+
+// Pause returns a PAUSE message.
+func Pause() Message {
+	return Message{Type: TypePause}
+}
+
+// Resume returns a RESUME message.
+func Resume() Message {
+	return Message{Type: TypeResume}
+}
+
+// Volume returns a VOLUME message.
+func Volume(volume int) Message {
+	return Message{Type: TypeVolume, Args: []string{strconv.Itoa(volume)}}
+}
+
+// Status is the client player's state, as reported in STATUS.
+type Status struct {
+	// Ack is the Seq of the last command the client handled. A status
+	// older than the last command sent is superseded by one still to come.
+	Ack    uint64
+	State  string
+	Volume int
+	// Err describes why playback stopped or a command failed, if it did.
+	Err string
+}
+
+// Message returns the STATUS message for s.
+func (s Status) Message() Message {
+	return Message{Type: TypeStatus, Args: []string{
+		strconv.FormatUint(s.Ack, 10), s.State, strconv.Itoa(s.Volume), s.Err,
+	}}
+}
+
+// ParseHello returns the versions and capabilities in a HELLO message.
+func ParseHello(msg Message) (versions []int, caps []string, err error) {
+	if msg.Type != TypeHello || len(msg.Args) < 1 {
+		return nil, nil, fmt.Errorf("%w: expected HELLO with versions", ErrMalformed)
+	}
+
+	for _, field := range strings.Split(msg.Args[0], ",") {
+		version, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: invalid version %q", ErrMalformed, field)
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, msg.Args[1:], nil
+}
+
+// ParsePlay returns the URL and volume in a PLAY message.
+func ParsePlay(msg Message) (url string, volume int, err error) {
+	if msg.Type != TypePlay || len(msg.Args) != 2 || msg.Args[0] == "" {
+		return "", 0, fmt.Errorf("%w: expected PLAY with URL and volume", ErrMalformed)
+	}
+
+	volume, err = strconv.Atoi(msg.Args[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: invalid volume %q", ErrMalformed, msg.Args[1])
+	}
+
+	return msg.Args[0], volume, nil
+}
+
+// ParseVolume returns the volume in a VOLUME message.
+func ParseVolume(msg Message) (int, error) {
+	if msg.Type != TypeVolume || len(msg.Args) != 1 {
+		return 0, fmt.Errorf("%w: expected VOLUME with volume", ErrMalformed)
+	}
+
+	volume, err := strconv.Atoi(msg.Args[0])
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid volume %q", ErrMalformed, msg.Args[0])
+	}
+
+	return volume, nil
+}
+
+// ParseStatus returns the status in a STATUS message.
+func ParseStatus(msg Message) (Status, error) {
+	if msg.Type != TypeStatus || len(msg.Args) != 4 {
+		return Status{}, fmt.Errorf("%w: expected STATUS with 4 fields", ErrMalformed)
+	}
+
+	ack, err := strconv.ParseUint(msg.Args[0], 10, 64)
+	if err != nil {
+		return Status{}, fmt.Errorf("%w: invalid ack %q", ErrMalformed, msg.Args[0])
+	}
+
+	volume, err := strconv.Atoi(msg.Args[2])
+	if err != nil {
+		return Status{}, fmt.Errorf("%w: invalid volume %q", ErrMalformed, msg.Args[2])
+	}
+
+	return Status{Ack: ack, State: msg.Args[1], Volume: volume, Err: msg.Args[3]}, nil
+}
+
+// Payload returns the text carried inside the escape sequence.
+func (m Message) Payload() string {
+	var b strings.Builder
+
+	b.WriteString(strconv.Itoa(Version))
+	b.WriteByte(';')
+	b.WriteString(strconv.FormatUint(m.Seq, 10))
+	b.WriteByte(';')
+	b.WriteString(m.Type)
+	for _, arg := range m.Args {
+		b.WriteByte(';')
+		b.WriteString(escape(arg))
+	}
+
+	return b.String()
+}
+
+// Encode returns msg as a complete escape sequence.
+func Encode(msg Message) []byte {
+	return []byte(oscPrefix + msg.Payload() + "\a")
+}
+
+// Decode parses a payload extracted by a Filter.
+func Decode(payload string) (Message, error) {
+	fields := strings.Split(payload, ";")
+
+	version, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return Message{}, fmt.Errorf("%w: missing version", ErrMalformed)
+	}
+	if version != Version {
+		return Message{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+
+	if len(fields) < 3 {
+		return Message{}, fmt.Errorf("%w: missing sequence number or type", ErrMalformed)
+	}
+
+	seq, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return Message{}, fmt.Errorf("%w: invalid sequence number %q", ErrMalformed, fields[1])
+	}
+
+	msg := Message{Seq: seq, Type: fields[2]}
+	if !validType(msg.Type) {
+		return Message{}, fmt.Errorf("%w: invalid type %q", ErrMalformed, msg.Type)
+	}
+
+	for _, field := range fields[3:] {
+		arg, err := unescape(field)
+		if err != nil {
+			return Message{}, err
+		}
+		msg.Args = append(msg.Args, arg)
+	}
+
+	return msg, nil
+}
+
+// validType reports whether t is a non-empty run of upper case letters.
+func validType(t string) bool {
+	if t == "" {
+		return false
+	}
+	for i := 0; i < len(t); i++ {
+		if t[i] < 'A' || t[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+const hexDigits = "0123456789ABCDEF"
+
+// escape percent-encodes every byte that is not printable ASCII, and the
+// space, '%' and ';' characters.
+func escape(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c > ' ' && c < 0x7f && c != '%' && c != ';' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&0xf])
+	}
+
+	return b.String()
+}
+
+// unescape reverses escape. Bytes escape would have encoded must be encoded.
+func unescape(s string) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '%' {
+			if c <= ' ' || c >= 0x7f {
+				return "", fmt.Errorf("%w: unescaped byte 0x%02x", ErrMalformed, c)
+			}
+			b.WriteByte(c)
+			continue
+		}
+
+		if i+2 >= len(s) {
+			return "", fmt.Errorf("%w: truncated escape", ErrMalformed)
+		}
+		v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid escape %q", ErrMalformed, s[i:i+3])
+		}
+		b.WriteByte(byte(v))
+		i += 2
+	}
+
+	return b.String(), nil
+}
+
+// Encoder writes messages, numbering them in order. It is safe for
+// concurrent use.
+type Encoder struct {
+	mu  sync.Mutex
+	w   io.Writer
+	seq uint64
+}
+
+// NewEncoder creates an Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Send assigns msg the next sequence number, writes it and returns the
+// number.
+func (e *Encoder) Send(msg Message) (uint64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.seq++
+	msg.Seq = e.seq
+
+	if _, err := e.w.Write(Encode(msg)); err != nil {
+		return msg.Seq, fmt.Errorf("failed to send %s: %w", msg.Type, err)
+	}
+
+	return msg.Seq, nil
+}
@@ -0,0 +1,241 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		want string
+	}{
+		{"stop", Message{Seq: 3, Type: TypeStop}, "\x1b]8888;1;3;STOP\a"},
+		{"hello", Hello(CapPlay, CapPause), "\x1b]8888;1;0;HELLO;1;play;pause\a"},
+		{"play", Play("http://jazz/stream", 70), "\x1b]8888;1;0;PLAY;http://jazz/stream;70\a"},
+		{
+			"play with separators in the URL",
+			Play("http://x/a;b?c=d%20e f", 5),
+			"\x1b]8888;1;0;PLAY;http://x/a%3Bb?c=d%2520e%20f;5\a",
+		},
+		{
+			"status with control characters",
+			Status{Ack: 7, State: StateStopped, Volume: 40, Err: "bad\a\x1b\\ ♫"}.Message(),
+			"\x1b]8888;1;0;STATUS;7;stopped;40;bad%07%1B\\%20%E2%99%AB\a",
+		},
+		{"empty argument", Status{State: StatePlaying}.Message(), "\x1b]8888;1;0;STATUS;0;playing;0;\a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Encode(tt.msg)); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+
+			decoded, err := Decode(tt.want[len(oscPrefix) : len(tt.want)-1])
+			if err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+			if !equalMessages(decoded, tt.msg) {
+				t.Errorf("Expected %+v after a round trip, got %+v", tt.msg, decoded)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    error
+	}{
+		{"", ErrMalformed},
+		{"PLAY;http://x;70", ErrMalformed},
+		{"2;1;PLAY;http://x;70", ErrUnsupportedVersion},
+		{"1", ErrMalformed},
+		{"1;1", ErrMalformed},
+		{"1;x;STOP", ErrMalformed},
+		{"1;-1;STOP", ErrMalformed},
+		{"1;1;", ErrMalformed},
+		{"1;1;stop", ErrMalformed},
+		{"1;1;PLAY;http://x y", ErrMalformed},
+		{"1;1;PLAY;%", ErrMalformed},
+		{"1;1;PLAY;%4", ErrMalformed},
+		{"1;1;PLAY;%zz", ErrMalformed},
+		{"1;1;PLAY;é", ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.payload, func(t *testing.T) {
+			if _, err := Decode(tt.payload); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	versions, caps, err := ParseHello(Hello(CapPlay, CapStatus))
+	if err != nil {
+		t.Fatalf("Failed to parse HELLO: %v", err)
+	}
+	if fmt.Sprint(versions) != "[1]" || fmt.Sprint(caps) != "[play status]" {
+		t.Errorf("Expected version 1 and play, status; got %v and %v", versions, caps)
+	}
+
+	// A newer peer may offer several versions
+	versions, _, err = ParseHello(Message{Type: TypeHello, Args: []string{"1,2"}})
+	if err != nil || fmt.Sprint(versions) != "[1 2]" {
+		t.Errorf("Expected versions 1 and 2, got %v (%v)", versions, err)
+	}
+
+	url, volume, err := ParsePlay(Play("http://jazz", 30))
+	if err != nil || url != "http://jazz" || volume != 30 {
+		t.Errorf("Expected http://jazz at 30, got %q at %d (%v)", url, volume, err)
+	}
+
+	volume, err = ParseVolume(Volume(55))
+	if err != nil || volume != 55 {
+		t.Errorf("Expected volume 55, got %d (%v)", volume, err)
+	}
+
+	want := Status{Ack: 9, State: StatePaused, Volume: 20, Err: "oops"}
+	status, err := ParseStatus(want.Message())
+	if err != nil || status != want {
+		t.Errorf("Expected %+v, got %+v (%v)", want, status, err)
+	}
+
+	invalid := []Message{
+		{Type: TypeHello},
+		{Type: TypeHello, Args: []string{"one"}},
+		{Type: TypePlay, Args: []string{"", "70"}},
+		{Type: TypePlay, Args: []string{"http://x"}},
+		{Type: TypePlay, Args: []string{"http://x", "loud"}},
+		{Type: TypeVolume},
+		{Type: TypeStatus, Args: []string{"1", "playing", "70"}},
+		{Type: TypeStatus, Args: []string{"-1", "playing", "70", ""}},
+		Stop(),
+	}
+	for _, msg := range invalid {
+		_, _, helloErr := ParseHello(msg)
+		_, _, playErr := ParsePlay(msg)
+		_, volumeErr := ParseVolume(msg)
+		_, statusErr := ParseStatus(msg)
+
+		var ok []string
+		for name, err := range map[string]error{"HELLO": helloErr, "PLAY": playErr, "VOLUME": volumeErr, "STATUS": statusErr} {
+			if err == nil {
+				ok = append(ok, name)
+			}
+		}
+		if len(ok) != 0 {
+			t.Errorf("Expected %+v to be rejected, parsed as %v", msg, ok)
+		}
+	}
+}
+
+func TestEncoderNumbersMessages(t *testing.T) {
+	var out bytes.Buffer
+	enc := NewEncoder(&out)
+
+	var payloads []string
+	filter := NewFilter(&bytes.Buffer{}, func(payload string) {
+		payloads = append(payloads, payload)
+	})
+
+	for i, msg := range []Message{Hello(CapPlay), Play("http://x", 70), Stop()} {
+		seq, err := enc.Send(msg)
+		if err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		if seq != uint64(i+1) {
+			t.Errorf("Expected sequence number %d, got %d", i+1, seq)
+		}
+	}
+
+	_, _ = filter.Write(out.Bytes())
+
+	for i, payload := range payloads {
+		msg, err := Decode(payload)
+		if err != nil {
+			t.Fatalf("Failed to decode %q: %v", payload, err)
+		}
+		if msg.Seq != uint64(i+1) {
+			t.Errorf("Expected message %d to have Seq %d, got %d", i, i+1, msg.Seq)
+		}
+	}
+}
+
+// equalMessages compares messages, treating nil and empty Args as equal.
+func equalMessages(a, b Message) bool {
+	if len(a.Args) == 0 && len(b.Args) == 0 {
+		return a.Seq == b.Seq && a.Type == b.Type
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, seed := range []string{
+		"1;1;HELLO;1;play;pause",
+		"1;2;PLAY;http://x/a%3Bb;70",
+		"1;3;STATUS;2;stopped;40;lost%20connection",
+		"2;1;STOP",
+		"PLAY;http://x;70",
+		"1;1;PLAY;%zz",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, payload string) {
+		msg, err := Decode(payload)
+		if err != nil {
+			return
+		}
+
+		// Anything accepted survives a round trip through the wire format
+		encoded := Encode(msg)
+		var payloads []string
+		filter := NewFilter(&bytes.Buffer{}, func(p string) { payloads = append(payloads, p) })
+		_, _ = filter.Write(encoded)
+
+		if len(payloads) != 1 {
+			t.Fatalf("Expected 1 message in %q, got %d", encoded, len(payloads))
+		}
+		again, err := Decode(payloads[0])
+		if err != nil {
+			t.Fatalf("Failed to decode re-encoded %q: %v", payloads[0], err)
+		}
+		if !equalMessages(msg, again) {
+			t.Errorf("Round trip changed %+v into %+v", msg, again)
+		}
+	})
+}
+
+func FuzzArgs(f *testing.F) {
+	f.Add("http://jazz/stream", "lost connection")
+	f.Add("http://x/a;b\x1b]8888;1;1;STOP\a", "\x1b\\%zz;")
+	f.Add("", "♫")
+
+	f.Fuzz(func(t *testing.T, url, reason string) {
+		msg := Message{Seq: 1, Type: TypeStatus, Args: []string{url, reason}}
+		encoded := Encode(msg)
+
+		// Escaped arguments never contain anything that ends the sequence
+		if bytes.IndexByte(encoded[len(oscPrefix):len(encoded)-1], esc) >= 0 ||
+			bytes.IndexByte(encoded[:len(encoded)-1], bel) >= 0 {
+			t.Fatalf("Expected no ESC or BEL inside %q", encoded)
+		}
+
+		payload := string(encoded[len(oscPrefix) : len(encoded)-1])
+		decoded, err := Decode(payload)
+		if err != nil {
+			t.Fatalf("Failed to decode %q: %v", payload, err)
+		}
+		if !reflect.DeepEqual(decoded, msg) {
+			t.Errorf("Expected %+v, got %+v", msg, decoded)
+		}
+	})
+}
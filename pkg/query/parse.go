@@ -0,0 +1,369 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// maxNumber is the largest number a query may compare with.
+const maxNumber = 1_000_000_000
+
+// fields maps the names a field may be written as to its canonical name.
+var fields = map[string]string{
+	"name":        "name",
+	"n":           "name",
+	"tag":         "tag",
+	"t":           "tag",
+	"country":     "country",
+	"cc":          "countrycode",
+	"countrycode": "countrycode",
+	"lang":        "language",
+	"language":    "language",
+	"codec":       "codec",
+	"bitrate":     "bitrate",
+	"votes":       "votes",
+	"clicks":      "clicks",
+	"https":       "https",
+	"sort":        "sort",
+	"order":       "sort",
+}
+
+// sortOrders maps the orders sort: accepts to the API's, and whether they
+// sort most first.
+var sortOrders = map[string]struct {
+	order   string
+	reverse bool
+}{
+	"votes":      {"votes", true},
+	"clicks":     {"clickcount", true},
+	"clickcount": {"clickcount", true},
+	"trend":      {"clicktrend", true},
+	"clicktrend": {"clicktrend", true},
+	"bitrate":    {"bitrate", true},
+	"name":       {"name", false},
+	"random":     {"random", false},
+}
+
+// Error is a mistake in a query.
+type Error struct {
+	// Column is where in the query the mistake is, counting runes from 1.
+	Column int
+	Msg    string
+}
+
+// Error returns the mistake and where it is.
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+// parser holds the state of parsing one query.
+type parser struct {
+	input string
+	pos   int
+	query Query
+	// words are the parts of the name: plain words and name: values.
+	words []string
+	// set records the parameters given in the query, so that a second
+	// occurrence becomes a filter rather than replacing the first.
+	set map[string]bool
+}
+
+// Parse parses a query. Parameters the query leaves out keep their values
+// from defaults; those it gives replace them.
+func Parse(input string, defaults radiobrowser.SearchParams) (Query, error) {
+	p := &parser{
+		input: input,
+		query: Query{Params: defaults},
+		set:   make(map[string]bool),
+	}
+
+	for {
+		p.skipSpace()
+		if p.pos == len(p.input) {
+			break
+		}
+
+		start := p.pos
+		negated, field, op, ok := p.field()
+		if !ok {
+			// A plain word, part of the name
+			p.pos = start
+			word, err := p.value()
+			if err != nil {
+				return Query{}, err
+			}
+			if word != "" {
+				p.words = append(p.words, word)
+			}
+			continue
+		}
+
+		value, err := p.value()
+		if err != nil {
+			return Query{}, err
+		}
+		if err := p.apply(start, negated, field, op, value); err != nil {
+			return Query{}, err
+		}
+	}
+
+	if len(p.words) > 0 {
+		p.query.Params.Name = strings.Join(p.words, " ")
+	}
+
+	params := p.query.Params
+	if params.BitrateMax > 0 && params.BitrateMin > params.BitrateMax {
+		return Query{}, &Error{Column: 1, Msg: fmt.Sprintf("no bitrate is both at least %d and at most %d", params.BitrateMin, params.BitrateMax)}
+	}
+
+	return p.query, nil
+}
+
+// field reads the field and operator starting a field term, e.g. "tag:" or
+// "-codec:", reporting false if the word is not one. Only known fields
+// start a term, so words such as "Radio:Rock" or "http://..." are left as
+// part of the name.
+func (p *parser) field() (negated bool, field string, op Op, ok bool) {
+	if p.peek() == '-' {
+		negated = true
+		p.pos++
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && isLetter(p.input[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return false, "", 0, false
+	}
+	name := strings.ToLower(p.input[start:p.pos])
+	if _, ok := fields[name]; !ok {
+		return false, "", 0, false
+	}
+
+	switch {
+	case strings.HasPrefix(p.input[p.pos:], ">="):
+		op, p.pos = OpGe, p.pos+2
+	case strings.HasPrefix(p.input[p.pos:], "<="):
+		op, p.pos = OpLe, p.pos+2
+	case p.peek() == '>':
+		op, p.pos = OpGt, p.pos+1
+	case p.peek() == '<':
+		op, p.pos = OpLt, p.pos+1
+	case p.peek() == ':' || p.peek() == '=':
+		op, p.pos = OpEq, p.pos+1
+	default:
+		return false, "", 0, false
+	}
+
+	return negated, name, op, true
+}
+
+// value reads a word, which may be quoted.
+func (p *parser) value() (string, error) {
+	if p.peek() != '"' {
+		start := p.pos
+		for p.pos < len(p.input) && !isSpace(p.input[p.pos]) {
+			p.pos++
+		}
+		return p.input[start:p.pos], nil
+	}
+
+	start := p.pos
+	p.pos++
+
+	var b strings.Builder
+	for {
+		if p.pos == len(p.input) {
+			return "", p.errorAt(start, "missing closing quote")
+		}
+
+		c := p.input[p.pos]
+		p.pos++
+		switch {
+		case c == '"':
+			if p.pos < len(p.input) && !isSpace(p.input[p.pos]) {
+				return "", p.errorAt(p.pos, "expected a space after the closing quote")
+			}
+			return b.String(), nil
+		case c == '\\' && p.pos < len(p.input):
+			b.WriteByte(p.input[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// apply adds the field term starting at start to the query.
+func (p *parser) apply(start int, negated bool, name string, op Op, value string) error {
+	field := fields[name]
+	if value == "" {
+		return p.errorAt(start, "%s needs a value", name)
+	}
+
+	params := &p.query.Params
+	switch field {
+	case "bitrate", "votes", "clicks":
+		if negated {
+			return p.errorAt(start, "%s cannot be negated; compare it with < or > instead", name)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > maxNumber {
+			return p.errorAt(start, "%s must be a whole number, not %q", name, value)
+		}
+
+		if field != "bitrate" {
+			p.filter(field, op, strconv.Itoa(n))
+			return nil
+		}
+		return p.bitrate(start, op, n)
+
+	case "https":
+		yes, err := parseYesNo(value)
+		if err != nil || op != OpEq {
+			return p.errorAt(start, "https must be https:yes or https:no")
+		}
+		if yes != negated {
+			params.HTTPSOnly = true
+		} else {
+			p.filter("https", OpEq, "no")
+		}
+		return nil
+
+	case "sort":
+		sort, ok := sortOrders[strings.ToLower(value)]
+		if !ok || negated || op != OpEq {
+			return p.errorAt(start, "sort must be one of votes, clicks, trend, bitrate, name or random")
+		}
+		params.Order, params.Reverse = sort.order, sort.reverse
+		return nil
+	}
+
+	// The text fields
+	if op != OpEq {
+		return p.errorAt(start, "%s cannot be compared with %s", name, op)
+	}
+	if field == "countrycode" {
+		if len(value) != 2 || !isLetter(value[0]) || !isLetter(value[1]) {
+			return p.errorAt(start, "a country code is two letters, such as IT, not %q", value)
+		}
+		value = strings.ToUpper(value)
+	}
+
+	if negated {
+		p.filter(field, OpNe, value)
+		return nil
+	}
+	if field == "name" {
+		p.words = append(p.words, value)
+		return nil
+	}
+	if p.set[field] {
+		// The API searches by one of each; the rest filter the results
+		p.filter(field, OpEq, value)
+		return nil
+	}
+	p.set[field] = true
+
+	switch field {
+	case "tag":
+		params.Tag = value
+	case "country":
+		params.Country = value
+	case "countrycode":
+		params.CountryCode = value
+	case "language":
+		params.Language = value
+	case "codec":
+		params.Codec = value
+	}
+	return nil
+}
+
+// bitrate narrows the bitrate range searched by a comparison with n.
+func (p *parser) bitrate(start int, op Op, n int) error {
+	params := &p.query.Params
+	if !p.set["bitrate"] {
+		p.set["bitrate"] = true
+		params.BitrateMin, params.BitrateMax = 0, 0
+	}
+
+	lowest, highest := 0, 0
+	switch op {
+	case OpEq:
+		lowest, highest = n, n
+	case OpGe:
+		lowest = n
+	case OpGt:
+		lowest = n + 1
+	case OpLe:
+		highest = n
+	case OpLt:
+		highest = n - 1
+	}
+
+	if op == OpEq || op == OpLe || op == OpLt {
+		if highest < 1 {
+			return p.errorAt(start, "no station has a bitrate below 1")
+		}
+		if params.BitrateMax == 0 || highest < params.BitrateMax {
+			params.BitrateMax = highest
+		}
+	}
+	if lowest > params.BitrateMin {
+		params.BitrateMin = lowest
+	}
+	return nil
+}
+
+// filter adds a filter on the results.
+func (p *parser) filter(field string, op Op, value string) {
+	p.query.Filters = append(p.query.Filters, Filter{Field: field, Op: op, Value: value})
+}
+
+// errorAt returns an error about the query at byte offset pos.
+func (p *parser) errorAt(pos int, format string, args ...any) error {
+	return &Error{
+		Column: utf8.RuneCountInString(p.input[:pos]) + 1,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// skipSpace moves past any spaces.
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && isSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// peek returns the next byte, or 0 at the end.
+func (p *parser) peek() byte {
+	if p.pos == len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// parseYesNo parses a yes or no answer.
+func parseYesNo(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "yes", "y", "true", "on", "1":
+		return true, nil
+	case "no", "n", "false", "off", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("not yes or no: %q", s)
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
@@ -0,0 +1,236 @@
+// Package query parses the search box's query language, such as
+//
+//	tag:jazz cc:IT codec:aac bitrate>=192 sort:clicks
+//
+// into Radio Browser search parameters plus filters applied to the results.
+// Plain words search station names, as they always have.
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// Op is how a filter compares a station's field with its value.
+type Op int
+
+const (
+	// OpEq matches a field containing the value; names, countries and
+	// languages match on a substring, other fields exactly.
+	OpEq Op = iota
+	// OpNe matches the fields OpEq does not.
+	OpNe
+	// OpLt, OpLe, OpGt and OpGe compare numeric fields.
+	OpLt
+	OpLe
+	OpGt
+	OpGe
+)
+
+// String returns the operator as written in a query.
+func (op Op) String() string {
+	switch op {
+	case OpEq:
+		return ":"
+	case OpNe:
+		return "-:"
+	case OpLt:
+		return "<"
+	case OpLe:
+		return "<="
+	case OpGt:
+		return ">"
+	case OpGe:
+		return ">="
+	default:
+		return "?"
+	}
+}
+
+// Filter is a condition the API cannot search by, checked on each result.
+type Filter struct {
+	// Field is one of "name", "tag", "country", "countrycode",
+	// "language", "codec", "https", "votes", "clicks" and "bitrate".
+	Field string
+	Op    Op
+	// Value is a number for numeric fields, and "no" for https.
+	Value string
+}
+
+// Match reports whether station passes the filter.
+func (f Filter) Match(station radiobrowser.Station) bool {
+	switch f.Field {
+	case "votes":
+		return f.compare(station.Votes)
+	case "clicks":
+		return f.compare(station.ClickCount)
+	case "bitrate":
+		return f.compare(station.Bitrate)
+	case "https":
+		// Only https:no needs a filter; https:yes is a search parameter
+		return !strings.HasPrefix(station.URLResolved, "https://")
+	}
+
+	var matched bool
+	switch f.Field {
+	case "name":
+		matched = containsFold(station.Name, f.Value)
+	case "country":
+		matched = containsFold(station.Country, f.Value)
+	case "language":
+		matched = containsFold(station.Language, f.Value)
+	case "countrycode":
+		matched = strings.EqualFold(station.CountryCode, f.Value)
+	case "codec":
+		matched = strings.EqualFold(station.Codec, f.Value)
+	case "tag":
+		for _, tag := range strings.Split(station.Tags, ",") {
+			if strings.EqualFold(strings.TrimSpace(tag), f.Value) {
+				matched = true
+				break
+			}
+		}
+	}
+
+	if f.Op == OpNe {
+		return !matched
+	}
+	return matched
+}
+
+// compare compares a numeric field with the filter's value.
+func (f Filter) compare(n int) bool {
+	value, _ := strconv.Atoi(f.Value)
+
+	switch f.Op {
+	case OpNe:
+		return n != value
+	case OpLt:
+		return n < value
+	case OpLe:
+		return n <= value
+	case OpGt:
+		return n > value
+	case OpGe:
+		return n >= value
+	default:
+		return n == value
+	}
+}
+
+// String returns the filter as written in a query.
+func (f Filter) String() string {
+	switch {
+	case f.Field == "https":
+		return "https:no"
+	case f.Op == OpNe:
+		return "-" + f.Field + ":" + quote(f.Value)
+	default:
+		return f.Field + f.Op.String() + quote(f.Value)
+	}
+}
+
+// containsFold reports whether substr is within s, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// Query is a parsed query.
+type Query struct {
+	// Params are what the API searches by.
+	Params radiobrowser.SearchParams
+	// Filters are applied to the results, all of which must match.
+	Filters []Filter
+}
+
+// Apply returns the stations matching every filter.
+func (q Query) Apply(stations []radiobrowser.Station) []radiobrowser.Station {
+	if len(q.Filters) == 0 {
+		return stations
+	}
+
+	matching := make([]radiobrowser.Station, 0, len(stations))
+	for _, station := range stations {
+		if q.Match(station) {
+			matching = append(matching, station)
+		}
+	}
+	return matching
+}
+
+// Match reports whether station matches every filter.
+func (q Query) Match(station radiobrowser.Station) bool {
+	for _, filter := range q.Filters {
+		if !filter.Match(station) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the query in canonical form; parsing it gives the same
+// query back.
+func (q Query) String() string {
+	var terms []string
+	add := func(format string, args ...any) {
+		terms = append(terms, fmt.Sprintf(format, args...))
+	}
+
+	p := q.Params
+	if p.Name != "" {
+		terms = append(terms, quote(p.Name))
+	}
+	if p.Tag != "" {
+		add("tag:%s", quote(p.Tag))
+	}
+	if p.Country != "" {
+		add("country:%s", quote(p.Country))
+	}
+	if p.CountryCode != "" {
+		add("cc:%s", quote(p.CountryCode))
+	}
+	if p.Language != "" {
+		add("lang:%s", quote(p.Language))
+	}
+	if p.Codec != "" {
+		add("codec:%s", quote(p.Codec))
+	}
+	if p.BitrateMin > 0 {
+		add("bitrate>=%d", p.BitrateMin)
+	}
+	if p.BitrateMax > 0 {
+		add("bitrate<=%d", p.BitrateMax)
+	}
+	if p.HTTPSOnly {
+		add("https:yes")
+	}
+	if p.Order != "" {
+		add("sort:%s", quote(p.Order))
+	}
+
+	for _, filter := range q.Filters {
+		terms = append(terms, filter.String())
+	}
+	return strings.Join(terms, " ")
+}
+
+// quote returns s, quoted if it would not otherwise read back as one word.
+func quote(s string) string {
+	if s != "" && !strings.HasPrefix(s, "-") && !strings.ContainsAny(s, " \t\n\r\v\f\":<>=\\") {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,254 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		defaults radiobrowser.SearchParams
+		want     Query
+	}{
+		{
+			name:  "empty",
+			input: "  ",
+			want:  Query{},
+		},
+		{
+			name:  "plain words search names",
+			input: " jazz   radio ",
+			want:  Query{Params: radiobrowser.SearchParams{Name: "jazz radio"}},
+		},
+		{
+			name:  "example",
+			input: "tag:jazz cc:it codec:aac bitrate>=192 sort:clicks",
+			want: Query{Params: radiobrowser.SearchParams{
+				Tag:         "jazz",
+				CountryCode: "IT",
+				Codec:       "aac",
+				BitrateMin:  192,
+				Order:       "clickcount",
+				Reverse:     true,
+			}},
+		},
+		{
+			name:  "aliases and case",
+			input: `T:rock LANG:italian Country=Italy order:name https:yes`,
+			want: Query{Params: radiobrowser.SearchParams{
+				Tag:       "rock",
+				Language:  "italian",
+				Country:   "Italy",
+				Order:     "name",
+				HTTPSOnly: true,
+			}},
+		},
+		{
+			name:  "quoted values",
+			input: `"radio \"one\"" tag:"smooth jazz" name:fm`,
+			want: Query{Params: radiobrowser.SearchParams{
+				Name: `radio "one" fm`,
+				Tag:  "smooth jazz",
+			}},
+		},
+		{
+			name:  "words that are not fields",
+			input: "-80s 12:00 ac/dc",
+			want:  Query{Params: radiobrowser.SearchParams{Name: "-80s 12:00 ac/dc"}},
+		},
+		{
+			name:  "words with unknown fields",
+			input: "Radio:Rock K=FM http://radio.example.com/live -tga:jazz tag:rock",
+			want: Query{Params: radiobrowser.SearchParams{
+				Name: "Radio:Rock K=FM http://radio.example.com/live -tga:jazz",
+				Tag:  "rock",
+			}},
+		},
+		{
+			name:  "bitrate range",
+			input: "bitrate>96 bitrate<=320 bitrate<256",
+			want:  Query{Params: radiobrowser.SearchParams{BitrateMin: 97, BitrateMax: 255}},
+		},
+		{
+			name:  "exact bitrate",
+			input: "bitrate:128",
+			want:  Query{Params: radiobrowser.SearchParams{BitrateMin: 128, BitrateMax: 128}},
+		},
+		{
+			name:  "filters",
+			input: "tag:jazz tag:live -tag:smooth -cc:us votes>=100 clicks<50 https:no",
+			want: Query{
+				Params: radiobrowser.SearchParams{Tag: "jazz"},
+				Filters: []Filter{
+					{Field: "tag", Op: OpEq, Value: "live"},
+					{Field: "tag", Op: OpNe, Value: "smooth"},
+					{Field: "countrycode", Op: OpNe, Value: "US"},
+					{Field: "votes", Op: OpGe, Value: "100"},
+					{Field: "clicks", Op: OpLt, Value: "50"},
+					{Field: "https", Op: OpEq, Value: "no"},
+				},
+			},
+		},
+		{
+			name:     "defaults are kept unless replaced",
+			input:    "jazz bitrate<=128",
+			defaults: radiobrowser.SearchParams{Tag: "live", BitrateMin: 64, BitrateMax: 320, Limit: 50, Order: "votes", Reverse: true},
+			want: Query{Params: radiobrowser.SearchParams{
+				Name:       "jazz",
+				Tag:        "live",
+				BitrateMax: 128,
+				Limit:      50,
+				Order:      "votes",
+				Reverse:    true,
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.defaults)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		column int
+		msg    string
+	}{
+		{"jazz tag>rock", 6, "tag cannot be compared with >"},
+		{"tag:", 1, "tag needs a value"},
+		{"bitrate>=fast", 1, `bitrate must be a whole number, not "fast"`},
+		{"votes>-1", 1, "votes must be a whole number"},
+		{"-bitrate:128", 1, "bitrate cannot be negated"},
+		{"tag>=jazz", 1, "tag cannot be compared with >="},
+		{"cc:ITA", 1, "a country code is two letters"},
+		{"https:maybe", 1, "https must be https:yes or https:no"},
+		{"sort:loudness", 1, "sort must be one of"},
+		{`jazz "smooth`, 6, "missing closing quote"},
+		{`tag:"a"b`, 8, "expected a space after the closing quote"},
+		{"bitrate>=320 bitrate<128", 1, "no bitrate is both at least 320 and at most 127"},
+		{"bitrate<1", 1, "no station has a bitrate below 1"},
+		{"électro codec<x", 9, "codec cannot be compared with <"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input, radiobrowser.SearchParams{})
+
+			var qerr *Error
+			if !errors.As(err, &qerr) {
+				t.Fatalf("Expected a query error, got %v", err)
+			}
+			if qerr.Column != tt.column || !strings.Contains(qerr.Msg, tt.msg) {
+				t.Errorf("Expected %q at column %d, got %q at column %d", tt.msg, tt.column, qerr.Msg, qerr.Column)
+			}
+		})
+	}
+}
+
+func TestQueryApply(t *testing.T) {
+	stations := []radiobrowser.Station{
+		{Name: "Smooth Jazz", Tags: "jazz,smooth", CountryCode: "US", Votes: 500, URLResolved: "https://a"},
+		{Name: "Jazz Live", Tags: "jazz, live", CountryCode: "IT", Votes: 50, ClickCount: 10, URLResolved: "http://b"},
+		{Name: "Rock Live", Tags: "rock,live", CountryCode: "IT", Votes: 150, URLResolved: "https://c"},
+	}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"jazz", []string{"Smooth Jazz", "Jazz Live", "Rock Live"}},
+		{"tag:jazz tag:live", []string{"Jazz Live", "Rock Live"}},
+		{"-tag:smooth", []string{"Jazz Live", "Rock Live"}},
+		{"-name:rock -cc:us", []string{"Jazz Live"}},
+		{"votes>=100", []string{"Smooth Jazz", "Rock Live"}},
+		{"votes:50 clicks>5", []string{"Jazz Live"}},
+		{"https:no", []string{"Jazz Live"}},
+		{"country:x country:ital", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := Parse(tt.input, radiobrowser.SearchParams{})
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", tt.input, err)
+			}
+
+			var got []string
+			for _, station := range q.Apply(stations) {
+				got = append(got, station.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestQueryString(t *testing.T) {
+	q, err := Parse(`tag:"smooth jazz" sort:trend radio -tag:x bitrate>64 votes<=9 "-a:b"`, radiobrowser.SearchParams{})
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	expected := `"radio -a:b" tag:"smooth jazz" bitrate>=65 sort:clicktrend -tag:x votes<=9`
+	if got := q.String(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"tag:jazz cc:IT codec:aac bitrate>=192 sort:clicks",
+		`"radio \"one\"" tag:"smooth jazz" name:fm`,
+		"-tag:smooth -cc:us votes>=100 clicks<50 https:no",
+		"bitrate>96 bitrate<=320 bitrate:128",
+		"jazz -80s 12:00 ac/dc",
+		`tag:"a"b`,
+		"\"\\",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		q, err := Parse(input, radiobrowser.SearchParams{})
+		if err != nil {
+			var qerr *Error
+			if !errors.As(err, &qerr) {
+				t.Fatalf("Expected a query error for %q, got %T", input, err)
+			}
+			if qerr.Column < 1 || qerr.Column > len([]rune(input))+1 {
+				t.Fatalf("Error column %d is outside %q", qerr.Column, input)
+			}
+			return
+		}
+
+		p := q.Params
+		if p.BitrateMin < 0 || p.BitrateMax < 0 || (p.BitrateMax > 0 && p.BitrateMin > p.BitrateMax) {
+			t.Fatalf("Invalid bitrate range %d-%d from %q", p.BitrateMin, p.BitrateMax, input)
+		}
+
+		// The canonical form parses back to the same query
+		again, err := Parse(q.String(), radiobrowser.SearchParams{})
+		if err != nil {
+			t.Fatalf("Failed to parse %q, the canonical form of %q: %v", q.String(), input, err)
+		}
+		if !reflect.DeepEqual(again, q) {
+			t.Fatalf("Expected %q to round-trip through %q: %+v != %+v", input, q.String(), again, q)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package player
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// eventBufferSize is how many undelivered events a subscriber may have
+// before the oldest ones are dropped.
+const eventBufferSize = 16
+
+// Event is a snapshot of a player published whenever it changes, including
+// changes the player makes on its own such as a stream dropping.
+type Event struct {
+	State   State
+	Station *radiobrowser.Station
+	Volume  int
+	// Err is set when playback stopped or failed without being asked to.
+	Err error
+}
+
+// eventHub fans events out to subscribers. The zero value is ready to use.
+//
+// Publishing never blocks: a subscriber that falls behind loses its oldest
+// events, which is fine because every event carries the full state. Events
+// carrying an error are kept over those that don't, so that a failure still
+// reaches a slow subscriber.
+type eventHub struct {
+	mu   sync.Mutex
+	subs []chan Event
+}
+
+// subscribe returns a new channel that receives every later event.
+func (h *eventHub) subscribe() <-chan Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Event, eventBufferSize)
+	h.subs = append(h.subs, ch)
+	return ch
+}
+
+// unsubscribe stops sending events to a channel returned by subscribe and
+// closes it. Other channels are ignored.
+func (h *eventHub) unsubscribe(events <-chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, ch := range h.subs {
+		if ch == events {
+			h.subs = slices.Delete(h.subs, i, i+1)
+			close(ch)
+			return
+		}
+	}
+}
+
+// publish sends event to every subscriber.
+func (h *eventHub) publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, ch := range h.subs {
+		select {
+		case ch <- event:
+		default:
+			requeue(ch, event)
+		}
+	}
+}
+
+// requeue adds event to ch, which was full, making room by dropping the
+// oldest event without an error. Only a subscriber sitting on a full buffer
+// of errors loses one, the oldest.
+func requeue(ch chan Event, event Event) {
+	queued := make([]Event, 0, cap(ch)+1)
+drain:
+	for len(queued) < cap(ch) {
+		select {
+		case e := <-ch:
+			queued = append(queued, e)
+		default:
+			// The subscriber took some meanwhile
+			break drain
+		}
+	}
+	queued = append(queued, event)
+
+	for len(queued) > cap(ch) {
+		i := slices.IndexFunc(queued, func(e Event) bool { return e.Err == nil })
+		queued = slices.Delete(queued, max(i, 0), max(i, 0)+1)
+	}
+
+	// The hub is the only sender, so this cannot block
+	for _, e := range queued {
+		ch <- e
+	}
+}
@@ -0,0 +1,164 @@
+package player
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// fakeFFplay is a shell script standing in for ffplay. Every launch is
+// recorded in a file before the script runs body.
+type fakeFFplay struct {
+	path       string
+	launchFile string
+}
+
+func newFakeFFplay(t *testing.T, body string) *fakeFFplay {
+	t.Helper()
+
+	dir := t.TempDir()
+	f := &fakeFFplay{
+		path:       filepath.Join(dir, "ffplay"),
+		launchFile: filepath.Join(dir, "launches"),
+	}
+
+	script := "#!/bin/sh\necho launch >> " + f.launchFile + "\n" + body + "\n"
+	if err := os.WriteFile(f.path, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake ffplay: %v", err)
+	}
+
+	return f
+}
+
+// launches returns how many times the fake ffplay was started.
+func (f *fakeFFplay) launches() int {
+	data, _ := os.ReadFile(f.launchFile)
+	return strings.Count(string(data), "launch")
+}
+
+// newTestFFplayPlayer creates a player with short reconnect delays.
+func newTestFFplayPlayer(path string, maxRetries int, retryDelay time.Duration) *FFplayPlayer {
+	p := NewFFplayPlayer(path)
+	p.SetMaxRetries(maxRetries)
+	p.retryDelay = retryDelay
+	p.maxRetryDelay = time.Second
+	return p
+}
+
+var testStation = &radiobrowser.Station{StationUUID: "jazz", Name: "Jazz Radio", URLResolved: "http://jazz/stream"}
+
+func TestFFplayReconnectsThenFails(t *testing.T) {
+	fake := newFakeFFplay(t, "exit 1")
+	p := newTestFFplayPlayer(fake.path, 2, 10*time.Millisecond)
+	defer p.Cleanup()
+
+	events := p.Events()
+
+	if err := p.Play(testStation); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+
+	var states []State
+	var failure error
+	timeout := time.After(5 * time.Second)
+	for failure == nil {
+		select {
+		case event := <-events:
+			states = append(states, event.State)
+			failure = event.Err
+		case <-timeout:
+			t.Fatalf("Expected a failure event after retries were exhausted, got states %v", states)
+		}
+	}
+
+	if !strings.Contains(failure.Error(), "2 reconnect attempts") {
+		t.Errorf("Expected failure to mention the attempts, got %v", failure)
+	}
+
+	// Play, then buffering and relaunching twice, then giving up
+	want := []State{StatePlaying, StateBuffering, StatePlaying, StateBuffering, StatePlaying, StateStopped}
+	if fmt.Sprint(states) != fmt.Sprint(want) {
+		t.Errorf("Expected states %v, got %v", want, states)
+	}
+
+	if got := fake.launches(); got != 3 {
+		t.Errorf("Expected 1 launch and 2 reconnects, got %d launches", got)
+	}
+	if p.GetState() != StateStopped || p.GetCurrentStation() != nil {
+		t.Errorf("Expected player to be stopped after giving up, got state %v", p.GetState())
+	}
+}
+
+func TestFFplayBuffersWhileReconnecting(t *testing.T) {
+	fake := newFakeFFplay(t, "exit 1")
+	p := newTestFFplayPlayer(fake.path, 1, time.Hour)
+	defer p.Cleanup()
+
+	if err := p.Play(testStation); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.GetState() != StateBuffering && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if p.GetState() != StateBuffering {
+		t.Fatalf("Expected StateBuffering while waiting to reconnect, got %v", p.GetState())
+	}
+	if p.GetCurrentStation() != testStation {
+		t.Errorf("Expected the station to be kept while reconnecting")
+	}
+
+	// Stopping cancels the pending reconnect
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Failed to stop: %v", err)
+	}
+	if p.GetState() != StateStopped {
+		t.Errorf("Expected StateStopped after Stop, got %v", p.GetState())
+	}
+}
+
+func TestFFplayUserStopDoesNotReconnect(t *testing.T) {
+	fake := newFakeFFplay(t, "exec sleep 30")
+	p := newTestFFplayPlayer(fake.path, 3, 10*time.Millisecond)
+	defer p.Cleanup()
+
+	events := p.Events()
+
+	if err := p.Play(testStation); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for fake.launches() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Failed to stop: %v", err)
+	}
+
+	timeout := time.After(200 * time.Millisecond)
+	for done := false; !done; {
+		select {
+		case event := <-events:
+			if event.Err != nil {
+				t.Errorf("Expected no failure after a user stop, got %v", event.Err)
+			}
+		case <-timeout:
+			done = true
+		}
+	}
+
+	if got := fake.launches(); got != 1 {
+		t.Errorf("Expected no relaunch after a user stop, got %d launches", got)
+	}
+	if p.GetState() != StateStopped {
+		t.Errorf("Expected StateStopped, got %v", p.GetState())
+	}
+}
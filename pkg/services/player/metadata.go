@@ -0,0 +1,198 @@
+package player
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// Metadata is the now-playing information announced by a stream.
+type Metadata struct {
+	// StreamTitle is the current track, usually formatted as "Artist - Title".
+	StreamTitle string
+}
+
+// MetadataReader reads ICY (Shoutcast/Icecast) in-band metadata from a
+// station's stream, independently of the player that is playing it.
+type MetadataReader struct {
+	httpClient *http.Client
+	userAgent  string
+}
+
+// NewMetadataReader creates a new ICY metadata reader.
+func NewMetadataReader() *MetadataReader {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		// Shoutcast v1 servers answer with "ICY 200 OK" instead of an
+		// HTTP status line, which net/http refuses to parse.
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &icyConn{Conn: conn}, nil
+		},
+		ResponseHeaderTimeout: 10 * time.Second,
+	}
+
+	return &MetadataReader{
+		httpClient: &http.Client{Transport: transport},
+		userAgent:  "Terminal.FM/1.0",
+	}
+}
+
+// Watch connects to the station's stream and publishes every StreamTitle
+// change on the returned channel. The channel is closed when the stream
+// ends or ctx is cancelled.
+func (r *MetadataReader) Watch(ctx context.Context, station *radiobrowser.Station) (<-chan Metadata, error) {
+	if station == nil || station.URLResolved == "" {
+		return nil, fmt.Errorf("invalid station or URL")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", station.URLResolved, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Icy-MetaData", "1")
+	req.Header.Set("User-Agent", r.userAgent)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to stream: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("stream returned status %d", resp.StatusCode)
+	}
+
+	metaint, err := strconv.Atoi(resp.Header.Get("icy-metaint"))
+	if err != nil || metaint <= 0 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("stream does not provide ICY metadata")
+	}
+
+	updates := make(chan Metadata)
+
+	go func() {
+		defer close(updates)
+		defer resp.Body.Close()
+
+		_ = readICYMetadata(bufio.NewReader(resp.Body), metaint, func(md Metadata) bool {
+			select {
+			case updates <- md:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return updates, nil
+}
+
+// readICYMetadata walks an ICY stream, skipping metaint bytes of audio
+// between metadata blocks, and calls emit whenever the title changes.
+// It returns when the stream ends or emit returns false.
+func readICYMetadata(r io.Reader, metaint int, emit func(Metadata) bool) error {
+	var last string
+	lengthByte := make([]byte, 1)
+
+	for {
+		// Skip the audio data
+		if _, err := io.CopyN(io.Discard, r, int64(metaint)); err != nil {
+			return err
+		}
+
+		// Metadata length is given in 16-byte blocks
+		if _, err := io.ReadFull(r, lengthByte); err != nil {
+			return err
+		}
+		length := int(lengthByte[0]) * 16
+		if length == 0 {
+			continue
+		}
+
+		block := make([]byte, length)
+		if _, err := io.ReadFull(r, block); err != nil {
+			return err
+		}
+
+		title, ok := parseStreamTitle(string(block))
+		if !ok || title == last {
+			continue
+		}
+		last = title
+
+		if !emit(Metadata{StreamTitle: title}) {
+			return nil
+		}
+	}
+}
+
+// parseStreamTitle extracts StreamTitle from a metadata block such as
+// "StreamTitle='Artist - Title';StreamUrl='http://...';". Titles may contain
+// quotes, so the value ends at the first "';" rather than the first quote.
+func parseStreamTitle(block string) (string, bool) {
+	block = strings.TrimRight(block, "\x00")
+
+	const key = "StreamTitle='"
+	start := strings.Index(block, key)
+	if start < 0 {
+		return "", false
+	}
+	value := block[start+len(key):]
+
+	if end := strings.Index(value, "';"); end >= 0 {
+		value = value[:end]
+	} else {
+		value = strings.TrimSuffix(value, "'")
+	}
+
+	return strings.TrimSpace(value), true
+}
+
+// icyConn rewrites a leading "ICY" status line to "HTTP/1.0" so that
+// Shoutcast v1 responses can be read by net/http.
+type icyConn struct {
+	net.Conn
+	checked bool
+	pending []byte
+}
+
+// Read implements io.Reader.
+func (c *icyConn) Read(p []byte) (int, error) {
+	if !c.checked {
+		c.checked = true
+
+		head := make([]byte, 3)
+		n, err := io.ReadFull(c.Conn, head)
+		if n == 3 && string(head) == "ICY" {
+			c.pending = []byte("HTTP/1.0")
+		} else {
+			c.pending = head[:n]
+		}
+		if err != nil && len(c.pending) == 0 {
+			return 0, err
+		}
+	}
+
+	if len(c.pending) > 0 {
+		n := copy(p, c.pending)
+		c.pending = c.pending[n:]
+		return n, nil
+	}
+
+	return c.Conn.Read(p)
+}
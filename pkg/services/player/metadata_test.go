@@ -0,0 +1,185 @@
+package player
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// icyBlock encodes a metadata block, prefixed by its length byte.
+func icyBlock(title string) []byte {
+	meta := fmt.Sprintf("StreamTitle='%s';StreamUrl='';", title)
+	blocks := (len(meta) + 15) / 16
+	data := make([]byte, 1+blocks*16)
+	data[0] = byte(blocks)
+	copy(data[1:], meta)
+	return data
+}
+
+// icyBody builds a stream body with metaint bytes of audio before each block.
+// An empty title produces an empty metadata block.
+func icyBody(metaint int, titles ...string) []byte {
+	var b bytes.Buffer
+	audio := bytes.Repeat([]byte{0xFF}, metaint)
+	for _, title := range titles {
+		b.Write(audio)
+		if title == "" {
+			b.WriteByte(0)
+		} else {
+			b.Write(icyBlock(title))
+		}
+	}
+	return b.Bytes()
+}
+
+// collectTitles drains updates until the channel closes or times out.
+func collectTitles(t *testing.T, updates <-chan Metadata) []string {
+	t.Helper()
+
+	var titles []string
+	for {
+		select {
+		case md, ok := <-updates:
+			if !ok {
+				return titles
+			}
+			titles = append(titles, md.StreamTitle)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for metadata, got %v", titles)
+		}
+	}
+}
+
+func TestMetadataReaderIcecast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Icy-MetaData") != "1" {
+			http.Error(w, "metadata not requested", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("icy-metaint", "16")
+		_, _ = w.Write(icyBody(16,
+			"Artist A - Song 1",
+			"Artist A - Song 1", // Repeated titles are not published twice
+			"",
+			"Guns N' Roses - Don't Cry",
+		))
+	}))
+	defer server.Close()
+
+	reader := NewMetadataReader()
+	updates, err := reader.Watch(context.Background(), &radiobrowser.Station{URLResolved: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to watch metadata: %v", err)
+	}
+
+	titles := collectTitles(t, updates)
+	expected := []string{"Artist A - Song 1", "Guns N' Roses - Don't Cry"}
+	if fmt.Sprint(titles) != fmt.Sprint(expected) {
+		t.Errorf("Expected titles %v, got %v", expected, titles)
+	}
+}
+
+func TestMetadataReaderShoutcast(t *testing.T) {
+	// Shoutcast v1 replies with an "ICY 200 OK" status line
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Failed to hijack connection: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		_, _ = buf.WriteString("ICY 200 OK\r\nicy-name:Test\r\nicy-metaint:8\r\ncontent-type:audio/mpeg\r\n\r\n")
+		_, _ = buf.Write(icyBody(8, "Shoutcast Artist - Track"))
+		_ = buf.Flush()
+	}))
+	defer server.Close()
+
+	reader := NewMetadataReader()
+	updates, err := reader.Watch(context.Background(), &radiobrowser.Station{URLResolved: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to watch metadata: %v", err)
+	}
+
+	titles := collectTitles(t, updates)
+	if len(titles) != 1 || titles[0] != "Shoutcast Artist - Track" {
+		t.Errorf("Expected Shoutcast title, got %v", titles)
+	}
+}
+
+func TestMetadataReaderNoMetaint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		_, _ = w.Write([]byte("audio"))
+	}))
+	defer server.Close()
+
+	reader := NewMetadataReader()
+	if _, err := reader.Watch(context.Background(), &radiobrowser.Station{URLResolved: server.URL}); err == nil {
+		t.Errorf("Expected error for stream without icy-metaint")
+	}
+}
+
+func TestMetadataReaderCancel(t *testing.T) {
+	// A live stream that never ends
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("icy-metaint", "16")
+		_, _ = w.Write(icyBody(16, "Live - Forever"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := NewMetadataReader()
+	updates, err := reader.Watch(ctx, &radiobrowser.Station{URLResolved: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to watch metadata: %v", err)
+	}
+
+	select {
+	case md := <-updates:
+		if md.StreamTitle != "Live - Forever" {
+			t.Errorf("Unexpected title %q", md.StreamTitle)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for metadata")
+	}
+
+	cancel()
+	if titles := collectTitles(t, updates); len(titles) != 0 {
+		t.Errorf("Expected no more titles after cancel, got %v", titles)
+	}
+}
+
+func TestParseStreamTitle(t *testing.T) {
+	tests := []struct {
+		name  string
+		block string
+		want  string
+		ok    bool
+	}{
+		{"simple", "StreamTitle='Artist - Title';", "Artist - Title", true},
+		{"with url", "StreamTitle='A - B';StreamUrl='http://x';\x00\x00", "A - B", true},
+		{"quotes", "StreamTitle='Don't Stop';", "Don't Stop", true},
+		{"unterminated", "StreamTitle='Cut off'", "Cut off", true},
+		{"empty", "StreamTitle='';", "", true},
+		{"missing", "StreamUrl='http://x';", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseStreamTitle(tt.block)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseStreamTitle(%q) = %q, %v; want %q, %v", tt.block, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
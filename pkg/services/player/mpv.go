@@ -0,0 +1,423 @@
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+const (
+	// mpvDialTimeout is how long to wait for mpv to create its IPC socket.
+	mpvDialTimeout = 5 * time.Second
+	// mpvCommandTimeout is how long to wait for mpv to answer a command.
+	mpvCommandTimeout = 2 * time.Second
+)
+
+// mpvSocketSeq makes IPC socket paths unique across launches, so a new
+// connection can never reach an mpv process that is still shutting down.
+var mpvSocketSeq atomic.Uint64
+
+// MpvPlayer implements Player using mpv controlled over its JSON IPC socket.
+// Unlike FFplayPlayer, volume changes and pause/resume are applied to the
+// running process instead of restarting the stream.
+type MpvPlayer struct {
+	mu             sync.RWMutex
+	cmd            *exec.Cmd
+	ipc            *mpvIPC
+	state          State
+	currentStation *radiobrowser.Station
+	volume         int
+	mpvPath        string
+	socketDir      string
+	socketPath     string
+	dialTimeout    time.Duration
+	processActive  bool
+	events         eventHub
+}
+
+// NewMpvPlayer creates a new mpv-based player.
+func NewMpvPlayer(mpvPath string) *MpvPlayer {
+	if mpvPath == "" {
+		mpvPath = "mpv"
+	}
+
+	return &MpvPlayer{
+		state:       StateStopped,
+		volume:      70, // Default volume
+		mpvPath:     mpvPath,
+		socketDir:   os.TempDir(),
+		dialTimeout: mpvDialTimeout,
+	}
+}
+
+// Play starts playing a radio station.
+func (p *MpvPlayer) Play(station *radiobrowser.Station) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
+
+	// Stop any current playback
+	if err := p.stopLocked(); err != nil {
+		return fmt.Errorf("failed to stop current playback: %w", err)
+	}
+
+	if station == nil || station.URLResolved == "" {
+		return fmt.Errorf("invalid station or URL")
+	}
+
+	socketPath := filepath.Join(p.socketDir,
+		fmt.Sprintf("terminal-fm-mpv-%d-%d.sock", os.Getpid(), mpvSocketSeq.Add(1)))
+
+	// Build mpv command
+	// --no-video: audio only
+	// --no-terminal: don't read keys from or write to our TTY
+	// --input-ipc-server: JSON IPC socket used for all later control
+	// --volume: initial volume (0-100)
+	// --: ends options, so a URL starting with "-" can't be read as one
+	args := []string{
+		"--no-video",
+		"--no-terminal",
+		"--input-ipc-server=" + socketPath,
+		fmt.Sprintf("--volume=%d", p.volume),
+		"--",
+		station.URLResolved,
+	}
+
+	cmd := exec.Command(p.mpvPath, args...)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start mpv: %w", err)
+	}
+
+	// mpv creates the socket shortly after startup
+	ipc, err := dialMpvIPC(socketPath, p.dialTimeout)
+	if err != nil {
+		_ = cmd.Process.Signal(syscall.SIGKILL)
+		_ = cmd.Wait()
+		return fmt.Errorf("failed to connect to mpv: %w", err)
+	}
+
+	p.cmd = cmd
+	p.ipc = ipc
+	p.socketPath = socketPath
+	p.state = StatePlaying
+	p.currentStation = station
+	p.processActive = true
+
+	// Monitor process in background
+	go func() {
+		err := cmd.Wait()
+
+		ipc.close()
+		_ = os.Remove(socketPath)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		// Only clean up if this is still our active command
+		if p.cmd == cmd {
+			unexpected := p.processActive
+
+			p.processActive = false
+			p.state = StateStopped
+			p.currentStation = nil
+			p.cmd = nil
+			p.ipc = nil
+
+			if unexpected {
+				if err == nil {
+					err = fmt.Errorf("stream ended")
+				}
+				p.emitLocked(fmt.Errorf("mpv stopped: %w", err))
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the current playback.
+func (p *MpvPlayer) Stop() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
+	return p.stopLocked()
+}
+
+// stopLocked stops playback without acquiring the lock (internal use).
+func (p *MpvPlayer) stopLocked() error {
+	wasActive := p.processActive
+	p.processActive = false
+	p.state = StateStopped
+
+	if wasActive {
+		// Ask mpv to quit, then signal it in case it is unresponsive.
+		// mpv may take a while to answer, so this happens without the
+		// lock, and the process is reaped by the monitoring goroutine.
+		ipc, cmd := p.ipc, p.cmd
+		go func() {
+			if ipc != nil {
+				_ = ipc.command("quit")
+				ipc.close()
+			}
+			if cmd != nil && cmd.Process != nil {
+				if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+					_ = cmd.Process.Signal(syscall.SIGKILL)
+				}
+			}
+		}()
+	}
+
+	p.ipc = nil
+	p.currentStation = nil
+
+	return nil
+}
+
+// Pause pauses the current playback without dropping the stream.
+func (p *MpvPlayer) Pause() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
+
+	if p.state != StatePlaying || p.ipc == nil {
+		return fmt.Errorf("nothing is playing")
+	}
+
+	if err := p.ipc.command("set_property", "pause", true); err != nil {
+		return fmt.Errorf("failed to pause: %w", err)
+	}
+
+	p.state = StatePaused
+	return nil
+}
+
+// Resume resumes paused playback.
+func (p *MpvPlayer) Resume() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
+
+	if p.state != StatePaused || p.ipc == nil {
+		return fmt.Errorf("playback is not paused")
+	}
+
+	if err := p.ipc.command("set_property", "pause", false); err != nil {
+		return fmt.Errorf("failed to resume: %w", err)
+	}
+
+	p.state = StatePlaying
+	return nil
+}
+
+// GetState returns the current playback state.
+func (p *MpvPlayer) GetState() State {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.state
+}
+
+// GetCurrentStation returns the currently playing station.
+func (p *MpvPlayer) GetCurrentStation() *radiobrowser.Station {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.currentStation
+}
+
+// SetVolume sets the playback volume (0-100).
+// The change is applied to the running process without restarting the stream.
+func (p *MpvPlayer) SetVolume(volume int) error {
+	if volume < 0 || volume > 100 {
+		return fmt.Errorf("volume must be between 0 and 100")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
+
+	p.volume = volume
+
+	if p.ipc != nil {
+		if err := p.ipc.command("set_property", "volume", volume); err != nil {
+			return fmt.Errorf("failed to set volume: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetVolume returns the current volume.
+func (p *MpvPlayer) GetVolume() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.volume
+}
+
+// Events returns a new subscription to state changes.
+func (p *MpvPlayer) Events() <-chan Event {
+	return p.events.subscribe()
+}
+
+// Unsubscribe ends a subscription from Events, closing its channel.
+func (p *MpvPlayer) Unsubscribe(events <-chan Event) {
+	p.events.unsubscribe(events)
+}
+
+// emitLocked publishes the current state (internal use).
+func (p *MpvPlayer) emitLocked(err error) {
+	p.events.publish(Event{State: p.state, Station: p.currentStation, Volume: p.volume, Err: err})
+}
+
+// Cleanup forcefully stops playback and cleans up resources.
+// Should be called when the session ends.
+func (p *MpvPlayer) Cleanup() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
+
+	if p.ipc != nil {
+		p.ipc.close()
+	}
+
+	if p.cmd != nil && p.cmd.Process != nil {
+		// Force kill the process
+		_ = p.cmd.Process.Signal(syscall.SIGKILL)
+	}
+
+	p.cmd = nil
+	p.ipc = nil
+	p.state = StateStopped
+	p.currentStation = nil
+	p.processActive = false
+
+	return nil
+}
+
+// mpvRequest is a single command sent over the mpv JSON IPC socket.
+type mpvRequest struct {
+	Command   []interface{} `json:"command"`
+	RequestID int           `json:"request_id"`
+}
+
+// mpvResponse is a reply or an asynchronous event read from the socket.
+type mpvResponse struct {
+	RequestID int             `json:"request_id"`
+	Error     string          `json:"error"`
+	Data      json.RawMessage `json:"data"`
+	Event     string          `json:"event"`
+}
+
+// mpvIPC is a minimal client for mpv's line-delimited JSON IPC protocol.
+type mpvIPC struct {
+	conn net.Conn
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]chan mpvResponse
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// dialMpvIPC connects to the mpv socket at path, retrying until it appears
+// or the timeout expires.
+func dialMpvIPC(path string, timeout time.Duration) (*mpvIPC, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			ipc := &mpvIPC{
+				conn:    conn,
+				pending: make(map[int]chan mpvResponse),
+				done:    make(chan struct{}),
+			}
+			go ipc.readLoop()
+			return ipc, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("IPC socket %s not available: %w", path, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// command sends a command and waits for mpv to acknowledge it.
+func (c *mpvIPC) command(args ...interface{}) error {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	reply := make(chan mpvResponse, 1)
+	c.pending[id] = reply
+
+	data, err := json.Marshal(mpvRequest{Command: args, RequestID: id})
+	if err == nil {
+		_, err = c.conn.Write(append(data, '\n'))
+	}
+	if err != nil {
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+	c.mu.Unlock()
+
+	select {
+	case resp := <-reply:
+		if resp.Error != "success" {
+			return fmt.Errorf("mpv: %s", resp.Error)
+		}
+		return nil
+	case <-c.done:
+		return fmt.Errorf("IPC connection closed")
+	case <-time.After(mpvCommandTimeout):
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return fmt.Errorf("timed out waiting for mpv")
+	}
+}
+
+// readLoop dispatches replies to their waiting commands until the
+// connection is closed. Events are ignored.
+func (c *mpvIPC) readLoop() {
+	defer c.close()
+
+	scanner := bufio.NewScanner(c.conn)
+	for scanner.Scan() {
+		var resp mpvResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			continue
+		}
+		if resp.Event != "" || resp.RequestID == 0 {
+			continue
+		}
+
+		c.mu.Lock()
+		reply, ok := c.pending[resp.RequestID]
+		delete(c.pending, resp.RequestID)
+		c.mu.Unlock()
+
+		if ok {
+			reply <- resp
+		}
+	}
+}
+
+// close shuts down the connection. It is safe to call more than once.
+func (c *mpvIPC) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
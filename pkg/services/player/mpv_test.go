@@ -0,0 +1,249 @@
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// fakeMpv stands in for mpv: a shell script records its arguments and
+// idles, while a unix socket server answers IPC commands like mpv would.
+type fakeMpv struct {
+	t        *testing.T
+	path     string
+	argsFile string
+
+	// ignoreQuit makes the server leave quit commands unanswered, like a
+	// hung mpv.
+	ignoreQuit bool
+
+	mu       sync.Mutex
+	commands [][]interface{}
+}
+
+func newFakeMpv(t *testing.T) *fakeMpv {
+	t.Helper()
+
+	dir := t.TempDir()
+	f := &fakeMpv{
+		t:        t,
+		path:     filepath.Join(dir, "mpv"),
+		argsFile: filepath.Join(dir, "args"),
+	}
+
+	script := "#!/bin/sh\necho \"$@\" > " + f.argsFile + "\nexec sleep 30\n"
+	if err := os.WriteFile(f.path, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake mpv: %v", err)
+	}
+
+	return f
+}
+
+// serve waits for the fake mpv to be launched, then listens on the IPC
+// socket path it was given.
+func (f *fakeMpv) serve() {
+	go func() {
+		var socketPath string
+		for i := 0; i < 100 && socketPath == ""; i++ {
+			data, _ := os.ReadFile(f.argsFile)
+			for _, arg := range strings.Fields(string(data)) {
+				if strings.HasPrefix(arg, "--input-ipc-server=") {
+					socketPath = strings.TrimPrefix(arg, "--input-ipc-server=")
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if socketPath == "" {
+			f.t.Errorf("fake mpv was never launched")
+			return
+		}
+
+		ln, err := net.Listen("unix", socketPath)
+		if err != nil {
+			f.t.Errorf("Failed to listen on IPC socket: %v", err)
+			return
+		}
+		defer ln.Close()
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var req mpvRequest
+			if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+				continue
+			}
+
+			f.mu.Lock()
+			f.commands = append(f.commands, req.Command)
+			f.mu.Unlock()
+
+			if f.ignoreQuit && len(req.Command) > 0 && req.Command[0] == "quit" {
+				continue
+			}
+
+			// Interleave an event to make sure the client skips it
+			_, _ = conn.Write([]byte(`{"event":"property-change","id":1}` + "\n"))
+			reply, _ := json.Marshal(map[string]interface{}{
+				"request_id": req.RequestID,
+				"error":      "success",
+				"data":       nil,
+			})
+			_, _ = conn.Write(append(reply, '\n'))
+		}
+	}()
+}
+
+// lastCommand returns the most recent command received by the server.
+func (f *fakeMpv) lastCommand() []interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.commands) == 0 {
+		return nil
+	}
+	return f.commands[len(f.commands)-1]
+}
+
+func TestMpvPlayerIPC(t *testing.T) {
+	fake := newFakeMpv(t)
+	fake.serve()
+
+	p := NewMpvPlayer(fake.path)
+	defer p.Cleanup()
+
+	station := &radiobrowser.Station{
+		StationUUID: "test-uuid",
+		Name:        "Test Station",
+		URLResolved: "http://example.com/stream",
+	}
+
+	if err := p.Play(station); err != nil {
+		t.Fatalf("Failed to start playback: %v", err)
+	}
+
+	if p.GetState() != StatePlaying {
+		t.Errorf("Expected state to be Playing, got %v", p.GetState())
+	}
+
+	args, _ := os.ReadFile(fake.argsFile)
+	if !strings.Contains(string(args), "--volume=70") || !strings.Contains(string(args), "-- "+station.URLResolved) {
+		t.Errorf("Unexpected mpv arguments: %s", args)
+	}
+
+	pid := p.cmd.Process.Pid
+
+	// Volume changes go over IPC without restarting the process
+	if err := p.SetVolume(40); err != nil {
+		t.Fatalf("Failed to set volume: %v", err)
+	}
+	if got := fake.lastCommandString(); got != "set_property volume 40" {
+		t.Errorf("Expected volume command, got %q", got)
+	}
+	if p.cmd.Process.Pid != pid {
+		t.Errorf("Expected mpv process to be reused after volume change")
+	}
+
+	if err := p.Pause(); err != nil {
+		t.Fatalf("Failed to pause: %v", err)
+	}
+	if p.GetState() != StatePaused {
+		t.Errorf("Expected state to be Paused, got %v", p.GetState())
+	}
+	if got := fake.lastCommandString(); got != "set_property pause true" {
+		t.Errorf("Expected pause command, got %q", got)
+	}
+
+	if err := p.Resume(); err != nil {
+		t.Fatalf("Failed to resume: %v", err)
+	}
+	if p.GetState() != StatePlaying {
+		t.Errorf("Expected state to be Playing after resume, got %v", p.GetState())
+	}
+
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Failed to stop: %v", err)
+	}
+	if p.GetState() != StateStopped || p.GetCurrentStation() != nil {
+		t.Errorf("Expected player to be stopped with no station")
+	}
+	fake.waitForCommand("quit")
+}
+
+func TestMpvPlayerStopDoesNotWaitForQuit(t *testing.T) {
+	fake := newFakeMpv(t)
+	fake.ignoreQuit = true
+	fake.serve()
+
+	p := NewMpvPlayer(fake.path)
+	defer p.Cleanup()
+
+	if err := p.Play(&radiobrowser.Station{Name: "Test", URLResolved: "http://example.com/stream"}); err != nil {
+		t.Fatalf("Failed to start playback: %v", err)
+	}
+
+	// mpv never answers the quit, yet the player is free straight away
+	start := time.Now()
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Failed to stop: %v", err)
+	}
+	fake.waitForCommand("quit")
+	if p.GetState() != StateStopped {
+		t.Errorf("Expected state to be Stopped, got %v", p.GetState())
+	}
+	if elapsed := time.Since(start); elapsed >= mpvCommandTimeout/2 {
+		t.Errorf("Expected Stop not to wait for mpv, took %v", elapsed)
+	}
+}
+
+func TestMpvPlayerNoSocket(t *testing.T) {
+	// The fake binary never gets a server, so the dial must time out
+	fake := newFakeMpv(t)
+
+	p := NewMpvPlayer(fake.path)
+	p.dialTimeout = 200 * time.Millisecond
+	defer p.Cleanup()
+
+	err := p.Play(&radiobrowser.Station{Name: "Test", URLResolved: "http://example.com/stream"})
+	if err == nil {
+		t.Fatalf("Expected error when mpv never opens its IPC socket")
+	}
+	if p.GetState() != StateStopped {
+		t.Errorf("Expected state to be Stopped, got %v", p.GetState())
+	}
+}
+
+// waitForCommand waits until want is the most recent command.
+func (f *fakeMpv) waitForCommand(want string) {
+	f.t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for f.lastCommandString() != want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := f.lastCommandString(); got != want {
+		f.t.Errorf("Expected %s command, got %q", want, got)
+	}
+}
+
+// lastCommandString formats the most recent command as a space-separated string.
+func (f *fakeMpv) lastCommandString() string {
+	cmd := f.lastCommand()
+	parts := make([]string, len(cmd))
+	for i, c := range cmd {
+		data, _ := json.Marshal(c)
+		parts[i] = strings.Trim(string(data), `"`)
+	}
+	return strings.Join(parts, " ")
+}
@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
 )
@@ -18,7 +19,7 @@ const (
 	StateStopped State = iota
 	// StatePlaying means audio is currently playing.
 	StatePlaying
-	// StatePaused means playback is paused (not supported by all players).
+	// StatePaused means playback is paused.
 	StatePaused
 	// StateBuffering means the player is buffering.
 	StateBuffering
@@ -30,6 +31,10 @@ type Player interface {
 	Play(station *radiobrowser.Station) error
 	// Stop stops the current playback.
 	Stop() error
+	// Pause pauses the current playback.
+	Pause() error
+	// Resume resumes paused playback.
+	Resume() error
 	// GetState returns the current playback state.
 	GetState() State
 	// GetCurrentStation returns the currently playing station, or nil.
@@ -38,9 +43,19 @@ type Player interface {
 	SetVolume(volume int) error
 	// GetVolume returns the current volume (0-100).
 	GetVolume() int
+	// Events returns a new subscription to state changes. Events are
+	// published for every change, including a stream dropping on its own.
+	Events() <-chan Event
+	// Unsubscribe ends a subscription from Events, closing its channel.
+	Unsubscribe(events <-chan Event)
 }
 
 // FFplayPlayer implements Player using ffplay.
+//
+// If ffplay exits without being asked to, for example because the stream
+// dropped, the player relaunches it with exponential backoff, reporting
+// StateBuffering meanwhile. After maxRetries failed attempts it gives up and
+// publishes an Event carrying the error.
 type FFplayPlayer struct {
 	mu             sync.RWMutex
 	cmd            *exec.Cmd
@@ -49,6 +64,20 @@ type FFplayPlayer struct {
 	volume         int
 	ffplayPath     string
 	processActive  bool
+
+	// session changes on every Play and Stop, so that a pending reconnect
+	// can tell it has been superseded.
+	session    uint64
+	retries    int
+	maxRetries int
+	// retryDelay is the first reconnect delay; it doubles on each attempt
+	// up to maxRetryDelay.
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
+	// stableAfter is how long ffplay must run for the retry count to reset.
+	stableAfter time.Duration
+
+	events eventHub
 }
 
 // NewFFplayPlayer creates a new ffplay-based player.
@@ -58,16 +87,48 @@ func NewFFplayPlayer(ffplayPath string) *FFplayPlayer {
 	}
 
 	return &FFplayPlayer{
-		state:      StateStopped,
-		volume:     70, // Default volume
-		ffplayPath: ffplayPath,
+		state:         StateStopped,
+		volume:        70, // Default volume
+		ffplayPath:    ffplayPath,
+		maxRetries:    3,
+		retryDelay:    time.Second,
+		maxRetryDelay: 30 * time.Second,
+		stableAfter:   30 * time.Second,
+	}
+}
+
+// SetMaxRetries sets how many times a dropped stream is reconnected before
+// playback stops. Zero disables reconnecting.
+func (p *FFplayPlayer) SetMaxRetries(retries int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if retries < 0 {
+		retries = 0
 	}
+	p.maxRetries = retries
+}
+
+// Events returns a new subscription to state changes.
+func (p *FFplayPlayer) Events() <-chan Event {
+	return p.events.subscribe()
+}
+
+// Unsubscribe ends a subscription from Events, closing its channel.
+func (p *FFplayPlayer) Unsubscribe(events <-chan Event) {
+	p.events.unsubscribe(events)
+}
+
+// emitLocked publishes the current state (internal use).
+func (p *FFplayPlayer) emitLocked(err error) {
+	p.events.publish(Event{State: p.state, Station: p.currentStation, Volume: p.volume, Err: err})
 }
 
 // Play starts playing a radio station.
 func (p *FFplayPlayer) Play(station *radiobrowser.Station) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
 
 	// Stop any current playback
 	if err := p.stopLocked(); err != nil {
@@ -78,55 +139,121 @@ func (p *FFplayPlayer) Play(station *radiobrowser.Station) error {
 		return fmt.Errorf("invalid station or URL")
 	}
 
+	p.retries = 0
+	return p.startLocked(station)
+}
+
+// startLocked launches ffplay for station and supervises it.
+func (p *FFplayPlayer) startLocked(station *radiobrowser.Station) error {
 	// Build ffplay command
 	// -nodisp: no video display
 	// -loglevel quiet: suppress output
 	// -autoexit: exit when playback ends
 	// -volume: set volume (0-100)
+	// --: ends options, so a URL starting with "-" can't be read as one
 	args := []string{
 		"-nodisp",
 		"-loglevel", "quiet",
 		"-autoexit",
 		"-volume", fmt.Sprintf("%d", p.volume),
+		"--",
 		station.URLResolved,
 	}
 
-	p.cmd = exec.Command(p.ffplayPath, args...)
+	cmd := exec.Command(p.ffplayPath, args...)
 
 	// Start the player
-	if err := p.cmd.Start(); err != nil {
+	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start ffplay: %w", err)
 	}
 
+	p.cmd = cmd
 	p.state = StatePlaying
 	p.currentStation = station
 	p.processActive = true
 
 	// Monitor process in background
-	cmd := p.cmd // Capture cmd for goroutine
+	startedAt := time.Now()
 	go func() {
-		_ = cmd.Wait() // Ignore wait errors in goroutine
+		err := cmd.Wait()
 
-		// Cleanup after process exits
 		p.mu.Lock()
 		defer p.mu.Unlock()
 
-		// Only clean up if this is still our active command
-		if p.cmd == cmd {
-			p.processActive = false
+		// Only handle the exit if this is still our active command
+		if p.cmd != cmd {
+			return
+		}
+		p.cmd = nil
+
+		if !p.processActive {
+			// Stopped on purpose
 			p.state = StateStopped
 			p.currentStation = nil
-			p.cmd = nil
+			return
+		}
+		p.processActive = false
+
+		if time.Since(startedAt) >= p.stableAfter {
+			p.retries = 0
 		}
+		if err == nil {
+			err = fmt.Errorf("stream ended")
+		}
+		p.reconnectLocked(err)
 	}()
 
 	return nil
 }
 
+// reconnectLocked schedules a relaunch of the current station after an
+// unexpected exit, or gives up once maxRetries is reached.
+func (p *FFplayPlayer) reconnectLocked(cause error) {
+	station := p.currentStation
+
+	if p.retries >= p.maxRetries || station == nil {
+		p.state = StateStopped
+		p.currentStation = nil
+
+		err := fmt.Errorf("lost connection to stream: %w", cause)
+		if p.retries > 0 {
+			err = fmt.Errorf("lost connection to stream after %d reconnect attempts: %w", p.retries, cause)
+		}
+		p.emitLocked(err)
+		return
+	}
+
+	delay := p.retryDelay << p.retries
+	if delay > p.maxRetryDelay || delay <= 0 {
+		delay = p.maxRetryDelay
+	}
+	p.retries++
+	p.state = StateBuffering
+	p.emitLocked(nil)
+
+	session := p.session
+	time.AfterFunc(delay, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		// Stopped or switched stations while waiting
+		if p.session != session {
+			return
+		}
+
+		if err := p.startLocked(station); err != nil {
+			p.reconnectLocked(err)
+			return
+		}
+		p.emitLocked(nil)
+	})
+}
+
 // Stop stops the current playback.
 func (p *FFplayPlayer) Stop() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
 	return p.stopLocked()
 }
 
@@ -136,6 +263,7 @@ func (p *FFplayPlayer) stopLocked() error {
 	wasActive := p.processActive
 	p.processActive = false
 	p.state = StateStopped
+	p.session++ // Cancels any pending reconnect
 
 	// Try to kill if we had an active process
 	if wasActive && p.cmd != nil && p.cmd.Process != nil {
@@ -144,6 +272,8 @@ func (p *FFplayPlayer) stopLocked() error {
 			// If SIGTERM fails, try SIGKILL
 			_ = p.cmd.Process.Signal(syscall.SIGKILL)
 		}
+		// A paused process only handles SIGTERM once it is continued
+		_ = p.cmd.Process.Signal(syscall.SIGCONT)
 		// Don't wait here - the goroutine will handle cleanup
 	}
 
@@ -152,6 +282,43 @@ func (p *FFplayPlayer) stopLocked() error {
 	return nil
 }
 
+// Pause suspends the ffplay process with SIGSTOP.
+// Live streams may skip ahead or reconnect after a long pause.
+func (p *FFplayPlayer) Pause() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
+
+	if p.state != StatePlaying || !p.processActive || p.cmd == nil || p.cmd.Process == nil {
+		return fmt.Errorf("nothing is playing")
+	}
+
+	if err := p.cmd.Process.Signal(syscall.SIGSTOP); err != nil {
+		return fmt.Errorf("failed to pause ffplay: %w", err)
+	}
+
+	p.state = StatePaused
+	return nil
+}
+
+// Resume continues a paused ffplay process with SIGCONT.
+func (p *FFplayPlayer) Resume() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
+
+	if p.state != StatePaused || !p.processActive || p.cmd == nil || p.cmd.Process == nil {
+		return fmt.Errorf("playback is not paused")
+	}
+
+	if err := p.cmd.Process.Signal(syscall.SIGCONT); err != nil {
+		return fmt.Errorf("failed to resume ffplay: %w", err)
+	}
+
+	p.state = StatePlaying
+	return nil
+}
+
 // GetState returns the current playback state.
 func (p *FFplayPlayer) GetState() State {
 	p.mu.RLock()
@@ -175,6 +342,7 @@ func (p *FFplayPlayer) SetVolume(volume int) error {
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
 
 	p.volume = volume
 
@@ -208,6 +376,7 @@ func (p *FFplayPlayer) GetVolume() int {
 func (p *FFplayPlayer) Cleanup() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
 
 	if p.cmd != nil && p.cmd.Process != nil {
 		// Force kill the process
@@ -218,17 +387,7 @@ func (p *FFplayPlayer) Cleanup() error {
 	p.state = StateStopped
 	p.currentStation = nil
 	p.processActive = false
+	p.session++
 
 	return nil
 }
-
-// MpvPlayer implements Player using mpv (for future implementation).
-type MpvPlayer struct {
-	// TODO: Implement mpv-based player in future
-}
-
-// NewMpvPlayer creates a new mpv-based player.
-func NewMpvPlayer(mpvPath string) *MpvPlayer {
-	// TODO: Implement
-	return &MpvPlayer{}
-}
@@ -2,57 +2,175 @@
 package player
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"sync"
 
+	"github.com/fulgidus/terminal-fm/pkg/protocol"
 	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
 )
 
+// ErrNoClient is returned when nothing on the other end of the session can
+// play audio.
+var ErrNoClient = errors.New("no player on your side; connect with terminal-fm-client to listen")
+
+// remoteCaps are the capabilities the server offers in its HELLO.
+var remoteCaps = []string{protocol.CapPlay, protocol.CapPause, protocol.CapVolume, protocol.CapStatus}
+
 // RemotePlayer implements Player by sending control commands to a remote client.
-// It uses custom OSC sequences to communicate with the terminal-radio client wrapper.
+// It speaks the protocol package's OSC protocol with terminal-fm-client: call
+// Hello when the session starts, and pass the payloads the client sends back
+// to HandleReply. Nothing plays until the client has answered the HELLO.
 type RemotePlayer struct {
 	mu             sync.RWMutex
 	state          State
 	currentStation *radiobrowser.Station
 	volume         int
-	writer         io.Writer
-}
+	encoder        *protocol.Encoder
+	events         eventHub
 
-// Custom OSC sequences for terminal-radio client communication
-const (
-	oscPrefix = "\033]8888;"
-	oscSuffix = "\007"
-)
+	// caps holds the capabilities the client announced; nil until it has
+	// replied to Hello.
+	caps map[string]bool
+	// lastSeq is the sequence number of the last command sent, so that
+	// status replies to earlier commands can be told apart.
+	lastSeq uint64
+}
 
 // NewRemotePlayer creates a new remote player that sends commands to the client wrapper.
 func NewRemotePlayer(writer io.Writer) *RemotePlayer {
-	return &RemotePlayer{
+	p := &RemotePlayer{
 		state:  StateStopped,
 		volume: 70,
-		writer: writer,
 	}
+	if writer != nil {
+		p.encoder = protocol.NewEncoder(writer)
+	}
+	return p
+}
+
+// Hello starts the handshake with the client.
+func (p *RemotePlayer) Hello() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.sendLocked(protocol.Hello(remoteCaps...))
+}
+
+// Connected reports whether a client has answered the handshake.
+func (p *RemotePlayer) Connected() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.caps != nil
+}
+
+// HandleReply processes a message payload sent by the client.
+func (p *RemotePlayer) HandleReply(payload string) error {
+	msg, err := protocol.Decode(payload)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch msg.Type {
+	case protocol.TypeHello:
+		versions, caps, err := protocol.ParseHello(msg)
+		if err != nil {
+			return err
+		}
+		if !containsVersion(versions, protocol.Version) {
+			return fmt.Errorf("%w: client supports %v", protocol.ErrUnsupportedVersion, versions)
+		}
+
+		p.caps = make(map[string]bool, len(caps))
+		for _, c := range caps {
+			p.caps[c] = true
+		}
+		return nil
+
+	case protocol.TypeStatus:
+		status, err := protocol.ParseStatus(msg)
+		if err != nil {
+			return err
+		}
+		p.applyStatusLocked(status)
+		return nil
+
+	default:
+		return fmt.Errorf("unexpected %s from client", msg.Type)
+	}
+}
+
+// applyStatusLocked updates the state from a client status report.
+func (p *RemotePlayer) applyStatusLocked(status protocol.Status) {
+	// A reply to an earlier command; the reply to the latest is on its way
+	if status.Ack < p.lastSeq {
+		return
+	}
+
+	var err error
+	if status.Err != "" {
+		err = fmt.Errorf("client player: %s", status.Err)
+	}
+
+	state := p.state
+	switch status.State {
+	case protocol.StateStopped:
+		state = StateStopped
+	case protocol.StatePlaying:
+		state = StatePlaying
+	case protocol.StatePaused:
+		state = StatePaused
+	case protocol.StateBuffering:
+		state = StateBuffering
+	}
+
+	if state == p.state && status.Volume == p.volume && err == nil {
+		return
+	}
+
+	p.state = state
+	if state == StateStopped {
+		p.currentStation = nil
+	}
+	if status.Volume >= 0 && status.Volume <= 100 {
+		p.volume = status.Volume
+	}
+	p.emitLocked(err)
+}
+
+// containsVersion reports whether versions includes v.
+func containsVersion(versions []int, v int) bool {
+	for _, version := range versions {
+		if version == v {
+			return true
+		}
+	}
+	return false
 }
 
 // Play starts playing a radio station by sending a PLAY command to the client.
 func (p *RemotePlayer) Play(station *radiobrowser.Station) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
 
 	if station == nil || station.URLResolved == "" {
 		return fmt.Errorf("invalid station or URL")
 	}
 
-	if p.writer == nil {
+	if p.encoder == nil {
 		return fmt.Errorf("no output writer configured")
 	}
 
-	// Stop any current playback first (ignore errors)
-	_ = p.sendCommand("STOP")
+	if !p.caps[protocol.CapPlay] {
+		return ErrNoClient
+	}
 
-	// Send PLAY command with URL and volume
-	cmd := fmt.Sprintf("PLAY;%s;%d", station.URLResolved, p.volume)
-	if err := p.sendCommand(cmd); err != nil {
+	if err := p.sendLocked(protocol.Play(station.URLResolved, p.volume)); err != nil {
 		return fmt.Errorf("failed to send play command: %w", err)
 	}
 
@@ -66,12 +184,13 @@ func (p *RemotePlayer) Play(station *radiobrowser.Station) error {
 func (p *RemotePlayer) Stop() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
 
 	if p.state == StateStopped {
 		return nil
 	}
 
-	if err := p.sendCommand("STOP"); err != nil {
+	if err := p.sendLocked(protocol.Stop()); err != nil {
 		return fmt.Errorf("failed to send stop command: %w", err)
 	}
 
@@ -81,6 +200,46 @@ func (p *RemotePlayer) Stop() error {
 	return nil
 }
 
+// Pause pauses playback by sending a PAUSE command to the client.
+func (p *RemotePlayer) Pause() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
+
+	if p.state != StatePlaying {
+		return fmt.Errorf("nothing is playing")
+	}
+
+	if !p.caps[protocol.CapPause] {
+		return fmt.Errorf("your player cannot pause")
+	}
+
+	if err := p.sendLocked(protocol.Pause()); err != nil {
+		return fmt.Errorf("failed to send pause command: %w", err)
+	}
+
+	p.state = StatePaused
+	return nil
+}
+
+// Resume resumes playback by sending a RESUME command to the client.
+func (p *RemotePlayer) Resume() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
+
+	if p.state != StatePaused {
+		return fmt.Errorf("playback is not paused")
+	}
+
+	if err := p.sendLocked(protocol.Resume()); err != nil {
+		return fmt.Errorf("failed to send resume command: %w", err)
+	}
+
+	p.state = StatePlaying
+	return nil
+}
+
 // GetState returns the current playback state.
 func (p *RemotePlayer) GetState() State {
 	p.mu.RLock()
@@ -103,13 +262,13 @@ func (p *RemotePlayer) SetVolume(volume int) error {
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
 
 	p.volume = volume
 
-	// If currently playing, send volume update
-	if p.state == StatePlaying {
-		cmd := fmt.Sprintf("VOLUME;%d", volume)
-		if err := p.sendCommand(cmd); err != nil {
+	// If currently playing or paused, send volume update
+	if p.state != StateStopped && p.caps[protocol.CapVolume] {
+		if err := p.sendLocked(protocol.Volume(volume)); err != nil {
 			return fmt.Errorf("failed to send volume command: %w", err)
 		}
 	}
@@ -124,19 +283,34 @@ func (p *RemotePlayer) GetVolume() int {
 	return p.volume
 }
 
+// Events returns a new subscription to state changes.
+func (p *RemotePlayer) Events() <-chan Event {
+	return p.events.subscribe()
+}
+
+// Unsubscribe ends a subscription from Events, closing its channel.
+func (p *RemotePlayer) Unsubscribe(events <-chan Event) {
+	p.events.unsubscribe(events)
+}
+
+// emitLocked publishes the current state (internal use).
+func (p *RemotePlayer) emitLocked(err error) {
+	p.events.publish(Event{State: p.state, Station: p.currentStation, Volume: p.volume, Err: err})
+}
+
 // Cleanup stops playback and cleans up resources.
 func (p *RemotePlayer) Cleanup() error {
 	return p.Stop()
 }
 
-// sendCommand sends a command to the client wrapper using OSC sequences.
-// Format: \033]8888;COMMAND\007
-func (p *RemotePlayer) sendCommand(cmd string) error {
-	if p.writer == nil {
+// sendLocked sends a message to the client and remembers its sequence
+// number.
+func (p *RemotePlayer) sendLocked(msg protocol.Message) error {
+	if p.encoder == nil {
 		return fmt.Errorf("no writer available")
 	}
 
-	msg := oscPrefix + cmd + oscSuffix
-	_, err := p.writer.Write([]byte(msg))
+	seq, err := p.encoder.Send(msg)
+	p.lastSeq = seq
 	return err
 }
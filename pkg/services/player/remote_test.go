@@ -0,0 +1,301 @@
+package player
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fulgidus/terminal-fm/pkg/protocol"
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// connect completes the handshake as a client with every capability.
+func connect(t *testing.T, p *RemotePlayer) {
+	t.Helper()
+
+	hello := protocol.Hello(protocol.CapPlay, protocol.CapPause, protocol.CapVolume, protocol.CapStatus)
+	if err := p.HandleReply(hello.Payload()); err != nil {
+		t.Fatalf("Failed to handle HELLO: %v", err)
+	}
+}
+
+// sent decodes every message written to out.
+func sent(t *testing.T, out *bytes.Buffer) []protocol.Message {
+	t.Helper()
+
+	var msgs []protocol.Message
+	filter := protocol.NewFilter(&bytes.Buffer{}, func(payload string) {
+		msg, err := protocol.Decode(payload)
+		if err != nil {
+			t.Fatalf("Failed to decode %q: %v", payload, err)
+		}
+		msgs = append(msgs, msg)
+	})
+	_, _ = filter.Write(out.Bytes())
+
+	return msgs
+}
+
+// sentTypes returns the types of the messages written to out.
+func sentTypes(t *testing.T, out *bytes.Buffer) string {
+	t.Helper()
+
+	var types []string
+	for _, msg := range sent(t, out) {
+		types = append(types, msg.Type)
+	}
+	return fmt.Sprint(types)
+}
+
+func TestRemotePlayerPauseResume(t *testing.T) {
+	var out bytes.Buffer
+	p := NewRemotePlayer(&out)
+	connect(t, p)
+
+	// Pausing with nothing playing is an error
+	if err := p.Pause(); err == nil {
+		t.Errorf("Expected error when pausing a stopped player")
+	}
+
+	station := &radiobrowser.Station{
+		StationUUID: "test-uuid",
+		Name:        "Test Station",
+		URLResolved: "http://example.com/stream",
+	}
+	if err := p.Play(station); err != nil {
+		t.Fatalf("Failed to start playback: %v", err)
+	}
+
+	out.Reset()
+	if err := p.Pause(); err != nil {
+		t.Fatalf("Failed to pause: %v", err)
+	}
+	if got := sentTypes(t, &out); got != "[PAUSE]" {
+		t.Errorf("Expected PAUSE command, got %s", got)
+	}
+	if p.GetState() != StatePaused {
+		t.Errorf("Expected state to be Paused, got %v", p.GetState())
+	}
+
+	out.Reset()
+	if err := p.Resume(); err != nil {
+		t.Fatalf("Failed to resume: %v", err)
+	}
+	if got := sentTypes(t, &out); got != "[RESUME]" {
+		t.Errorf("Expected RESUME command, got %s", got)
+	}
+	if p.GetState() != StatePlaying {
+		t.Errorf("Expected state to be Playing, got %v", p.GetState())
+	}
+
+	// Resuming while playing is an error
+	if err := p.Resume(); err == nil {
+		t.Errorf("Expected error when resuming an unpaused player")
+	}
+}
+
+func TestRemotePlayerEvents(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewRemotePlayer(&buf)
+	connect(t, p)
+
+	events := p.Events()
+	other := p.Events()
+
+	station := &radiobrowser.Station{StationUUID: "jazz", Name: "Jazz Radio", URLResolved: "http://jazz"}
+	if err := p.Play(station); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+	if err := p.SetVolume(40); err != nil {
+		t.Fatalf("Failed to set volume: %v", err)
+	}
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Failed to stop: %v", err)
+	}
+
+	want := []Event{
+		{State: StatePlaying, Station: station, Volume: 70},
+		{State: StatePlaying, Station: station, Volume: 40},
+		{State: StateStopped, Volume: 40},
+	}
+
+	for _, sub := range []<-chan Event{events, other} {
+		for i, w := range want {
+			select {
+			case got := <-sub:
+				if got != w {
+					t.Errorf("Event %d: expected %+v, got %+v", i, w, got)
+				}
+			default:
+				t.Fatalf("Expected event %d to be published to every subscriber", i)
+			}
+		}
+	}
+}
+
+func TestRemotePlayerNeedsClient(t *testing.T) {
+	var out bytes.Buffer
+	p := NewRemotePlayer(&out)
+
+	if err := p.Hello(); err != nil {
+		t.Fatalf("Failed to send HELLO: %v", err)
+	}
+	if got := sentTypes(t, &out); got != "[HELLO]" {
+		t.Errorf("Expected HELLO, got %s", got)
+	}
+
+	station := &radiobrowser.Station{Name: "Jazz Radio", URLResolved: "http://jazz"}
+
+	// Without a reply nothing would play, so nothing claims to
+	if err := p.Play(station); !errors.Is(err, ErrNoClient) {
+		t.Errorf("Expected ErrNoClient before the handshake, got %v", err)
+	}
+	if p.GetState() != StateStopped || p.Connected() {
+		t.Errorf("Expected a stopped, unconnected player, got %v", p.GetState())
+	}
+
+	// A client of another version cannot connect
+	if err := p.HandleReply("1;1;HELLO;2;play"); !errors.Is(err, protocol.ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	// A client that can only play gets no pause or volume commands
+	if err := p.HandleReply(protocol.Hello(protocol.CapPlay).Payload()); err != nil {
+		t.Fatalf("Failed to handle HELLO: %v", err)
+	}
+	if err := p.Play(station); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+	if err := p.Pause(); err == nil {
+		t.Errorf("Expected an error pausing a client that cannot pause")
+	}
+	out.Reset()
+	if err := p.SetVolume(20); err != nil {
+		t.Fatalf("Failed to set volume: %v", err)
+	}
+	if got := sentTypes(t, &out); got != "[]" {
+		t.Errorf("Expected no VOLUME for a client without volume control, got %s", got)
+	}
+}
+
+func TestRemotePlayerFollowsClientStatus(t *testing.T) {
+	var out bytes.Buffer
+	p := NewRemotePlayer(&out)
+	connect(t, p)
+
+	station := &radiobrowser.Station{Name: "Jazz Radio", URLResolved: "http://jazz;live"}
+	if err := p.Play(station); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+
+	msgs := sent(t, &out)
+	if len(msgs) != 1 {
+		t.Fatalf("Expected a single PLAY, got %+v", msgs)
+	}
+	url, volume, err := protocol.ParsePlay(msgs[0])
+	if err != nil || url != station.URLResolved || volume != 70 {
+		t.Errorf("Expected PLAY of %s at 70, got %q at %d (%v)", station.URLResolved, url, volume, err)
+	}
+	playSeq := msgs[0].Seq
+
+	events := p.Events()
+	reply := func(status protocol.Status) {
+		t.Helper()
+		if err := p.HandleReply(status.Message().Payload()); err != nil {
+			t.Fatalf("Failed to handle STATUS: %v", err)
+		}
+	}
+
+	// A status from before the PLAY is stale and ignored
+	reply(protocol.Status{Ack: playSeq - 1, State: protocol.StateStopped, Volume: 70})
+	if p.GetState() != StatePlaying {
+		t.Errorf("Expected a stale status to be ignored, got %v", p.GetState())
+	}
+
+	// The client reconnecting shows as buffering
+	reply(protocol.Status{Ack: playSeq, State: protocol.StateBuffering, Volume: 70})
+	if p.GetState() != StateBuffering || p.GetCurrentStation() != station {
+		t.Errorf("Expected buffering on the same station, got %v", p.GetState())
+	}
+
+	// The client giving up stops playback with its reason
+	reply(protocol.Status{Ack: playSeq, State: protocol.StateStopped, Volume: 70, Err: "stream lost"})
+	if p.GetState() != StateStopped || p.GetCurrentStation() != nil {
+		t.Errorf("Expected the player to stop, got %v", p.GetState())
+	}
+
+	var last Event
+	for len(events) > 0 {
+		last = <-events
+	}
+	if last.Err == nil || last.State != StateStopped {
+		t.Errorf("Expected a stopped event carrying the client's error, got %+v", last)
+	}
+
+	// Commands from the client are not accepted
+	if err := p.HandleReply(protocol.Play("http://x", 1).Payload()); err == nil {
+		t.Errorf("Expected an error for a PLAY from the client")
+	}
+}
+
+func TestEventHubDropsOldest(t *testing.T) {
+	var hub eventHub
+	events := hub.subscribe()
+
+	for i := 0; i < eventBufferSize+5; i++ {
+		hub.publish(Event{Volume: i})
+	}
+
+	first := <-events
+	if first.Volume != 5 {
+		t.Errorf("Expected the oldest events to be dropped, first volume is %d", first.Volume)
+	}
+	if len(events) != eventBufferSize-1 {
+		t.Errorf("Expected a full buffer, got %d queued events", len(events))
+	}
+}
+
+func TestEventHubKeepsErrors(t *testing.T) {
+	var hub eventHub
+	events := hub.subscribe()
+	failed := errors.New("stream dropped")
+
+	hub.publish(Event{Volume: -1, Err: failed})
+	for i := 0; i < eventBufferSize+5; i++ {
+		hub.publish(Event{Volume: i})
+	}
+
+	// The error outlives the state changes after it
+	first := <-events
+	if first.Err != failed {
+		t.Errorf("Expected the error to be kept, got %+v", first)
+	}
+	if second := <-events; second.Volume != 6 {
+		t.Errorf("Expected the oldest state changes to be dropped, got volume %d", second.Volume)
+	}
+}
+
+func TestEventHubUnsubscribe(t *testing.T) {
+	var hub eventHub
+	events := hub.subscribe()
+	other := hub.subscribe()
+
+	hub.publish(Event{Volume: 1})
+	hub.unsubscribe(events)
+	hub.publish(Event{Volume: 2})
+
+	// Events already queued are still delivered, then the channel closes
+	if got := <-events; got.Volume != 1 {
+		t.Errorf("Expected the queued event, got %+v", got)
+	}
+	if _, ok := <-events; ok {
+		t.Errorf("Expected the channel to be closed")
+	}
+	if len(hub.subs) != 1 || len(other) != 2 {
+		t.Errorf("Expected the other subscriber to get both events, got %d", len(other))
+	}
+
+	// Unsubscribing twice is harmless
+	hub.unsubscribe(events)
+}
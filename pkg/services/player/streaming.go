@@ -6,43 +6,161 @@ import (
 	"io"
 	"log"
 	"os/exec"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
+	"github.com/fulgidus/terminal-fm/pkg/protocol"
 	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
 )
 
+// streamCodecs are the codecs StreamingPlayer can send, most preferred first.
+var streamCodecs = []string{protocol.CodecOpus, protocol.CodecMP3, protocol.CodecPCM}
+
+// streamCodec describes how ffmpeg produces a codec.
+type streamCodec struct {
+	format protocol.Format
+	// args are ffmpeg's output options.
+	args []string
+	// bitrate is the constant bitrate of the output, in bits per second,
+	// from which frame timestamps are worked out.
+	bitrate int
+	// frameSize is the amount of audio sent in each frame.
+	frameSize int
+}
+
+var streamCodecSettings = map[string]streamCodec{
+	// About 64 kbps; Ogg pages are flushed every 100ms to keep latency low
+	protocol.CodecOpus: {
+		format:    protocol.Format{Codec: protocol.CodecOpus, SampleRate: 48000, Channels: 2},
+		args:      []string{"-c:a", "libopus", "-b:a", "64k", "-vbr", "off", "-page_duration", "100000", "-f", "ogg"},
+		bitrate:   64000,
+		frameSize: 1024,
+	},
+	protocol.CodecMP3: {
+		format:    protocol.Format{Codec: protocol.CodecMP3, SampleRate: 44100, Channels: 2},
+		args:      []string{"-c:a", "libmp3lame", "-b:a", "128k", "-f", "mp3"},
+		bitrate:   128000,
+		frameSize: 1024,
+	},
+	// CD quality: 44.1kHz, 16-bit, stereo, or about 1.4 Mbps
+	protocol.CodecPCM: {
+		format:    protocol.Format{Codec: protocol.CodecPCM, SampleRate: 44100, Channels: 2},
+		args:      []string{"-f", "s16le"},
+		bitrate:   44100 * 2 * 16,
+		frameSize: protocol.MaxFrameData,
+	},
+}
+
 // StreamingPlayer implements Player by streaming audio data through an io.Writer.
 // This allows audio to be sent through SSH sessions or other transports.
+//
+// The audio travels in protocol FRAME messages, encoded by ffmpeg with the
+// codec preferred by both sides. Call Hello when the session starts and pass
+// the payloads the client sends back to HandleReply; nothing plays until the
+// client has answered with the codecs it can decode.
 type StreamingPlayer struct {
 	mu             sync.RWMutex
 	cmd            *exec.Cmd
 	state          State
 	currentStation *radiobrowser.Station
 	volume         int
-	writer         io.Writer
+	encoder        *protocol.Encoder
 	ffmpegPath     string
 	processActive  bool
+	events         eventHub
+
+	// codecs holds the codecs the client can decode; nil until it has
+	// replied to Hello.
+	codecs []string
 }
 
-// NewStreamingPlayer creates a new streaming player that writes PCM audio to the given writer.
+// NewStreamingPlayer creates a new streaming player that writes audio frames to the given writer.
 func NewStreamingPlayer(writer io.Writer, ffmpegPath string) *StreamingPlayer {
 	if ffmpegPath == "" {
 		ffmpegPath = "ffmpeg"
 	}
 
-	return &StreamingPlayer{
+	p := &StreamingPlayer{
 		state:      StateStopped,
 		volume:     70,
-		writer:     writer,
 		ffmpegPath: ffmpegPath,
 	}
+	if writer != nil {
+		p.encoder = protocol.NewEncoder(writer)
+	}
+	return p
+}
+
+// Hello starts the handshake with the client, offering the codecs the
+// player can stream.
+func (p *StreamingPlayer) Hello() error {
+	if p.encoder == nil {
+		return fmt.Errorf("no output writer configured")
+	}
+
+	_, err := p.encoder.Send(protocol.Hello(protocol.CodecCaps(streamCodecs...)...))
+	return err
+}
+
+// Connected reports whether a client has answered the handshake.
+func (p *StreamingPlayer) Connected() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.codecs != nil
+}
+
+// HandleReply processes a message payload sent by the client.
+func (p *StreamingPlayer) HandleReply(payload string) error {
+	msg, err := protocol.Decode(payload)
+	if err != nil {
+		return err
+	}
+
+	switch msg.Type {
+	case protocol.TypeHello:
+		versions, caps, err := protocol.ParseHello(msg)
+		if err != nil {
+			return err
+		}
+		if !containsVersion(versions, protocol.Version) {
+			return fmt.Errorf("%w: client supports %v", protocol.ErrUnsupportedVersion, versions)
+		}
+
+		p.mu.Lock()
+		p.codecs = append([]string{}, protocol.Codecs(caps)...)
+		p.mu.Unlock()
+		return nil
+
+	case protocol.TypeStatus:
+		// The client's own player is idle while we stream
+		return nil
+
+	default:
+		return fmt.Errorf("unexpected %s from client", msg.Type)
+	}
+}
+
+// Format returns the format of the audio being streamed, or the format the
+// next stream will have when nothing is playing. It reports false until a
+// codec has been agreed with the client.
+func (p *StreamingPlayer) Format() (protocol.Format, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	codec, ok := protocol.NegotiateCodec(streamCodecs, p.codecs)
+	if !ok {
+		return protocol.Format{}, false
+	}
+	return streamCodecSettings[codec].format, true
 }
 
 // Play starts playing a radio station by streaming audio to the writer.
 func (p *StreamingPlayer) Play(station *radiobrowser.Station) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
 
 	// Stop any current playback
 	if err := p.stopLocked(); err != nil {
@@ -53,34 +171,47 @@ func (p *StreamingPlayer) Play(station *radiobrowser.Station) error {
 		return fmt.Errorf("invalid station or URL")
 	}
 
-	if p.writer == nil {
+	if p.encoder == nil {
 		return fmt.Errorf("no output writer configured")
 	}
 
-	// Use ffmpeg to transcode stream to PCM audio (CD quality: 44.1kHz, 16-bit, stereo)
+	if p.codecs == nil {
+		return ErrNoClient
+	}
+
+	codec, ok := protocol.NegotiateCodec(streamCodecs, p.codecs)
+	if !ok {
+		return fmt.Errorf("your player cannot decode any of %s", strings.Join(streamCodecs, ", "))
+	}
+	settings := streamCodecSettings[codec]
+
+	// Use ffmpeg to transcode the stream to the negotiated codec
 	// -i: input URL
-	// -f s16le: output format (signed 16-bit little-endian PCM)
-	// -ar 44100: sample rate
-	// -ac 2: stereo (2 channels)
+	// -ar, -ac: sample rate and channels
 	// -af volume: adjust volume
 	// pipe:1: output to stdout
 	volumeFilter := fmt.Sprintf("volume=%.2f", float64(p.volume)/100.0)
 	args := []string{
 		"-i", station.URLResolved,
-		"-f", "s16le",
-		"-ar", "44100",
-		"-ac", "2",
+		"-ar", fmt.Sprint(settings.format.SampleRate),
+		"-ac", fmt.Sprint(settings.format.Channels),
 		"-af", volumeFilter,
-		"-loglevel", "error", // Only show errors
-		"pipe:1",
 	}
+	args = append(args, settings.args...)
+	args = append(args, "-loglevel", "error", "pipe:1") // Only show errors
 
 	p.cmd = exec.Command(p.ffmpegPath, args...)
-	p.cmd.Stdout = p.writer
 	p.cmd.Stderr = nil // Discard stderr to avoid polluting TUI
 
+	stdout, err := p.cmd.StdoutPipe()
+	if err != nil {
+		p.cmd = nil
+		return fmt.Errorf("failed to create ffmpeg pipe: %w", err)
+	}
+
 	// Start the transcoding process
 	if err := p.cmd.Start(); err != nil {
+		p.cmd = nil
 		return fmt.Errorf("failed to start ffmpeg: %w", err)
 	}
 
@@ -88,11 +219,12 @@ func (p *StreamingPlayer) Play(station *radiobrowser.Station) error {
 	p.currentStation = station
 	p.processActive = true
 
-	log.Printf("Started streaming: %s (%s)", station.Name, station.URLResolved)
+	log.Printf("Started streaming: %s (%s, %s)", station.Name, station.URLResolved, settings.format)
 
-	// Monitor process in background
+	// Send the audio and monitor the process in background
 	cmd := p.cmd
 	go func() {
+		p.sendFrames(cmd, stdout, settings)
 		err := cmd.Wait()
 
 		p.mu.Lock()
@@ -106,20 +238,68 @@ func (p *StreamingPlayer) Play(station *radiobrowser.Station) error {
 				log.Printf("Streaming ended normally")
 			}
 
+			unexpected := p.processActive
+
 			p.processActive = false
 			p.state = StateStopped
 			p.currentStation = nil
 			p.cmd = nil
+
+			if unexpected {
+				if err == nil {
+					err = fmt.Errorf("stream ended")
+				}
+				p.emitLocked(fmt.Errorf("ffmpeg stopped: %w", err))
+			}
 		}
 	}()
 
 	return nil
 }
 
+// sendFrames sends ffmpeg's output as frames until it ends. If the client
+// cannot be written to, ffmpeg is killed.
+func (p *StreamingPlayer) sendFrames(cmd *exec.Cmd, stdout io.Reader, settings streamCodec) {
+	buf := make([]byte, settings.frameSize)
+	var seq uint64
+	var sent int64
+
+	for {
+		n, err := io.ReadFull(stdout, buf)
+		if n > 0 && p.isActive(cmd) {
+			frame := protocol.Frame{
+				Format:    settings.format,
+				Seq:       seq,
+				Timestamp: time.Duration(float64(sent*8) / float64(settings.bitrate) * float64(time.Second)),
+				Data:      buf[:n],
+			}
+			if _, sendErr := p.encoder.Send(frame.Message()); sendErr != nil {
+				log.Printf("Failed to send audio: %v", sendErr)
+				_ = cmd.Process.Kill()
+				_, _ = io.Copy(io.Discard, stdout)
+				return
+			}
+			seq++
+			sent += int64(n)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// isActive reports whether cmd is the running ffmpeg process.
+func (p *StreamingPlayer) isActive(cmd *exec.Cmd) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.cmd == cmd && p.processActive
+}
+
 // Stop stops the current playback.
 func (p *StreamingPlayer) Stop() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
 	return p.stopLocked()
 }
 
@@ -135,9 +315,55 @@ func (p *StreamingPlayer) stopLocked() error {
 			// If SIGTERM fails, try SIGKILL
 			_ = p.cmd.Process.Signal(syscall.SIGKILL)
 		}
+		// A paused process only handles SIGTERM once it is continued
+		_ = p.cmd.Process.Signal(syscall.SIGCONT)
 	}
 
 	p.currentStation = nil
+
+	// Let the client close its decoder
+	if wasActive && p.encoder != nil {
+		if _, err := p.encoder.Send(protocol.Stop()); err != nil {
+			return fmt.Errorf("failed to send stop command: %w", err)
+		}
+	}
+	return nil
+}
+
+// Pause suspends the ffmpeg process with SIGSTOP, so no audio is written
+// until Resume is called.
+func (p *StreamingPlayer) Pause() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
+
+	if p.state != StatePlaying || !p.processActive || p.cmd == nil || p.cmd.Process == nil {
+		return fmt.Errorf("nothing is playing")
+	}
+
+	if err := p.cmd.Process.Signal(syscall.SIGSTOP); err != nil {
+		return fmt.Errorf("failed to pause ffmpeg: %w", err)
+	}
+
+	p.state = StatePaused
+	return nil
+}
+
+// Resume continues a paused ffmpeg process with SIGCONT.
+func (p *StreamingPlayer) Resume() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
+
+	if p.state != StatePaused || !p.processActive || p.cmd == nil || p.cmd.Process == nil {
+		return fmt.Errorf("playback is not paused")
+	}
+
+	if err := p.cmd.Process.Signal(syscall.SIGCONT); err != nil {
+		return fmt.Errorf("failed to resume ffmpeg: %w", err)
+	}
+
+	p.state = StatePlaying
 	return nil
 }
 
@@ -164,6 +390,7 @@ func (p *StreamingPlayer) SetVolume(volume int) error {
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
 
 	p.volume = volume
 
@@ -191,10 +418,26 @@ func (p *StreamingPlayer) GetVolume() int {
 	return p.volume
 }
 
+// Events returns a new subscription to state changes.
+func (p *StreamingPlayer) Events() <-chan Event {
+	return p.events.subscribe()
+}
+
+// Unsubscribe ends a subscription from Events, closing its channel.
+func (p *StreamingPlayer) Unsubscribe(events <-chan Event) {
+	p.events.unsubscribe(events)
+}
+
+// emitLocked publishes the current state (internal use).
+func (p *StreamingPlayer) emitLocked(err error) {
+	p.events.publish(Event{State: p.state, Station: p.currentStation, Volume: p.volume, Err: err})
+}
+
 // Cleanup forcefully stops playback and cleans up resources.
 func (p *StreamingPlayer) Cleanup() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.emitLocked(nil)
 
 	if p.cmd != nil && p.cmd.Process != nil {
 		_ = p.cmd.Process.Signal(syscall.SIGKILL)
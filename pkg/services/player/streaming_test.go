@@ -0,0 +1,183 @@
+package player
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/protocol"
+)
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) bytes() *bytes.Buffer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.NewBuffer(append([]byte(nil), b.buf.Bytes()...))
+}
+
+// newFakeFFmpeg writes a script standing in for ffmpeg, which records its
+// arguments in the returned file and writes size bytes of audio.
+func newFakeFFmpeg(t *testing.T, size int) (path, argsFile string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	path = filepath.Join(dir, "ffmpeg")
+	argsFile = filepath.Join(dir, "args")
+
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\nhead -c " + strconv.Itoa(size) + " /dev/zero\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake ffmpeg: %v", err)
+	}
+	return path, argsFile
+}
+
+// handshake answers the player's HELLO as a client decoding codecs.
+func handshake(t *testing.T, p *StreamingPlayer, codecs ...string) {
+	t.Helper()
+
+	hello := protocol.Hello(append([]string{protocol.CapPlay}, protocol.CodecCaps(codecs...)...)...)
+	if err := p.HandleReply(hello.Payload()); err != nil {
+		t.Fatalf("Failed to handle HELLO: %v", err)
+	}
+}
+
+func TestStreamingPlayerNegotiatesCodec(t *testing.T) {
+	ffmpeg, argsFile := newFakeFFmpeg(t, 10)
+
+	tests := []struct {
+		codecs []string
+		want   string
+		args   string
+	}{
+		{[]string{protocol.CodecPCM, protocol.CodecMP3, protocol.CodecOpus}, protocol.CodecOpus, "-ar 48000 -ac 2 -af volume=0.70 -c:a libopus"},
+		{[]string{protocol.CodecPCM, protocol.CodecMP3}, protocol.CodecMP3, "-c:a libmp3lame -b:a 128k -f mp3"},
+		{[]string{protocol.CodecPCM, "flac"}, protocol.CodecPCM, "-ar 44100 -ac 2 -af volume=0.70 -f s16le"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			p := NewStreamingPlayer(&lockedBuffer{}, ffmpeg)
+			defer p.Cleanup()
+
+			if err := p.Play(testStation); !errors.Is(err, ErrNoClient) {
+				t.Errorf("Expected ErrNoClient before the handshake, got %v", err)
+			}
+
+			handshake(t, p, tt.codecs...)
+
+			format, ok := p.Format()
+			if !ok || format.Codec != tt.want {
+				t.Errorf("Expected to agree on %s, got %+v (%v)", tt.want, format, ok)
+			}
+
+			if err := p.Play(testStation); err != nil {
+				t.Fatalf("Failed to play: %v", err)
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			var args []byte
+			for len(args) == 0 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+				args, _ = os.ReadFile(argsFile)
+			}
+			if !strings.Contains(string(args), tt.args) {
+				t.Errorf("Expected ffmpeg arguments to contain %q, got %q", tt.args, args)
+			}
+			_ = os.Remove(argsFile)
+		})
+	}
+
+	// A client that decodes nothing we can send
+	p := NewStreamingPlayer(&lockedBuffer{}, ffmpeg)
+	handshake(t, p, "flac")
+	if err := p.Play(testStation); err == nil || !strings.Contains(err.Error(), "cannot decode") {
+		t.Errorf("Expected an error without a common codec, got %v", err)
+	}
+}
+
+func TestStreamingPlayerSendsFrames(t *testing.T) {
+	// Two and a half PCM frames
+	size := protocol.MaxFrameData*2 + protocol.MaxFrameData/2
+	ffmpeg, _ := newFakeFFmpeg(t, size)
+
+	out := &lockedBuffer{}
+	p := NewStreamingPlayer(out, ffmpeg)
+	defer p.Cleanup()
+
+	if err := p.Hello(); err != nil {
+		t.Fatalf("Failed to send HELLO: %v", err)
+	}
+	handshake(t, p, protocol.CodecPCM)
+	events := p.Events()
+
+	if err := p.Play(testStation); err != nil {
+		t.Fatalf("Failed to play: %v", err)
+	}
+
+	// The stream ends on its own once the fake ffmpeg has written its audio
+	var ended error
+	timeout := time.After(5 * time.Second)
+	for ended == nil {
+		select {
+		case event := <-events:
+			ended = event.Err
+		case <-timeout:
+			t.Fatalf("Expected an event when the stream ended")
+		}
+	}
+
+	msgs := sent(t, out.bytes())
+	if len(msgs) != 4 || msgs[0].Type != protocol.TypeHello {
+		t.Fatalf("Expected HELLO and 3 frames, got %d messages", len(msgs))
+	}
+
+	hello, _, _ := protocol.ParseHello(msgs[0])
+	if len(hello) != 1 || !strings.Contains(msgs[0].Payload(), "codec:opus;codec:mp3;codec:pcm") {
+		t.Errorf("Expected HELLO to offer every codec, got %q", msgs[0].Payload())
+	}
+
+	total := 0
+	for i, msg := range msgs[1:] {
+		frame, err := protocol.ParseFrame(msg)
+		if err != nil {
+			t.Fatalf("Failed to parse frame %d: %v", i, err)
+		}
+		if frame.Seq != uint64(i) {
+			t.Errorf("Expected frame %d to have Seq %d, got %d", i, i, frame.Seq)
+		}
+		if frame.Format != (protocol.Format{Codec: protocol.CodecPCM, SampleRate: 44100, Channels: 2}) {
+			t.Errorf("Expected 44.1kHz stereo PCM, got %s", frame.Format)
+		}
+
+		// 4 bytes per sample at 44.1kHz
+		want := time.Duration(total/4) * time.Second / 44100
+		if diff := frame.Timestamp - want; diff < -time.Millisecond || diff > time.Millisecond {
+			t.Errorf("Expected frame %d at %v, got %v", i, want, frame.Timestamp)
+		}
+		total += len(frame.Data)
+	}
+
+	if total != size {
+		t.Errorf("Expected %d bytes of audio, got %d", size, total)
+	}
+	if p.GetState() != StateStopped {
+		t.Errorf("Expected the player to stop when the stream ended, got %v", p.GetState())
+	}
+}
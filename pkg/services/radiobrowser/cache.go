@@ -0,0 +1,322 @@
+package radiobrowser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache lifetimes, following docs/API.md.
+const (
+	// browseTTL applies to searches without a filter, such as the list
+	// shown at startup.
+	browseTTL = time.Hour
+	// searchTTL applies to other searches.
+	searchTTL = 10 * time.Minute
+	// stationTTL applies to single stations.
+	stationTTL = 5 * time.Minute
+	// catalogTTL applies to the lists of countries, languages and tags,
+	// which hardly change.
+	catalogTTL = 24 * time.Hour
+	// revalidateWindow is how long after expiring an entry is still
+	// returned straight away while it is refreshed in the background.
+	// Older entries are fetched again first, and only returned if that
+	// fails.
+	revalidateWindow = 24 * time.Hour
+	// pruneInterval is how often entries past their revalidate window
+	// are removed.
+	pruneInterval = 24 * time.Hour
+)
+
+// cacheEntry is a cached response, stored as a JSON file.
+type cacheEntry struct {
+	Key       string          `json:"key"`
+	FetchedAt time.Time       `json:"fetched_at"`
+	TTL       time.Duration   `json:"ttl"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// CachedClient implements Client by caching another client's responses in
+// files under a directory. Expired entries are returned while they are
+// refreshed in the background, and when the client fails, e.g. offline,
+// any cached entry is returned. Entries past their revalidate window are
+// pruned, at startup and daily.
+type CachedClient struct {
+	client Client
+	dir    string
+	now    func() time.Time
+
+	mu sync.Mutex
+	// refreshing holds the keys being refreshed in the background.
+	refreshing map[string]bool
+	refreshes  sync.WaitGroup
+	// prunedAt is when old entries were last removed.
+	prunedAt time.Time
+}
+
+// NewCachedClient creates a client caching client's responses in dir,
+// removing the entries there that are too old to be used.
+func NewCachedClient(client Client, dir string) (*CachedClient, error) {
+	return newCachedClient(client, dir, time.Now)
+}
+
+// newCachedClient is NewCachedClient telling the time with now.
+func newCachedClient(client Client, dir string, now func() time.Time) (*CachedClient, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	c := &CachedClient{
+		client:     client,
+		dir:        dir,
+		now:        now,
+		refreshing: make(map[string]bool),
+		prunedAt:   now(),
+	}
+	c.prune()
+	return c, nil
+}
+
+// Search searches for radio stations, using the cache when possible.
+func (c *CachedClient) Search(params SearchParams) ([]Station, error) {
+	return c.SearchContext(context.Background(), params)
+}
+
+// SearchContext searches for radio stations, using the cache when possible.
+func (c *CachedClient) SearchContext(ctx context.Context, params SearchParams) ([]Station, error) {
+	params = normalizeParams(params)
+
+	ttl := searchTTL
+	if params.isBrowse() {
+		ttl = browseTTL
+	}
+
+	return cached(ctx, c, searchKey(params), ttl, func(ctx context.Context) ([]Station, error) {
+		return c.client.SearchContext(ctx, params)
+	})
+}
+
+// GetStationByUUID retrieves a station, using the cache when possible.
+func (c *CachedClient) GetStationByUUID(uuid string) (*Station, error) {
+	return c.GetStationByUUIDContext(context.Background(), uuid)
+}
+
+// GetStationByUUIDContext retrieves a station, using the cache when
+// possible.
+func (c *CachedClient) GetStationByUUIDContext(ctx context.Context, uuid string) (*Station, error) {
+	stations, err := cached(ctx, c, "station/"+uuid, stationTTL, func(ctx context.Context) ([]Station, error) {
+		station, err := c.client.GetStationByUUIDContext(ctx, uuid)
+		if err != nil {
+			return nil, err
+		}
+		return []Station{*station}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(stations) == 0 {
+		return nil, fmt.Errorf("station not found: %s", uuid)
+	}
+	return &stations[0], nil
+}
+
+// ListCountries lists countries, using the cache when possible.
+func (c *CachedClient) ListCountries(ctx context.Context) ([]Country, error) {
+	return cached(ctx, c, "countries", catalogTTL, c.client.ListCountries)
+}
+
+// ListLanguages lists languages, using the cache when possible.
+func (c *CachedClient) ListLanguages(ctx context.Context) ([]Language, error) {
+	return cached(ctx, c, "languages", catalogTTL, c.client.ListLanguages)
+}
+
+// ListTags lists tags, using the cache when possible.
+func (c *CachedClient) ListTags(ctx context.Context) ([]Tag, error) {
+	return cached(ctx, c, "tags", catalogTTL, c.client.ListTags)
+}
+
+// cached returns the response cached under key in c, fetching it when it is
+// missing or too old.
+func cached[T any](ctx context.Context, c *CachedClient, key string, ttl time.Duration, fetch func(context.Context) (T, error)) (T, error) {
+	var data T
+	fetchedAt, ok := c.read(key, &data)
+	age := c.now().Sub(fetchedAt)
+
+	if ok && age < ttl {
+		return data, nil
+	}
+
+	// Stale while revalidate
+	if ok && age < ttl+revalidateWindow {
+		c.refresh(key, ttl, func(ctx context.Context) (any, error) {
+			return fetch(ctx)
+		})
+		return data, nil
+	}
+
+	fresh, err := fetch(ctx)
+	if err != nil {
+		if ok && ctx.Err() == nil {
+			// Offline; old data beats none
+			return data, nil
+		}
+		return fresh, err
+	}
+
+	c.write(key, ttl, fresh)
+	return fresh, nil
+}
+
+// refresh fetches key again in the background, unless that is already
+// happening.
+func (c *CachedClient) refresh(key string, ttl time.Duration, fetch func(context.Context) (any, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.refreshing[key] {
+		return
+	}
+	c.refreshing[key] = true
+	c.refreshes.Add(1)
+
+	go func() {
+		defer c.refreshes.Done()
+
+		// Failures leave the stale entry in place for next time
+		if data, err := fetch(context.Background()); err == nil {
+			c.write(key, ttl, data)
+		}
+
+		c.mu.Lock()
+		delete(c.refreshing, key)
+		c.mu.Unlock()
+	}()
+}
+
+// read decodes the response cached under key into v, returning when it was
+// fetched and whether there was one.
+func (c *CachedClient) read(key string, v any) (time.Time, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return time.Time{}, false
+	}
+	if err := json.Unmarshal(entry.Data, v); err != nil {
+		return time.Time{}, false
+	}
+	return entry.FetchedAt, true
+}
+
+// write caches a response under key, fresh for ttl. The cache is best
+// effort, so failures are ignored.
+func (c *CachedClient) write(key string, ttl time.Duration, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(cacheEntry{Key: key, FetchedAt: c.now(), TTL: ttl, Data: body})
+	if err != nil {
+		return
+	}
+
+	// Write to a temporary file first so readers never see half an entry
+	tmp, err := os.CreateTemp(c.dir, "entry-*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		return
+	}
+	_ = os.Rename(tmp.Name(), c.path(key))
+
+	// Long-running clients prune as they go
+	c.mu.Lock()
+	due := c.now().Sub(c.prunedAt) >= pruneInterval
+	if due {
+		c.prunedAt = c.now()
+	}
+	c.mu.Unlock()
+	if due {
+		c.prune()
+	}
+}
+
+// prune removes the entries past their revalidate window, and those that
+// cannot be read, so that the cache does not grow with every search ever
+// made. Such entries would only have been used while offline.
+func (c *CachedClient) prune() {
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var entry cacheEntry
+		if err := json.Unmarshal(data, &entry); err == nil && c.now().Sub(entry.FetchedAt) <= entry.TTL+revalidateWindow {
+			continue
+		}
+		_ = os.Remove(file)
+	}
+}
+
+// path returns the file caching key.
+func (c *CachedClient) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// normalizeParams returns params in a canonical form, so that equivalent
+// searches share a cache entry.
+func normalizeParams(params SearchParams) SearchParams {
+	params.Name = strings.ToLower(strings.TrimSpace(params.Name))
+	params.Country = strings.ToLower(strings.TrimSpace(params.Country))
+	params.CountryCode = strings.ToUpper(strings.TrimSpace(params.CountryCode))
+	params.Language = strings.ToLower(strings.TrimSpace(params.Language))
+	params.Tag = strings.ToLower(strings.TrimSpace(params.Tag))
+	params.Codec = strings.ToLower(strings.TrimSpace(params.Codec))
+	params.Order = strings.ToLower(strings.TrimSpace(params.Order))
+
+	// The API's defaults
+	if params.Limit <= 0 {
+		params.Limit = 50
+	}
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+
+	return params
+}
+
+// isBrowse reports whether normalized params list stations without
+// filtering them.
+func (p SearchParams) isBrowse() bool {
+	unfiltered := SearchParams{Limit: p.Limit, Offset: p.Offset, Order: p.Order, Reverse: p.Reverse}
+	return p == unfiltered
+}
+
+// searchKey returns the cache key of normalized params.
+func searchKey(params SearchParams) string {
+	data, _ := json.Marshal(params)
+	return "search/" + string(data)
+}
@@ -0,0 +1,286 @@
+package radiobrowser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingClient is a Client serving one station named after the current
+// version, counting its calls.
+type countingClient struct {
+	mu      sync.Mutex
+	calls   int
+	version string
+	err     error
+}
+
+func (c *countingClient) set(version string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version, c.err = version, err
+}
+
+func (c *countingClient) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+func (c *countingClient) Search(params SearchParams) ([]Station, error) {
+	return c.SearchContext(context.Background(), params)
+}
+
+func (c *countingClient) SearchContext(ctx context.Context, params SearchParams) ([]Station, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return []Station{{StationUUID: "s", Name: c.version}}, nil
+}
+
+func (c *countingClient) GetStationByUUID(uuid string) (*Station, error) {
+	return c.GetStationByUUIDContext(context.Background(), uuid)
+}
+
+func (c *countingClient) GetStationByUUIDContext(ctx context.Context, uuid string) (*Station, error) {
+	stations, err := c.SearchContext(ctx, SearchParams{})
+	if err != nil {
+		return nil, err
+	}
+	return &Station{StationUUID: uuid, Name: stations[0].Name}, nil
+}
+
+func (c *countingClient) ListCountries(ctx context.Context) ([]Country, error) {
+	stations, err := c.SearchContext(ctx, SearchParams{})
+	if err != nil {
+		return nil, err
+	}
+	return []Country{{Name: stations[0].Name}}, nil
+}
+
+func (c *countingClient) ListLanguages(ctx context.Context) ([]Language, error) {
+	stations, err := c.SearchContext(ctx, SearchParams{})
+	if err != nil {
+		return nil, err
+	}
+	return []Language{{Name: stations[0].Name}}, nil
+}
+
+func (c *countingClient) ListTags(ctx context.Context) ([]Tag, error) {
+	stations, err := c.SearchContext(ctx, SearchParams{})
+	if err != nil {
+		return nil, err
+	}
+	return []Tag{{Name: stations[0].Name}}, nil
+}
+
+// newTestCache creates a cache in a temporary directory using clock.
+func newTestCache(t *testing.T, client Client, dir string, clock *testClock) *CachedClient {
+	t.Helper()
+
+	cache, err := newCachedClient(client, dir, clock.Now)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	return cache
+}
+
+// searchName returns the name of the station a search returns.
+func searchName(t *testing.T, cache *CachedClient, params SearchParams) string {
+	t.Helper()
+
+	stations, err := cache.Search(params)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	return stations[0].Name
+}
+
+func TestCachedClientServesFreshEntries(t *testing.T) {
+	client := &countingClient{version: "v1"}
+	clock := &testClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	cache := newTestCache(t, client, t.TempDir(), clock)
+
+	searchName(t, cache, SearchParams{Name: "Jazz "})
+	client.set("v2", nil)
+
+	// Equivalent searches share an entry
+	if got := searchName(t, cache, SearchParams{Name: "jazz", Limit: 50}); got != "v1" {
+		t.Errorf("Expected the cached result, got %s", got)
+	}
+	if client.count() != 1 {
+		t.Errorf("Expected 1 request, got %d", client.count())
+	}
+
+	// Different searches do not
+	if got := searchName(t, cache, SearchParams{Name: "jazz", Offset: 50}); got != "v2" {
+		t.Errorf("Expected a new request for another page, got %s", got)
+	}
+
+	// The browse list lives longer than a search
+	searchName(t, cache, SearchParams{Order: "votes"})
+	client.set("v3", nil)
+	clock.Advance(searchTTL)
+
+	if got := searchName(t, cache, SearchParams{Order: "votes"}); got != "v2" {
+		t.Errorf("Expected the browse list to still be fresh, got %s", got)
+	}
+	if got := searchName(t, cache, SearchParams{Name: "jazz"}); got != "v1" {
+		t.Errorf("Expected the stale search to be returned while revalidating, got %s", got)
+	}
+	cache.refreshes.Wait()
+	if got := searchName(t, cache, SearchParams{Name: "jazz"}); got != "v3" {
+		t.Errorf("Expected the revalidated search, got %s", got)
+	}
+}
+
+func TestCachedClientFallsBackWhenOffline(t *testing.T) {
+	dir := t.TempDir()
+	client := &countingClient{version: "v1"}
+	clock := &testClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	browse := SearchParams{Limit: 50, Order: "votes"}
+
+	searchName(t, newTestCache(t, client, dir, clock), browse)
+
+	// A later run starts offline, long after the entry expired
+	offline := errors.New("network is unreachable")
+	client.set("v2", offline)
+	clock.Advance(browseTTL + revalidateWindow)
+
+	cache := newTestCache(t, client, dir, clock)
+	if got := searchName(t, cache, browse); got != "v1" {
+		t.Errorf("Expected the old browse list when offline, got %s", got)
+	}
+	if client.count() != 2 {
+		t.Errorf("Expected the old entry to be fetched again first, got %d requests", client.count())
+	}
+
+	// Nothing cached, nothing to fall back on
+	if _, err := cache.Search(SearchParams{Name: "never searched"}); !errors.Is(err, offline) {
+		t.Errorf("Expected the client's error, got %v", err)
+	}
+
+	// A failed background refresh keeps the stale entry
+	client.set("v2", nil)
+	searchName(t, cache, browse)
+	client.set("v3", offline)
+	clock.Advance(browseTTL)
+	if got := searchName(t, cache, browse); got != "v2" {
+		t.Errorf("Expected the stale entry, got %s", got)
+	}
+	cache.refreshes.Wait()
+	if got := searchName(t, cache, browse); got != "v2" {
+		t.Errorf("Expected the stale entry to survive a failed refresh, got %s", got)
+	}
+}
+
+func TestCachedClientGetStationByUUID(t *testing.T) {
+	client := &countingClient{version: "v1"}
+	clock := &testClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	cache := newTestCache(t, client, t.TempDir(), clock)
+
+	for i := 0; i < 2; i++ {
+		station, err := cache.GetStationByUUID("jazz")
+		if err != nil || station.StationUUID != "jazz" || station.Name != "v1" {
+			t.Fatalf("Expected the jazz station, got %+v (%v)", station, err)
+		}
+	}
+	if client.count() != 1 {
+		t.Errorf("Expected 1 request, got %d", client.count())
+	}
+
+	// A cancelled request is not mistaken for being offline
+	client.set("v2", context.Canceled)
+	clock.Advance(stationTTL + revalidateWindow)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cache.GetStationByUUIDContext(ctx, "jazz"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCachedClientCatalogs(t *testing.T) {
+	client := &countingClient{version: "v1"}
+	clock := &testClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	cache := newTestCache(t, client, t.TempDir(), clock)
+	ctx := context.Background()
+
+	if _, err := cache.ListCountries(ctx); err != nil {
+		t.Fatalf("Failed to list countries: %v", err)
+	}
+	if _, err := cache.ListTags(ctx); err != nil {
+		t.Fatalf("Failed to list tags: %v", err)
+	}
+	client.set("v2", nil)
+
+	// Catalogs outlive the browse list
+	clock.Advance(browseTTL)
+	countries, err := cache.ListCountries(ctx)
+	if err != nil || len(countries) != 1 || countries[0].Name != "v1" {
+		t.Errorf("Expected the cached countries, got %+v (%v)", countries, err)
+	}
+	tags, err := cache.ListTags(ctx)
+	if err != nil || len(tags) != 1 || tags[0].Name != "v1" {
+		t.Errorf("Expected the cached tags, got %+v (%v)", tags, err)
+	}
+	if client.count() != 2 {
+		t.Errorf("Expected 2 requests, got %d", client.count())
+	}
+
+	// Each catalog has its own entry
+	languages, err := cache.ListLanguages(ctx)
+	if err != nil || len(languages) != 1 || languages[0].Name != "v2" {
+		t.Errorf("Expected fresh languages, got %+v (%v)", languages, err)
+	}
+}
+
+func TestCachedClientPrunesOldEntries(t *testing.T) {
+	dir := t.TempDir()
+	client := &countingClient{version: "v1"}
+	clock := &testClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	cache := newTestCache(t, client, dir, clock)
+	ctx := context.Background()
+
+	entries := func() int {
+		t.Helper()
+		files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			t.Fatalf("Failed to list the cache: %v", err)
+		}
+		return len(files)
+	}
+
+	searchName(t, cache, SearchParams{Name: "jazz"})
+	if _, err := cache.ListCountries(ctx); err != nil {
+		t.Fatalf("Failed to list countries: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644); err != nil {
+		t.Fatalf("Failed to write entry: %v", err)
+	}
+
+	// A later run removes the search past its revalidate window, and what
+	// cannot be read, but keeps the longer lived catalog
+	clock.Advance(searchTTL + revalidateWindow + time.Second)
+	newTestCache(t, client, dir, clock)
+	if got := entries(); got != 1 {
+		t.Errorf("Expected only the countries to be kept, got %d entries", got)
+	}
+	if _, ok := cache.read("countries", &[]Country{}); !ok {
+		t.Errorf("Expected the countries to be kept")
+	}
+
+	// A long-running client prunes as it writes, once a day
+	clock.Advance(catalogTTL)
+	searchName(t, cache, SearchParams{Name: "rock"})
+	if _, ok := cache.read("countries", &[]Country{}); ok || entries() != 1 {
+		t.Errorf("Expected only the new search to be kept, got %d entries", entries())
+	}
+}
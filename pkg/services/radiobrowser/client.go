@@ -1,33 +1,237 @@
 package radiobrowser
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
-// Client interface for Radio Browser API
+// Client interface for Radio Browser API.
+// The Context variants give up once ctx is done, returning an error that
+// wraps the context's.
 type Client interface {
 	Search(params SearchParams) ([]Station, error)
+	SearchContext(ctx context.Context, params SearchParams) ([]Station, error)
 	GetStationByUUID(uuid string) (*Station, error)
+	GetStationByUUIDContext(ctx context.Context, uuid string) (*Station, error)
+
+	// ListCountries, ListLanguages and ListTags list what stations can be
+	// filtered by, most stations first.
+	ListCountries(ctx context.Context) ([]Country, error)
+	ListLanguages(ctx context.Context) ([]Language, error)
+	ListTags(ctx context.Context) ([]Tag, error)
 }
 
 // MockClient provides mock data for development
-type MockClient struct{}
+type MockClient struct {
+	stations []Station
+}
 
 // NewMockClient creates a new mock client with sample stations
 func NewMockClient() *MockClient {
-	return &MockClient{}
+	return &MockClient{stations: mockStations()}
+}
+
+// NewGeneratedMockClient creates a mock client serving the sample stations
+// followed by n generated ones, the same every time, for exercising long
+// lists.
+func NewGeneratedMockClient(n int) *MockClient {
+	return &MockClient{stations: append(mockStations(), generateStations(n)...)}
 }
 
-// Search returns mock stations for testing
+// Search returns the mock stations matching params
 func (c *MockClient) Search(params SearchParams) ([]Station, error) {
-	// Return mock stations for development
-	mockStations := []Station{
+	return c.SearchContext(context.Background(), params)
+}
+
+// SearchContext returns the mock stations matching params, unless ctx is
+// done.
+func (c *MockClient) SearchContext(ctx context.Context, params SearchParams) ([]Station, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	filtered := []Station{}
+	for _, station := range c.stations {
+		if matches(station, params) {
+			filtered = append(filtered, station)
+		}
+	}
+
+	SortStations(filtered, params.Order, params.Reverse)
+
+	// Page through the results as the API does
+	limit := params.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	if params.Offset >= len(filtered) {
+		return []Station{}, nil
+	}
+	if params.Offset > 0 {
+		filtered = filtered[params.Offset:]
+	}
+	if len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+
+	return filtered, nil
+}
+
+// matches reports whether station meets every filter in params, much as
+// the API would.
+func matches(station Station, params SearchParams) bool {
+	contains := func(s, substr string) bool {
+		return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+	}
+
+	switch {
+	case params.Name != "" && !contains(station.Name, params.Name):
+		return false
+	case params.Country != "" && !contains(station.Country, params.Country):
+		return false
+	case params.CountryCode != "" && !strings.EqualFold(station.CountryCode, params.CountryCode):
+		return false
+	case params.Language != "" && !contains(station.Language, params.Language):
+		return false
+	case params.Tag != "" && !hasTag(station, params.Tag):
+		return false
+	case params.Codec != "" && !strings.EqualFold(station.Codec, params.Codec):
+		return false
+	case params.BitrateMin > 0 && station.Bitrate < params.BitrateMin:
+		return false
+	case params.BitrateMax > 0 && station.Bitrate > params.BitrateMax:
+		return false
+	case params.HTTPSOnly && !strings.HasPrefix(station.URLResolved, "https://"):
+		return false
+	}
+	return true
+}
+
+// hasTag reports whether station is tagged with tag.
+func hasTag(station Station, tag string) bool {
+	for _, t := range strings.Split(station.Tags, ",") {
+		if strings.EqualFold(strings.TrimSpace(t), tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetStationByUUID returns a mock station by UUID
+func (c *MockClient) GetStationByUUID(uuid string) (*Station, error) {
+	return c.GetStationByUUIDContext(context.Background(), uuid)
+}
+
+// GetStationByUUIDContext returns a mock station by UUID, unless ctx is done.
+func (c *MockClient) GetStationByUUIDContext(ctx context.Context, uuid string) (*Station, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, station := range c.stations {
+		if station.StationUUID == uuid {
+			return &station, nil
+		}
+	}
+	return nil, fmt.Errorf("station not found: %s", uuid)
+}
+
+// ListCountries returns the countries of the mock stations.
+func (c *MockClient) ListCountries(ctx context.Context) ([]Country, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	codes := make(map[string]string)
+	counts := countStations(c.stations, func(station Station) []string {
+		codes[station.Country] = station.CountryCode
+		return []string{station.Country}
+	})
+
+	countries := make([]Country, len(counts))
+	for i, count := range counts {
+		countries[i] = Country{Name: count.name, Code: codes[count.name], StationCount: count.stations}
+	}
+	return countries, nil
+}
+
+// ListLanguages returns the languages of the mock stations.
+func (c *MockClient) ListLanguages(ctx context.Context) ([]Language, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	codes := make(map[string]string)
+	counts := countStations(c.stations, func(station Station) []string {
+		codes[station.Language] = station.LanguageCodes
+		return []string{station.Language}
+	})
+
+	languages := make([]Language, len(counts))
+	for i, count := range counts {
+		languages[i] = Language{Name: count.name, Code: codes[count.name], StationCount: count.stations}
+	}
+	return languages, nil
+}
+
+// ListTags returns the tags of the mock stations.
+func (c *MockClient) ListTags(ctx context.Context) ([]Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	counts := countStations(c.stations, func(station Station) []string {
+		return strings.Split(station.Tags, ",")
+	})
+
+	tags := make([]Tag, len(counts))
+	for i, count := range counts {
+		tags[i] = Tag{Name: count.name, StationCount: count.stations}
+	}
+	return tags, nil
+}
+
+// stationCount is how many mock stations have a facet value.
+type stationCount struct {
+	name     string
+	stations int
+}
+
+// countStations counts stations per value returned by values, most
+// stations first.
+func countStations(stations []Station, values func(Station) []string) []stationCount {
+	var counts []stationCount
+	index := make(map[string]int)
+	for _, station := range stations {
+		for _, value := range values(station) {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			i, ok := index[value]
+			if !ok {
+				i = len(counts)
+				index[value] = i
+				counts = append(counts, stationCount{name: value})
+			}
+			counts[i].stations++
+		}
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool { return counts[i].stations > counts[j].stations })
+	return counts
+}
+
+// mockStations returns the stations served by MockClient.
+func mockStations() []Station {
+	return []Station{
 		{
 			StationUUID:   "960b51d-0601-11e8-ae97-52543be04c81",
 			Name:          "Jazz Radio",
@@ -114,60 +318,151 @@ func (c *MockClient) Search(params SearchParams) ([]Station, error) {
 			ClickCount:    3456,
 		},
 	}
-
-	// Simple filtering by name if provided
-	if params.Name != "" {
-		// Case-insensitive search would go here - for now return all
-		filtered := append([]Station{}, mockStations...)
-		return filtered, nil
-	}
-
-	return mockStations, nil
 }
 
-// GetStationByUUID returns a mock station by UUID
-func (c *MockClient) GetStationByUUID(uuid string) (*Station, error) {
-	stations, _ := c.Search(SearchParams{})
-	for _, station := range stations {
-		if station.StationUUID == uuid {
-			return &station, nil
+// generateStations returns n made-up stations, numbered in order of
+// decreasing votes. The same n always gives the same stations.
+func generateStations(n int) []Station {
+	genres := []string{"jazz", "rock", "pop", "electronic", "classical", "news", "talk", "ambient"}
+	countries := []struct{ name, code, language, languageCode string }{
+		{"Italy", "IT", "italian", "it"},
+		{"Germany", "DE", "german", "de"},
+		{"France", "FR", "french", "fr"},
+		{"United States", "US", "english", "en"},
+		{"Japan", "JP", "japanese", "ja"},
+		{"Brazil", "BR", "portuguese", "pt"},
+	}
+	codecs := []string{"MP3", "AAC", "OGG"}
+	bitrates := []int{64, 96, 128, 192, 256, 320}
+
+	stations := make([]Station, n)
+	for i := range stations {
+		genre := genres[i%len(genres)]
+		country := countries[i%len(countries)]
+		url := fmt.Sprintf("https://stream.example.com/mock-%05d", i)
+
+		stations[i] = Station{
+			StationUUID:   fmt.Sprintf("00000000-0000-4000-8000-%012d", i),
+			Name:          fmt.Sprintf("%s %s %d", country.code, strings.ToUpper(genre[:1])+genre[1:], i),
+			URL:           url,
+			URLResolved:   url,
+			Tags:          genre + ",mock",
+			Country:       country.name,
+			CountryCode:   country.code,
+			Language:      country.language,
+			LanguageCodes: country.languageCode,
+			Votes:         n - i,
+			Codec:         codecs[i%len(codecs)],
+			Bitrate:       bitrates[i%len(bitrates)],
+			LastCheckOK:   1,
+			ClickCount:    (i * 7919) % 1000,
 		}
 	}
-	return nil, fmt.Errorf("station not found: %s", uuid)
+	return stations
 }
 
+// Mirror defaults.
+const (
+	// defaultServer is used until the server list has been resolved.
+	defaultServer = "https://de1.api.radio-browser.info"
+	// serverListURL lists every Radio Browser API server.
+	serverListURL = "https://all.api.radio-browser.info/json/servers"
+	// mirrorCooldown is how long a failing server is skipped.
+	mirrorCooldown = 5 * time.Minute
+	// resolveInterval is how often the server list is refreshed.
+	resolveInterval = time.Hour
+	// resolveRetry is how soon a failed refresh is tried again.
+	resolveRetry = time.Minute
+)
+
 // APIClient implements the Client interface using the real Radio Browser API.
+// Requests go to one of the API's mirrors; when a mirror fails with a
+// network error or a 5xx response, it is skipped for a cooldown and the
+// request is retried on the next one.
 type APIClient struct {
-	baseURL    string
+	mirrors    *mirrorList
 	httpClient *http.Client
 	userAgent  string
+	logger     *slog.Logger
+
+	// serverListURL is where the mirrors are resolved from, every
+	// resolveInterval; empty to keep a fixed list.
+	serverListURL   string
+	resolveInterval time.Duration
+	// resolves tracks the refreshes running in the background.
+	resolves sync.WaitGroup
 }
 
 // NewAPIClient creates a new Radio Browser API client.
-// It automatically resolves the best API server to use.
-func NewAPIClient() (*APIClient, error) {
-	client := &APIClient{
-		baseURL: "https://de1.api.radio-browser.info", // Default server
+// It automatically resolves the API servers to use, and logs mirror
+// failures to logger; a nil logger discards them.
+func NewAPIClient(logger *slog.Logger) (*APIClient, error) {
+	client := newAPIClient(logger, serverListURL, defaultServer)
+
+	// Try to resolve the servers
+	if client.mirrors.resolveDue() {
+		if err := client.resolve(context.Background()); err != nil {
+			// If resolution fails, continue with default server
+			client.logger.Warn("Could not resolve Radio Browser servers, using default",
+				"server", defaultServer, "error", err)
+		}
+	}
+
+	return client, nil
+}
+
+// NewAPIClientForServer creates a Radio Browser API client that always uses
+// the server at baseURL, without resolving the others.
+func NewAPIClientForServer(baseURL string) *APIClient {
+	return newAPIClient(nil, "", baseURL)
+}
+
+// newAPIClient creates a client for the given servers.
+func newAPIClient(logger *slog.Logger, serverListURL string, baseURLs ...string) *APIClient {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	for i, baseURL := range baseURLs {
+		baseURLs[i] = strings.TrimSuffix(baseURL, "/")
+	}
+
+	return &APIClient{
+		mirrors: newMirrorList(baseURLs, mirrorCooldown, time.Now),
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		userAgent: "Terminal.FM/1.0",
+		userAgent:       "Terminal.FM/1.0",
+		logger:          logger,
+		serverListURL:   serverListURL,
+		resolveInterval: resolveInterval,
 	}
+}
 
-	// Try to resolve the best server
-	if err := client.resolveBestServer(); err != nil {
-		// If resolution fails, continue with default server
-		fmt.Printf("Warning: Could not resolve best server, using default: %v\n", err)
+// resolve resolves the servers, as claimed with resolveDue, and schedules
+// the next resolution: after resolveInterval, or after resolveRetry if
+// this one failed.
+func (c *APIClient) resolve(ctx context.Context) error {
+	err := c.resolveServers(ctx)
+	if err != nil {
+		c.mirrors.resolveDone(resolveRetry)
+	} else {
+		c.mirrors.resolveDone(c.resolveInterval)
 	}
-
-	return client, nil
+	return err
 }
 
-// resolveBestServer finds the best Radio Browser API server using DNS.
-func (c *APIClient) resolveBestServer() error {
+// resolveServers replaces the mirrors with the servers Radio Browser lists.
+func (c *APIClient) resolveServers(ctx context.Context) error {
 	// Radio Browser uses DNS to distribute load across servers
-	// Query all.api.radio-browser.info to get a random server
-	resp, err := c.httpClient.Get("https://all.api.radio-browser.info/json/servers")
+	// Query all.api.radio-browser.info to list them
+	req, err := http.NewRequestWithContext(ctx, "GET", c.serverListURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to resolve servers: %w", err)
 	}
@@ -185,21 +480,126 @@ func (c *APIClient) resolveBestServer() error {
 		return fmt.Errorf("failed to decode servers: %w", err)
 	}
 
-	if len(servers) > 0 {
-		// Use the first server (they're already randomized by DNS)
-		c.baseURL = "https://" + servers[0].Name
+	// The list is already randomized by DNS
+	baseURLs := make([]string, 0, len(servers))
+	for _, server := range servers {
+		if server.Name != "" {
+			baseURLs = append(baseURLs, "https://"+server.Name)
+		}
+	}
+	if len(baseURLs) == 0 {
+		return fmt.Errorf("no servers listed")
 	}
 
+	c.mirrors.set(baseURLs)
+	c.logger.Info("Resolved Radio Browser servers", "count", len(baseURLs))
 	return nil
 }
 
+// get sends a GET request for endpoint to the first mirror that answers
+// without a network error or a 5xx status.
+func (c *APIClient) get(ctx context.Context, endpoint string, query url.Values) (*http.Response, error) {
+	// The list is refreshed in the background, so the request neither
+	// waits for it nor cancels it
+	if c.serverListURL != "" && c.mirrors.resolveDue() {
+		c.resolves.Add(1)
+		go func() {
+			defer c.resolves.Done()
+			if err := c.resolve(context.Background()); err != nil {
+				c.logger.Warn("Could not refresh Radio Browser servers", "error", err)
+			}
+		}()
+	}
+
+	var lastErr error
+	for _, m := range c.mirrors.candidates() {
+		// Construct full URL
+		fullURL := m.baseURL + endpoint
+		if len(query) > 0 {
+			fullURL += "?" + query.Encode()
+		}
+
+		// Create request
+		req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("User-Agent", c.userAgent)
+
+		// Execute request
+		resp, err := c.httpClient.Do(req)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			// Given up on, not the mirror's fault
+			return nil, fmt.Errorf("failed to execute request: %w", err)
+		case err != nil:
+			lastErr = fmt.Errorf("failed to execute request: %w", err)
+		case resp.StatusCode >= 500:
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			resp.Body.Close()
+			lastErr = fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		default:
+			c.mirrors.markUp(m)
+			return resp, nil
+		}
+
+		c.mirrors.markDown(m)
+		c.logger.Warn("Radio Browser mirror failed, trying the next one",
+			"mirror", m.baseURL, "error", lastErr, "cooldown", c.mirrors.cooldown)
+	}
+
+	return nil, lastErr
+}
+
 // Search searches for radio stations using the provided parameters.
 func (c *APIClient) Search(params SearchParams) ([]Station, error) {
+	return c.SearchContext(context.Background(), params)
+}
+
+// SearchContext searches for radio stations, giving up when ctx is done.
+func (c *APIClient) SearchContext(ctx context.Context, params SearchParams) ([]Station, error) {
 	// Build the search endpoint based on parameters
 	endpoint := "/json/stations/search"
+	query := searchQuery(params)
+
+	// Execute request
+	resp, err := c.get(ctx, endpoint, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-	// Build query parameters
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	// Decode response
+	var stations []Station
+	if err := json.NewDecoder(resp.Body).Decode(&stations); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Filter out stations with empty URLs or that failed last check
+	filtered := make([]Station, 0, len(stations))
+	for _, station := range stations {
+		if station.URLResolved != "" && station.LastCheckOK == 1 {
+			// Clean up tags
+			station.Tags = strings.TrimSpace(station.Tags)
+			filtered = append(filtered, station)
+		}
+	}
+
+	return filtered, nil
+}
+
+// searchQuery returns the query string of a station search for params.
+func searchQuery(params SearchParams) url.Values {
 	query := url.Values{}
+	// Broken stations are left out by the API rather than after, so that
+	// pages come back full and offsets count only the stations returned
+	query.Set("hidebroken", "true")
 
 	if params.Name != "" {
 		query.Set("name", params.Name)
@@ -207,15 +607,32 @@ func (c *APIClient) Search(params SearchParams) ([]Station, error) {
 	if params.Country != "" {
 		query.Set("country", params.Country)
 	}
+	if params.CountryCode != "" {
+		query.Set("countrycode", params.CountryCode)
+	}
 	if params.Language != "" {
 		query.Set("language", params.Language)
 	}
 	if params.Tag != "" {
 		query.Set("tag", params.Tag)
 	}
+	if params.Codec != "" {
+		query.Set("codec", params.Codec)
+	}
+	if params.BitrateMin > 0 {
+		query.Set("bitrateMin", fmt.Sprintf("%d", params.BitrateMin))
+	}
+	if params.BitrateMax > 0 {
+		query.Set("bitrateMax", fmt.Sprintf("%d", params.BitrateMax))
+	}
+	if params.HTTPSOnly {
+		query.Set("is_https", "true")
+	}
 	if params.Order != "" {
 		query.Set("order", params.Order)
-		query.Set("reverse", "true") // Most voted/clicked first
+	}
+	if params.Reverse {
+		query.Set("reverse", "true")
 	}
 	if params.Limit > 0 {
 		query.Set("limit", fmt.Sprintf("%d", params.Limit))
@@ -226,81 +643,97 @@ func (c *APIClient) Search(params SearchParams) ([]Station, error) {
 		query.Set("offset", fmt.Sprintf("%d", params.Offset))
 	}
 
-	// Construct full URL
-	fullURL := c.baseURL + endpoint
-	if len(query) > 0 {
-		fullURL += "?" + query.Encode()
-	}
+	return query
+}
 
-	// Create request
-	req, err := http.NewRequest("GET", fullURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+// GetStationByUUID retrieves a specific station by its UUID.
+func (c *APIClient) GetStationByUUID(uuid string) (*Station, error) {
+	return c.GetStationByUUIDContext(context.Background(), uuid)
+}
 
-	req.Header.Set("User-Agent", c.userAgent)
+// GetStationByUUIDContext retrieves a station by its UUID, giving up when
+// ctx is done.
+func (c *APIClient) GetStationByUUIDContext(ctx context.Context, uuid string) (*Station, error) {
+	endpoint := fmt.Sprintf("/json/stations/byuuid/%s", uuid)
 
-	// Execute request
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.get(ctx, endpoint, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
 	}
 
-	// Decode response
 	var stations []Station
 	if err := json.NewDecoder(resp.Body).Decode(&stations); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Filter out stations with empty URLs or that failed last check
-	filtered := make([]Station, 0, len(stations))
-	for _, station := range stations {
-		if station.URLResolved != "" && station.LastCheckOK == 1 {
-			// Clean up tags
-			station.Tags = strings.TrimSpace(station.Tags)
-			filtered = append(filtered, station)
-		}
+	if len(stations) == 0 {
+		return nil, fmt.Errorf("station not found: %s", uuid)
 	}
 
-	return filtered, nil
+	return &stations[0], nil
 }
 
-// GetStationByUUID retrieves a specific station by its UUID.
-func (c *APIClient) GetStationByUUID(uuid string) (*Station, error) {
-	endpoint := fmt.Sprintf("/json/stations/byuuid/%s", uuid)
-	fullURL := c.baseURL + endpoint
+// tagLimit is how many tags ListTags returns; there are tens of thousands,
+// most used by a single station.
+const tagLimit = 500
 
-	req, err := http.NewRequest("GET", fullURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// ListCountries lists the countries with stations, most stations first.
+func (c *APIClient) ListCountries(ctx context.Context) ([]Country, error) {
+	var countries []Country
+	if err := c.list(ctx, "/json/countries", 0, &countries); err != nil {
+		return nil, err
 	}
+	return countries, nil
+}
 
-	req.Header.Set("User-Agent", c.userAgent)
+// ListLanguages lists the languages stations broadcast in, most stations
+// first.
+func (c *APIClient) ListLanguages(ctx context.Context) ([]Language, error) {
+	var languages []Language
+	if err := c.list(ctx, "/json/languages", 0, &languages); err != nil {
+		return nil, err
+	}
+	return languages, nil
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+// ListTags lists the most used station tags, most stations first.
+func (c *APIClient) ListTags(ctx context.Context) ([]Tag, error) {
+	var tags []Tag
+	if err := c.list(ctx, "/json/tags", tagLimit, &tags); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return tags, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+// list decodes a catalog endpoint ordered by station count into v, returning
+// at most limit entries unless limit is 0.
+func (c *APIClient) list(ctx context.Context, endpoint string, limit int, v any) error {
+	query := url.Values{}
+	query.Set("order", "stationcount")
+	query.Set("reverse", "true")
+	query.Set("hidebroken", "true")
+	if limit > 0 {
+		query.Set("limit", fmt.Sprintf("%d", limit))
 	}
 
-	var stations []Station
-	if err := json.NewDecoder(resp.Body).Decode(&stations); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	resp, err := c.get(ctx, endpoint, query)
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
 
-	if len(stations) == 0 {
-		return nil, fmt.Errorf("station not found: %s", uuid)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	return &stations[0], nil
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,393 @@
+package radiobrowser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const stationsJSON = `[
+	{"stationuuid": "jazz", "name": "Jazz Radio", "url_resolved": "http://jazz/stream", "lastcheckok": 1, "tags": " jazz,smooth "},
+	{"stationuuid": "dead", "name": "Dead Radio", "url_resolved": "http://dead/stream", "lastcheckok": 0},
+	{"stationuuid": "nourl", "name": "No URL", "lastcheckok": 1}
+]`
+
+// newSlowServer starts a server that answers with body after delay, unless
+// the request is cancelled first. Cancelled requests are reported on the
+// returned channel.
+func newSlowServer(t *testing.T, delay time.Duration, body string) (*httptest.Server, <-chan string) {
+	t.Helper()
+
+	cancelled := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, body)
+		case <-r.Context().Done():
+			cancelled <- r.URL.RequestURI()
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, cancelled
+}
+
+func TestAPIClientSearchContext(t *testing.T) {
+	srv, _ := newSlowServer(t, 10*time.Millisecond, stationsJSON)
+	client := NewAPIClientForServer(srv.URL)
+
+	stations, err := client.SearchContext(context.Background(), SearchParams{Name: "jazz"})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+
+	// Stations without a URL or that failed their last check are dropped
+	if len(stations) != 1 || stations[0].StationUUID != "jazz" {
+		t.Fatalf("Expected only the jazz station, got %+v", stations)
+	}
+	if stations[0].Tags != "jazz,smooth" {
+		t.Errorf("Expected tags to be trimmed, got %q", stations[0].Tags)
+	}
+}
+
+func TestAPIClientCancellation(t *testing.T) {
+	srv, cancelled := newSlowServer(t, 10*time.Second, stationsJSON)
+	client := NewAPIClientForServer(srv.URL)
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{"search", func(ctx context.Context) error {
+			_, err := client.SearchContext(ctx, SearchParams{Name: "slow"})
+			return err
+		}},
+		{"by uuid", func(ctx context.Context) error {
+			_, err := client.GetStationByUUIDContext(ctx, "jazz")
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			start := time.Now()
+			err := tt.call(ctx)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Expected the call to return when cancelled, took %v", elapsed)
+			}
+
+			select {
+			case <-cancelled:
+			case <-time.After(5 * time.Second):
+				t.Errorf("Expected the server to see the request cancelled")
+			}
+		})
+	}
+
+	// Deadlines are honoured too
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.SearchContext(ctx, SearchParams{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestAPIClientGetStationByUUIDContext(t *testing.T) {
+	srv, _ := newSlowServer(t, 10*time.Millisecond, stationsJSON)
+	client := NewAPIClientForServer(srv.URL + "/")
+
+	station, err := client.GetStationByUUIDContext(context.Background(), "jazz")
+	if err != nil {
+		t.Fatalf("Failed to get station: %v", err)
+	}
+	if station.Name != "Jazz Radio" {
+		t.Errorf("Expected Jazz Radio, got %q", station.Name)
+	}
+
+	empty, _ := newSlowServer(t, 0, "[]")
+	if _, err := NewAPIClientForServer(empty.URL).GetStationByUUIDContext(context.Background(), "gone"); err == nil {
+		t.Errorf("Expected an error for an unknown station")
+	}
+}
+
+func TestMockClientContext(t *testing.T) {
+	client := NewMockClient()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.SearchContext(ctx, SearchParams{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled from Search, got %v", err)
+	}
+	if _, err := client.GetStationByUUIDContext(ctx, "960b51d-0601-11e8-ae97-52543be04c81"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled from GetStationByUUID, got %v", err)
+	}
+
+	if station, err := client.GetStationByUUID("960b51d-0601-11e8-ae97-52543be04c81"); err != nil || station.Name != "Jazz Radio" {
+		t.Errorf("Expected Jazz Radio, got %+v (%v)", station, err)
+	}
+}
+
+func TestAPIClientCatalogs(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/json/countries":
+			fmt.Fprint(w, `[{"name": "Italy", "iso_3166_1": "IT", "stationcount": 2000}]`)
+		case "/json/languages":
+			fmt.Fprint(w, `[{"name": "italian", "iso_639": "it", "stationcount": 1800}]`)
+		case "/json/tags":
+			fmt.Fprint(w, `[{"name": "jazz", "stationcount": 900}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewAPIClientForServer(srv.URL)
+	ctx := context.Background()
+
+	countries, err := client.ListCountries(ctx)
+	if err != nil || len(countries) != 1 || countries[0] != (Country{Name: "Italy", Code: "IT", StationCount: 2000}) {
+		t.Errorf("Expected Italy, got %+v (%v)", countries, err)
+	}
+	languages, err := client.ListLanguages(ctx)
+	if err != nil || len(languages) != 1 || languages[0] != (Language{Name: "italian", Code: "it", StationCount: 1800}) {
+		t.Errorf("Expected italian, got %+v (%v)", languages, err)
+	}
+	tags, err := client.ListTags(ctx)
+	if err != nil || len(tags) != 1 || tags[0] != (Tag{Name: "jazz", StationCount: 900}) {
+		t.Errorf("Expected jazz, got %+v (%v)", tags, err)
+	}
+
+	expected := []string{
+		"/json/countries?hidebroken=true&order=stationcount&reverse=true",
+		"/json/languages?hidebroken=true&order=stationcount&reverse=true",
+		fmt.Sprintf("/json/tags?hidebroken=true&limit=%d&order=stationcount&reverse=true", tagLimit),
+	}
+	if fmt.Sprint(queries) != fmt.Sprint(expected) {
+		t.Errorf("Expected requests %v, got %v", expected, queries)
+	}
+}
+
+func TestMockClientCatalogs(t *testing.T) {
+	client := NewMockClient()
+	ctx := context.Background()
+
+	countries, err := client.ListCountries(ctx)
+	if err != nil || len(countries) == 0 {
+		t.Fatalf("Expected mock countries, got %+v (%v)", countries, err)
+	}
+	languages, err := client.ListLanguages(ctx)
+	if err != nil || len(languages) == 0 {
+		t.Fatalf("Expected mock languages, got %+v (%v)", languages, err)
+	}
+	tags, err := client.ListTags(ctx)
+	if err != nil || len(tags) == 0 {
+		t.Fatalf("Expected mock tags, got %+v (%v)", tags, err)
+	}
+
+	// Counts add up to the stations and are sorted, most first
+	stations, _ := client.Search(SearchParams{})
+	total := 0
+	for i, country := range countries {
+		total += country.StationCount
+		if country.Code == "" {
+			t.Errorf("Expected %s to have a country code", country.Name)
+		}
+		if i > 0 && country.StationCount > countries[i-1].StationCount {
+			t.Errorf("Expected countries sorted by station count, got %+v", countries)
+		}
+	}
+	if total != len(stations) {
+		t.Errorf("Expected counts to add up to %d stations, got %d", len(stations), total)
+	}
+	for _, tag := range tags {
+		if tag.Name == "" || strings.TrimSpace(tag.Name) != tag.Name {
+			t.Errorf("Expected trimmed tag names, got %q", tag.Name)
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.ListTags(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		params SearchParams
+		want   string
+	}{
+		{
+			name:   "defaults",
+			params: SearchParams{},
+			want:   "hidebroken=true&limit=50",
+		},
+		{
+			name:   "name",
+			params: SearchParams{Name: "jazz fm", Limit: 20, Offset: 40},
+			want:   "hidebroken=true&limit=20&name=jazz+fm&offset=40",
+		},
+		{
+			name:   "place and language",
+			params: SearchParams{Country: "Italy", CountryCode: "IT", Language: "italian"},
+			want:   "country=Italy&countrycode=IT&hidebroken=true&language=italian&limit=50",
+		},
+		{
+			name:   "stream quality",
+			params: SearchParams{Tag: "jazz", Codec: "AAC", BitrateMin: 128, BitrateMax: 320, HTTPSOnly: true},
+			want:   "bitrateMax=320&bitrateMin=128&codec=AAC&hidebroken=true&is_https=true&limit=50&tag=jazz",
+		},
+		{
+			name:   "ascending order",
+			params: SearchParams{Order: "name"},
+			want:   "hidebroken=true&limit=50&order=name",
+		},
+		{
+			name:   "descending order",
+			params: SearchParams{Order: "votes", Reverse: true},
+			want:   "hidebroken=true&limit=50&order=votes&reverse=true",
+		},
+		{
+			name:   "unset numbers are left out",
+			params: SearchParams{BitrateMin: -1, Offset: -5, Limit: -1},
+			want:   "hidebroken=true&limit=50",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchQuery(tt.params).Encode(); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMockClientFilters(t *testing.T) {
+	client := NewMockClient()
+
+	tests := []struct {
+		name   string
+		params SearchParams
+		want   int
+	}{
+		{"all", SearchParams{}, 5},
+		{"name", SearchParams{Name: "jazz"}, 1},
+		{"country code", SearchParams{CountryCode: "it"}, 1},
+		{"language", SearchParams{Language: "german"}, 2},
+		{"tag", SearchParams{Tag: "Rock"}, 1},
+		{"bitrate", SearchParams{BitrateMin: 129, BitrateMax: 256}, 1},
+		{"no match", SearchParams{Name: "jazz", CountryCode: "IT"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stations, err := client.Search(tt.params)
+			if err != nil {
+				t.Fatalf("Failed to search: %v", err)
+			}
+			if len(stations) != tt.want {
+				t.Errorf("Expected %d stations, got %d", tt.want, len(stations))
+			}
+		})
+	}
+}
+
+func TestGeneratedMockClientPages(t *testing.T) {
+	client := NewGeneratedMockClient(1000)
+	sample := len(mockStations())
+
+	seen := make(map[string]bool)
+	offset := 0
+	for {
+		page, err := client.Search(SearchParams{Limit: 64, Offset: offset})
+		if err != nil {
+			t.Fatalf("Failed to search at offset %d: %v", offset, err)
+		}
+		for _, station := range page {
+			if seen[station.StationUUID] {
+				t.Fatalf("Expected unique stations, got %s twice", station.StationUUID)
+			}
+			seen[station.StationUUID] = true
+		}
+		offset += len(page)
+		if len(page) < 64 {
+			break
+		}
+	}
+	if len(seen) != sample+1000 {
+		t.Errorf("Expected %d stations, got %d", sample+1000, len(seen))
+	}
+
+	// The data is the same every time, and filters apply before paging
+	again := NewGeneratedMockClient(1000)
+	first, _ := client.Search(SearchParams{CountryCode: "JP", Offset: 10, Limit: 5})
+	second, _ := again.Search(SearchParams{CountryCode: "JP", Offset: 10, Limit: 5})
+	if len(first) != 5 || fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Errorf("Expected the same 5 Japanese stations, got %v and %v", first, second)
+	}
+	for _, station := range first {
+		if station.CountryCode != "JP" {
+			t.Errorf("Expected only Japanese stations, got %s", station.Name)
+		}
+	}
+
+	station, err := client.GetStationByUUID(first[0].StationUUID)
+	if err != nil || station.Name != first[0].Name {
+		t.Errorf("Expected %s by UUID, got %+v (%v)", first[0].Name, station, err)
+	}
+}
+
+func TestSortStations(t *testing.T) {
+	stations := []Station{
+		{Name: "b", Country: "Italy", Votes: 5, Bitrate: 128},
+		{Name: "C", Country: "austria", Votes: 9, Bitrate: 64},
+		{Name: "a", Country: "Italy", Votes: 5, Bitrate: 320},
+	}
+
+	tests := []struct {
+		order   string
+		reverse bool
+		want    string
+	}{
+		{"name", false, "abC"},
+		{"name", true, "Cba"},
+		{"country", false, "Cba"},
+		{"votes", true, "Cba"},
+		{"votes", false, "baC"},
+		{"bitrate", true, "abC"},
+		{"random", false, "bCa"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s reverse %v", tt.order, tt.reverse), func(t *testing.T) {
+			sorted := append([]Station(nil), stations...)
+			SortStations(sorted, tt.order, tt.reverse)
+
+			var got string
+			for _, station := range sorted {
+				got += station.Name
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
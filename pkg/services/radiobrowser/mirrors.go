@@ -0,0 +1,136 @@
+package radiobrowser
+
+import (
+	"sync"
+	"time"
+)
+
+// mirror is one Radio Browser API server.
+type mirror struct {
+	baseURL string
+	// downUntil is when a failing mirror may be tried again.
+	downUntil time.Time
+}
+
+// mirrorList tracks the health of the known API servers and which one
+// requests go to. It is safe for concurrent use.
+type mirrorList struct {
+	mu      sync.Mutex
+	mirrors []*mirror
+	// current is the index of the mirror requests go to first.
+	current int
+	// nextResolve is when the list should be resolved again, and
+	// resolving is set while that is under way.
+	nextResolve time.Time
+	resolving   bool
+	cooldown    time.Duration
+	now         func() time.Time
+}
+
+func newMirrorList(baseURLs []string, cooldown time.Duration, now func() time.Time) *mirrorList {
+	l := &mirrorList{cooldown: cooldown, now: now}
+	l.set(baseURLs)
+	return l
+}
+
+// set replaces the mirrors, keeping the health of those already known and
+// staying on the current one if it is still listed.
+func (l *mirrorList) set(baseURLs []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	known := make(map[string]*mirror, len(l.mirrors))
+	for _, m := range l.mirrors {
+		known[m.baseURL] = m
+	}
+
+	var current string
+	if len(l.mirrors) > 0 {
+		current = l.mirrors[l.current].baseURL
+	}
+
+	mirrors := make([]*mirror, 0, len(baseURLs))
+	seen := make(map[string]bool, len(baseURLs))
+	l.current = 0
+	for _, baseURL := range baseURLs {
+		if seen[baseURL] {
+			continue
+		}
+		seen[baseURL] = true
+
+		m := known[baseURL]
+		if m == nil {
+			m = &mirror{baseURL: baseURL}
+		}
+		if baseURL == current {
+			l.current = len(mirrors)
+		}
+		mirrors = append(mirrors, m)
+	}
+	l.mirrors = mirrors
+}
+
+// candidates returns the mirrors to try in order: the healthy ones starting
+// from the current one, then, as a last resort, those cooling down.
+func (l *mirrorList) candidates() []*mirror {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var up, down []*mirror
+	for i := range l.mirrors {
+		m := l.mirrors[(l.current+i)%len(l.mirrors)]
+		if now.Before(m.downUntil) {
+			down = append(down, m)
+		} else {
+			up = append(up, m)
+		}
+	}
+	return append(up, down...)
+}
+
+// markDown takes a failing mirror out of rotation for the cooldown.
+func (l *mirrorList) markDown(m *mirror) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m.downUntil = l.now().Add(l.cooldown)
+	if l.mirrors[l.current] == m {
+		l.current = (l.current + 1) % len(l.mirrors)
+	}
+}
+
+// markUp makes a mirror that answered the current one.
+func (l *mirrorList) markUp(m *mirror) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m.downUntil = time.Time{}
+	for i, other := range l.mirrors {
+		if other == m {
+			l.current = i
+		}
+	}
+}
+
+// resolveDue reports whether the list should be resolved again. If it
+// should, the caller is the one to do it and must call resolveDone after.
+func (l *mirrorList) resolveDue() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.resolving || l.now().Before(l.nextResolve) {
+		return false
+	}
+	l.resolving = true
+	return true
+}
+
+// resolveDone ends a resolution, scheduling the next one after d.
+func (l *mirrorList) resolveDone(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.resolving = false
+	l.nextResolve = l.now().Add(d)
+}
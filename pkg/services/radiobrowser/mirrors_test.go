@@ -0,0 +1,232 @@
+package radiobrowser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testMirror is an API server whose health can be switched.
+type testMirror struct {
+	*httptest.Server
+	name   string
+	status atomic.Int32
+	hits   atomic.Int32
+}
+
+func newTestMirror(t *testing.T, name string, tls bool) *testMirror {
+	t.Helper()
+
+	m := &testMirror{name: name}
+	m.status.Store(http.StatusOK)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.hits.Add(1)
+		if status := int(m.status.Load()); status != http.StatusOK {
+			http.Error(w, "unavailable", status)
+			return
+		}
+		fmt.Fprintf(w, `[{"stationuuid": %q, "name": %q, "url_resolved": "http://x", "lastcheckok": 1}]`, name, name)
+	})
+
+	if tls {
+		m.Server = httptest.NewTLSServer(handler)
+	} else {
+		m.Server = httptest.NewServer(handler)
+	}
+	t.Cleanup(m.Close)
+	return m
+}
+
+// servedBy returns the name of the mirror that answered a search.
+func servedBy(t *testing.T, client *APIClient) string {
+	t.Helper()
+
+	stations, err := client.SearchContext(context.Background(), SearchParams{})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(stations) != 1 {
+		t.Fatalf("Expected 1 station, got %d", len(stations))
+	}
+	return stations[0].Name
+}
+
+// testClock is a settable clock safe for concurrent use.
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func TestAPIClientFailsOverInTurn(t *testing.T) {
+	a := newTestMirror(t, "a", false)
+	b := newTestMirror(t, "b", false)
+	c := newTestMirror(t, "c", false)
+
+	var logs bytes.Buffer
+	clock := &testClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	client := newAPIClient(slog.New(slog.NewTextHandler(&logs, nil)), "", a.URL, b.URL, c.URL)
+	client.mirrors.now = clock.Now
+
+	steps := []struct {
+		name    string
+		fail    *testMirror
+		advance time.Duration
+		want    string
+	}{
+		{"all healthy", nil, 0, "a"},
+		{"a fails", a, 0, "b"},
+		{"b fails too", b, 0, "c"},
+		// a and b are still cooling down, so c keeps serving
+		{"a recovers", nil, 0, "c"},
+		// Once the cooldown is over, a is tried again when c fails
+		{"c fails after the cooldown", c, mirrorCooldown, "a"},
+	}
+
+	for _, step := range steps {
+		if step.fail != nil {
+			step.fail.status.Store(http.StatusServiceUnavailable)
+		}
+		if step.name == "a recovers" {
+			a.status.Store(http.StatusOK)
+		}
+		clock.Advance(step.advance)
+
+		if got := servedBy(t, client); got != step.want {
+			t.Errorf("%s: expected mirror %s to answer, got %s", step.name, step.want, got)
+		}
+	}
+
+	if a.hits.Load() != 3 || b.hits.Load() != 2 || c.hits.Load() != 3 {
+		t.Errorf("Expected 3, 2 and 3 requests, got %d, %d and %d", a.hits.Load(), b.hits.Load(), c.hits.Load())
+	}
+
+	out := logs.String()
+	if !strings.Contains(out, "level=WARN") || !strings.Contains(out, "mirror="+b.URL) || !strings.Contains(out, "status 503") {
+		t.Errorf("Expected structured warnings about failing mirrors, got:\n%s", out)
+	}
+}
+
+func TestAPIClientFailsOverOnNetworkErrors(t *testing.T) {
+	down := newTestMirror(t, "down", false)
+	down.Close()
+	up := newTestMirror(t, "up", false)
+
+	client := newAPIClient(nil, "", down.URL, up.URL)
+	if got := servedBy(t, client); got != "up" {
+		t.Errorf("Expected the second mirror to answer, got %s", got)
+	}
+
+	// Client errors are the request's fault, not the mirror's
+	up.status.Store(http.StatusNotFound)
+	if _, err := client.SearchContext(context.Background(), SearchParams{}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a 404 error, got %v", err)
+	}
+	if up.hits.Load() != 2 {
+		t.Errorf("Expected the mirror to stay in use after a 404, got %d requests", up.hits.Load())
+	}
+
+	// When every mirror fails, those cooling down are tried as a last resort
+	up.status.Store(http.StatusInternalServerError)
+	if _, err := client.SearchContext(context.Background(), SearchParams{}); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Expected the error from the last mirror tried, got %v", err)
+	}
+	if up.hits.Load() != 3 {
+		t.Errorf("Expected the healthy mirror to be tried first, got %d requests", up.hits.Load())
+	}
+}
+
+func TestAPIClientResolvesPeriodically(t *testing.T) {
+	first := newTestMirror(t, "first", true)
+	second := newTestMirror(t, "second", true)
+
+	var listed atomic.Value
+	var listHits atomic.Int32
+	listed.Store(first)
+	list := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listHits.Add(1)
+		m := listed.Load().(*testMirror)
+		if m == nil {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `[{"name": %q}, {"name": %q}]`, m.Listener.Addr(), m.Listener.Addr())
+	}))
+	defer list.Close()
+
+	clock := &testClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	client := newAPIClient(nil, list.URL, "https://unused.invalid")
+	client.httpClient = first.Client()
+	client.mirrors.now = clock.Now
+
+	// Resolved up front, as NewAPIClient does
+	if !client.mirrors.resolveDue() {
+		t.Fatalf("Expected a new client to resolve its mirrors")
+	}
+	if err := client.resolve(context.Background()); err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+
+	if got := servedBy(t, client); got != "first" {
+		t.Errorf("Expected the resolved mirror to answer, got %s", got)
+	}
+	if n := len(client.mirrors.candidates()); n != 1 {
+		t.Errorf("Expected duplicate servers to be merged, got %d mirrors", n)
+	}
+
+	// The list is only fetched again once the interval has passed
+	listed.Store(second)
+	if got := servedBy(t, client); got != "first" {
+		t.Errorf("Expected the list to be kept until the interval passed, got %s", got)
+	}
+
+	// The refresh runs in the background, without holding up the request
+	clock.Advance(resolveInterval)
+	if got := servedBy(t, client); got != "first" {
+		t.Errorf("Expected the request to go to the known mirror, got %s", got)
+	}
+	client.resolves.Wait()
+	if got := servedBy(t, client); got != "second" {
+		t.Errorf("Expected the refreshed mirror to answer, got %s", got)
+	}
+
+	// A failed refresh is retried soon rather than an interval later
+	listed.Store((*testMirror)(nil))
+	clock.Advance(resolveInterval)
+	servedBy(t, client)
+	client.resolves.Wait()
+	hits := listHits.Load()
+
+	listed.Store(first)
+	servedBy(t, client)
+	client.resolves.Wait()
+	if listHits.Load() != hits {
+		t.Errorf("Expected no refresh before the retry delay")
+	}
+
+	clock.Advance(resolveRetry)
+	servedBy(t, client)
+	client.resolves.Wait()
+	if got := servedBy(t, client); got != "first" || listHits.Load() != hits+1 {
+		t.Errorf("Expected the refresh to be retried, got %s after %d fetches", got, listHits.Load()-hits)
+	}
+}
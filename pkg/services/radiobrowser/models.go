@@ -1,5 +1,10 @@
 package radiobrowser
 
+import (
+	"sort"
+	"strings"
+)
+
 // Station represents a radio station from Radio Browser API
 type Station struct {
 	StationUUID   string  `json:"stationuuid"`
@@ -25,16 +30,76 @@ type Station struct {
 	GeoLong       float64 `json:"geo_long"`
 }
 
-// SearchParams holds parameters for searching stations
+// SearchParams holds parameters for searching stations. Zero values match
+// any station.
 type SearchParams struct {
 	Name        string
 	Country     string
 	CountryCode string
 	Language    string
 	Tag         string
-	Bitrate     int
-	Limit       int
-	Offset      int
-	Order       string
-	Reverse     bool
+	Codec       string
+	// BitrateMin and BitrateMax bound the bitrate in kbps.
+	BitrateMin int
+	BitrateMax int
+	// HTTPSOnly limits results to stations streaming over HTTPS.
+	HTTPSOnly bool
+	Limit     int
+	Offset    int
+	// Order is the field to sort by, e.g. "votes"; Reverse sorts it
+	// descending.
+	Order   string
+	Reverse bool
+}
+
+// Country is a country with the number of stations in it.
+type Country struct {
+	Name         string `json:"name"`
+	Code         string `json:"iso_3166_1"`
+	StationCount int    `json:"stationcount"`
+}
+
+// Language is a broadcast language with the number of stations using it.
+type Language struct {
+	Name         string `json:"name"`
+	Code         string `json:"iso_639"`
+	StationCount int    `json:"stationcount"`
+}
+
+// Tag is a genre tag with the number of stations carrying it.
+type Tag struct {
+	Name         string `json:"name"`
+	StationCount int    `json:"stationcount"`
+}
+
+// SortStations sorts stations by order, as the API orders search results,
+// descending if reverse is set. Stations that compare equal keep their
+// order, as do all for an order it cannot sort by, such as "random".
+func SortStations(stations []Station, order string, reverse bool) {
+	var less func(a, b Station) bool
+	switch order {
+	case "name":
+		less = func(a, b Station) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	case "country":
+		less = func(a, b Station) bool { return strings.ToLower(a.Country) < strings.ToLower(b.Country) }
+	case "codec":
+		less = func(a, b Station) bool { return strings.ToLower(a.Codec) < strings.ToLower(b.Codec) }
+	case "votes":
+		less = func(a, b Station) bool { return a.Votes < b.Votes }
+	case "clickcount":
+		less = func(a, b Station) bool { return a.ClickCount < b.ClickCount }
+	case "clicktrend":
+		less = func(a, b Station) bool { return a.ClickTrend < b.ClickTrend }
+	case "bitrate":
+		less = func(a, b Station) bool { return a.Bitrate < b.Bitrate }
+	default:
+		return
+	}
+
+	sort.SliceStable(stations, func(i, j int) bool {
+		if reverse {
+			return less(stations[j], stations[i])
+		}
+		return less(stations[i], stations[j])
+	})
 }
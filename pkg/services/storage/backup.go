@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// backupPrefix and backupSuffix surround the timestamp in backup names.
+	backupPrefix = "terminal-fm-"
+	backupSuffix = ".db"
+	// backupTimeLayout is the timestamp format used in backup names. It
+	// goes down to the nanosecond so that backups taken within the same
+	// second don't collide.
+	backupTimeLayout = "20060102-150405.000000000"
+	// oldBackupTimeLayout is the format of backups named by earlier
+	// versions, which are still listed and cleaned up.
+	oldBackupTimeLayout = "20060102-150405"
+)
+
+// Backup describes a database snapshot in the backup directory.
+type Backup struct {
+	Path      string
+	CreatedAt time.Time
+}
+
+// SetBackupDir sets the directory used by Backup, ListBackups and
+// CleanOldBackups.
+func (s *Store) SetBackupDir(dir string) {
+	s.backupDir = dir
+}
+
+// Backup writes a consistent snapshot of the database into the backup
+// directory and returns its path. It uses VACUUM INTO, so it is safe to run
+// while the database is in use.
+func (s *Store) Backup() (string, error) {
+	if s.backupDir == "" {
+		return "", fmt.Errorf("backup directory not set")
+	}
+
+	if err := os.MkdirAll(s.backupDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := backupPrefix + s.now().UTC().Format(backupTimeLayout) + backupSuffix
+	path := filepath.Join(s.backupDir, name)
+
+	if _, err := s.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return "", fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	return path, nil
+}
+
+// ListBackups returns the backups in the backup directory, newest first.
+// Files that don't look like backups are ignored.
+func (s *Store) ListBackups() ([]Backup, error) {
+	if s.backupDir == "" {
+		return nil, fmt.Errorf("backup directory not set")
+	}
+
+	entries, err := os.ReadDir(s.backupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		createdAt, ok := parseBackupName(entry.Name())
+		if !ok {
+			continue
+		}
+
+		backups = append(backups, Backup{
+			Path:      filepath.Join(s.backupDir, entry.Name()),
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// CleanOldBackups removes backups older than specified days. The most recent
+// backup is always kept, however old it is.
+func (s *Store) CleanOldBackups(days int) error {
+	backups, err := s.ListBackups()
+	if err != nil {
+		return err
+	}
+
+	cutoff := s.now().Add(-time.Duration(days) * 24 * time.Hour)
+
+	for i, backup := range backups {
+		if i == 0 || !backup.CreatedAt.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(backup.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove backup %s: %w", backup.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// StartBackups backs up the database now if the last backup is older than
+// interval, and again every interval, pruning backups older than keepDays
+// each time. Errors are passed to onError, which may be nil. The returned
+// function stops the schedule and must be called before Close.
+func (s *Store) StartBackups(interval time.Duration, keepDays int, onError func(error)) (stop func()) {
+	if onError == nil {
+		onError = func(error) {}
+	}
+
+	run := func() {
+		if _, err := s.Backup(); err != nil {
+			onError(err)
+			return
+		}
+		if err := s.CleanOldBackups(keepDays); err != nil {
+			onError(err)
+		}
+	}
+
+	// Don't pile up snapshots when the app is restarted often
+	backups, err := s.ListBackups()
+	if err != nil {
+		onError(err)
+	} else if len(backups) == 0 || s.now().Sub(backups[0].CreatedAt) >= interval {
+		run()
+	}
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if interval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				run()
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(quit)
+			<-done
+		})
+	}
+}
+
+// RestoreBackup replaces the database at dbPath with the backup at
+// backupPath. The backup is checked for integrity first, and the database
+// must not be open while it is restored.
+func RestoreBackup(backupPath, dbPath string) error {
+	if _, err := os.Stat(backupPath); err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+
+	uri := url.URL{Scheme: "file", Path: backupPath, RawQuery: "mode=ro"}
+	backup, err := sql.Open("sqlite3", uri.String())
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer backup.Close()
+
+	if err := checkBackup(backup); err != nil {
+		return err
+	}
+
+	// Write a fresh copy next to the database so the final rename is atomic
+	tmpPath := dbPath + ".restore"
+	os.Remove(tmpPath)
+
+	if _, err := backup.Exec(`VACUUM INTO ?`, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to copy backup: %w", err)
+	}
+
+	// Leftover journals belong to the old database
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		os.Remove(dbPath + suffix)
+	}
+
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace database: %w", err)
+	}
+
+	return nil
+}
+
+// checkBackup verifies that db is an intact Terminal.FM database that this
+// version can open.
+func checkBackup(db *sql.DB) error {
+	var result string
+	if err := db.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("failed to check backup: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup is corrupt: %s", result)
+	}
+
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'bookmarks'`).Scan(&tables); err != nil {
+		return fmt.Errorf("failed to check backup: %w", err)
+	}
+	if tables == 0 {
+		return fmt.Errorf("not a Terminal.FM database")
+	}
+
+	// Databases from before schema versioning have no schema_migrations
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&tables); err != nil {
+		return fmt.Errorf("failed to check backup: %w", err)
+	}
+	if tables == 0 {
+		return nil
+	}
+
+	version, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if latest := migrations[len(migrations)-1].version; version > latest {
+		return fmt.Errorf("%w (backup version %d, supported %d)", ErrSchemaTooNew, version, latest)
+	}
+
+	return nil
+}
+
+// parseBackupName extracts the creation time from a backup file name.
+func parseBackupName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+		return time.Time{}, false
+	}
+
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix)
+	for _, layout := range []string{backupTimeLayout, oldBackupTimeLayout} {
+		if createdAt, err := time.Parse(layout, stamp); err == nil {
+			return createdAt, true
+		}
+	}
+
+	return time.Time{}, false
+}
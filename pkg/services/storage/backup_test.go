@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// fakeClock is a settable clock for Store.now.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newBackupStore opens a test store that backs up into a temporary
+// directory using the given clock.
+func newBackupStore(t *testing.T, clock *fakeClock) *Store {
+	t.Helper()
+
+	store := newTestStore(t)
+	store.SetBackupDir(filepath.Join(t.TempDir(), "backups"))
+	store.now = clock.Now
+
+	return store
+}
+
+func TestCleanOldBackups(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	store := newBackupStore(t, clock)
+
+	// One backup a day for ten days
+	var paths []string
+	for i := 0; i < 10; i++ {
+		path, err := store.Backup()
+		if err != nil {
+			t.Fatalf("Failed to back up: %v", err)
+		}
+		paths = append(paths, path)
+		clock.Advance(24 * time.Hour)
+	}
+
+	// Unrelated files are never touched
+	notes := filepath.Join(store.backupDir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("keep me"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// Now is day 10, so days 0-2 are older than a week
+	if err := store.CleanOldBackups(7); err != nil {
+		t.Fatalf("Failed to clean backups: %v", err)
+	}
+
+	for i, path := range paths {
+		_, err := os.Stat(path)
+		if i < 3 && !os.IsNotExist(err) {
+			t.Errorf("Expected backup from day %d to be removed", i)
+		}
+		if i >= 3 && err != nil {
+			t.Errorf("Expected backup from day %d to be kept: %v", i, err)
+		}
+	}
+
+	if _, err := os.Stat(notes); err != nil {
+		t.Errorf("Expected unrelated file to be kept: %v", err)
+	}
+
+	// A month later the newest backup still survives
+	clock.Advance(30 * 24 * time.Hour)
+	if err := store.CleanOldBackups(7); err != nil {
+		t.Fatalf("Failed to clean backups: %v", err)
+	}
+
+	backups, err := store.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(backups) != 1 || backups[0].Path != paths[len(paths)-1] {
+		t.Errorf("Expected only the newest backup to remain, got %+v", backups)
+	}
+}
+
+func TestBackupsWithinASecond(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	store := newBackupStore(t, clock)
+
+	var paths []string
+	for i := 0; i < 2; i++ {
+		path, err := store.Backup()
+		if err != nil {
+			t.Fatalf("Failed to back up: %v", err)
+		}
+		paths = append(paths, path)
+		clock.Advance(time.Millisecond)
+	}
+
+	// Backups named by earlier versions are still listed
+	old := filepath.Join(store.backupDir, "terminal-fm-20240430-120000.db")
+	if err := os.WriteFile(old, nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	backups, err := store.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	want := []string{paths[1], paths[0], old}
+	if len(backups) != len(want) {
+		t.Fatalf("Expected %d backups, got %+v", len(want), backups)
+	}
+	for i, backup := range backups {
+		if backup.Path != want[i] {
+			t.Errorf("Expected backup %d to be %s, got %s", i, want[i], backup.Path)
+		}
+	}
+	if !backups[1].CreatedAt.Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the creation time to be read back, got %v", backups[1].CreatedAt)
+	}
+}
+
+func TestStartBackupsSkipsRecentBackup(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	store := newBackupStore(t, clock)
+
+	count := func() int {
+		backups, err := store.ListBackups()
+		if err != nil {
+			t.Fatalf("Failed to list backups: %v", err)
+		}
+		return len(backups)
+	}
+
+	fail := func(err error) { t.Errorf("Unexpected backup error: %v", err) }
+
+	store.StartBackups(24*time.Hour, 7, fail)()
+	if count() != 1 {
+		t.Fatalf("Expected a backup on first start, got %d", count())
+	}
+
+	clock.Advance(time.Hour)
+	store.StartBackups(24*time.Hour, 7, fail)()
+	if count() != 1 {
+		t.Errorf("Expected no backup an hour later, got %d", count())
+	}
+
+	clock.Advance(24 * time.Hour)
+	store.StartBackups(24*time.Hour, 7, fail)()
+	if count() != 2 {
+		t.Errorf("Expected a new backup a day later, got %d", count())
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	store, err := NewStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	// Characters that mean something in a URI are taken literally
+	store.SetBackupDir(filepath.Join(t.TempDir(), "back?ups #1"))
+	store.now = clock.Now
+
+	jazz := &radiobrowser.Station{StationUUID: "jazz", Name: "Jazz Radio", URL: "http://jazz", URLResolved: "http://jazz"}
+	if err := store.AddBookmark(jazz); err != nil {
+		t.Fatalf("Failed to add bookmark: %v", err)
+	}
+
+	backupPath, err := store.Backup()
+	if err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+
+	if err := store.RemoveBookmark("jazz"); err != nil {
+		t.Fatalf("Failed to remove bookmark: %v", err)
+	}
+	store.Close()
+
+	if err := RestoreBackup(backupPath, dbPath); err != nil {
+		t.Fatalf("Failed to restore backup: %v", err)
+	}
+
+	restored, err := NewStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open restored database: %v", err)
+	}
+	defer restored.Close()
+
+	if ok, _ := restored.IsBookmarked("jazz"); !ok {
+		t.Errorf("Expected restored database to contain the bookmark")
+	}
+}
+
+func TestRestoreBackupRejectsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+
+	garbage := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(garbage, []byte("not a database"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := RestoreBackup(garbage, dbPath); err == nil {
+		t.Errorf("Expected error restoring a non-database file")
+	}
+
+	if err := RestoreBackup(filepath.Join(dir, "missing.db"), dbPath); err == nil {
+		t.Errorf("Expected error restoring a missing file")
+	}
+
+	newer := loadFixture(t, "v1.sql")
+	db, err := sql.Open("sqlite3", newer)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, latestVersion()+1); err != nil {
+		t.Fatalf("Failed to bump schema version: %v", err)
+	}
+	db.Close()
+
+	if err := RestoreBackup(newer, dbPath); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew, got %v", err)
+	}
+
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Errorf("Expected failed restores to leave the database untouched")
+	}
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// BookmarkExportVersion is the version of the bookmark export format
+// written by ExportBookmarks.
+const BookmarkExportVersion = 1
+
+// BookmarkExport is the JSON document used to share bookmarks.
+type BookmarkExport struct {
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Bookmarks  []ExportedBookmark `json:"bookmarks"`
+}
+
+// ExportedBookmark is a bookmarked station and the time it was added.
+type ExportedBookmark struct {
+	radiobrowser.Station
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MergePolicy decides what happens when an imported bookmark already exists.
+type MergePolicy int
+
+const (
+	// MergeSkipExisting keeps existing bookmarks untouched.
+	MergeSkipExisting MergePolicy = iota
+	// MergeOverwrite replaces existing bookmarks with imported ones.
+	MergeOverwrite
+	// MergeKeepNewest keeps whichever bookmark was created most recently.
+	MergeKeepNewest
+)
+
+// ParseMergePolicy parses "skip", "overwrite" or "newest".
+func ParseMergePolicy(s string) (MergePolicy, error) {
+	switch s {
+	case "skip":
+		return MergeSkipExisting, nil
+	case "overwrite":
+		return MergeOverwrite, nil
+	case "newest":
+		return MergeKeepNewest, nil
+	default:
+		return 0, fmt.Errorf("invalid merge policy: %s (must be 'skip', 'overwrite' or 'newest')", s)
+	}
+}
+
+// ImportResult summarizes what an import changed.
+type ImportResult struct {
+	Added   int
+	Updated int
+	Skipped int
+}
+
+// ExportBookmarks exports the user's bookmarks as a versioned JSON document.
+func (u *UserStore) ExportBookmarks() ([]byte, error) {
+	query := `
+	SELECT ` + bookmarkColumns + `, created_at
+	FROM bookmarks
+	WHERE user_id = ?
+	ORDER BY created_at ASC
+	`
+
+	rows, err := u.store.db.Query(query, u.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	export := BookmarkExport{
+		Version:    BookmarkExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Bookmarks:  []ExportedBookmark{},
+	}
+
+	for rows.Next() {
+		var bookmark ExportedBookmark
+		fields := append(bookmarkFields(&bookmark.Station), &bookmark.CreatedAt)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, fmt.Errorf("failed to scan bookmark: %w", err)
+		}
+		bookmark.CreatedAt = bookmark.CreatedAt.UTC()
+
+		export.Bookmarks = append(export.Bookmarks, bookmark)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bookmarks: %w", err)
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bookmarks: %w", err)
+	}
+
+	return data, nil
+}
+
+// ImportBookmarks imports bookmarks from a JSON document created by
+// ExportBookmarks. The whole import is applied in a single transaction.
+func (u *UserStore) ImportBookmarks(data []byte, policy MergePolicy) (ImportResult, error) {
+	var result ImportResult
+
+	var export BookmarkExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return result, fmt.Errorf("failed to decode bookmarks: %w", err)
+	}
+
+	if export.Version < 1 || export.Version > BookmarkExportVersion {
+		return result, fmt.Errorf("unsupported bookmark export version %d", export.Version)
+	}
+
+	return u.importBookmarks(export.Bookmarks, policy)
+}
+
+// importBookmarks validates and merges bookmarks in a single transaction.
+func (u *UserStore) importBookmarks(bookmarks []ExportedBookmark, policy MergePolicy) (ImportResult, error) {
+	var result ImportResult
+
+	for i, bookmark := range bookmarks {
+		if bookmark.StationUUID == "" || bookmark.Name == "" || bookmark.URL == "" {
+			return result, fmt.Errorf("bookmark %d is missing a UUID, name or URL", i+1)
+		}
+	}
+
+	tx, err := u.store.db.Begin()
+	if err != nil {
+		return result, fmt.Errorf("failed to begin import: %w", err)
+	}
+	defer tx.Rollback() // No-op after commit
+
+	for _, bookmark := range bookmarks {
+		outcome, err := importBookmark(tx, u.userID, bookmark, policy)
+		if err != nil {
+			return ImportResult{}, err
+		}
+
+		switch outcome {
+		case importAdded:
+			result.Added++
+		case importUpdated:
+			result.Updated++
+		default:
+			result.Skipped++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ImportResult{}, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	return result, nil
+}
+
+// importOutcome is what importBookmark did with a single bookmark.
+type importOutcome int
+
+const (
+	importSkipped importOutcome = iota
+	importAdded
+	importUpdated
+)
+
+// importBookmark inserts or merges a single bookmark for userID according
+// to policy.
+func importBookmark(tx *sql.Tx, userID int64, bookmark ExportedBookmark, policy MergePolicy) (importOutcome, error) {
+	station := bookmark.Station
+	if station.URLResolved == "" {
+		station.URLResolved = station.URL
+	}
+
+	createdAt := bookmark.CreatedAt.UTC()
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+
+	outcome := importAdded
+
+	var existingCreatedAt time.Time
+	err := tx.QueryRow(`SELECT created_at FROM bookmarks WHERE user_id = ? AND station_uuid = ?`, userID, station.StationUUID).
+		Scan(&existingCreatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return importSkipped, fmt.Errorf("failed to check bookmark %s: %w", station.StationUUID, err)
+	}
+
+	if err == nil {
+		switch {
+		case policy == MergeOverwrite:
+			outcome = importUpdated
+		case policy == MergeKeepNewest && createdAt.After(existingCreatedAt):
+			outcome = importUpdated
+		default:
+			return importSkipped, nil
+		}
+	}
+
+	query := `
+	INSERT OR REPLACE INTO bookmarks (user_id, ` + bookmarkColumns + `, created_at)
+	VALUES (?, ` + bookmarkPlaceholders + `, ?)
+	`
+	values := append([]interface{}{userID}, bookmarkValues(&station)...)
+	if _, err := tx.Exec(query, append(values, createdAt)...); err != nil {
+		return importSkipped, fmt.Errorf("failed to import bookmark %s: %w", station.StationUUID, err)
+	}
+
+	return outcome, nil
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// exportDocument builds an export document with the given bookmarks.
+func exportDocument(t *testing.T, bookmarks ...ExportedBookmark) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(BookmarkExport{Version: BookmarkExportVersion, Bookmarks: bookmarks})
+	if err != nil {
+		t.Fatalf("Failed to encode export: %v", err)
+	}
+	return data
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	source := newTestStore(t)
+
+	station := &radiobrowser.Station{
+		StationUUID: "jazz",
+		Name:        "Jazz Radio",
+		URL:         "http://jazz",
+		URLResolved: "http://jazz/live",
+		Favicon:     "http://jazz/icon.png",
+		State:       "Zurich",
+		HLS:         1,
+		ClickTrend:  -3,
+		GeoLat:      47.37,
+		GeoLong:     8.54,
+		Bitrate:     128,
+	}
+	if err := source.AddBookmark(station); err != nil {
+		t.Fatalf("Failed to add bookmark: %v", err)
+	}
+
+	data, err := source.ExportBookmarks()
+	if err != nil {
+		t.Fatalf("Failed to export bookmarks: %v", err)
+	}
+
+	target := newTestStore(t)
+	result, err := target.ImportBookmarks(data, MergeSkipExisting)
+	if err != nil {
+		t.Fatalf("Failed to import bookmarks: %v", err)
+	}
+	if result.Added != 1 {
+		t.Errorf("Expected 1 added bookmark, got %+v", result)
+	}
+
+	bookmarks, err := target.GetBookmarks()
+	if err != nil {
+		t.Fatalf("Failed to get bookmarks: %v", err)
+	}
+	if len(bookmarks) != 1 || bookmarks[0] != *station {
+		t.Errorf("Expected imported station %+v, got %+v", *station, bookmarks)
+	}
+}
+
+func TestImportMergePolicies(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(24 * time.Hour)
+
+	existing := ExportedBookmark{
+		Station:   radiobrowser.Station{StationUUID: "jazz", Name: "Old Name", URL: "http://jazz"},
+		CreatedAt: newer,
+	}
+
+	tests := []struct {
+		name     string
+		policy   MergePolicy
+		imported time.Time
+		wantName string
+		want     ImportResult
+	}{
+		{"skip keeps existing", MergeSkipExisting, newer.Add(time.Hour), "Old Name", ImportResult{Added: 1, Skipped: 1}},
+		{"overwrite replaces", MergeOverwrite, older, "New Name", ImportResult{Added: 1, Updated: 1}},
+		{"newest keeps newer existing", MergeKeepNewest, older, "Old Name", ImportResult{Added: 1, Skipped: 1}},
+		{"newest takes newer import", MergeKeepNewest, newer.Add(time.Hour), "New Name", ImportResult{Added: 1, Updated: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			if _, err := store.ImportBookmarks(exportDocument(t, existing), MergeSkipExisting); err != nil {
+				t.Fatalf("Failed to seed bookmarks: %v", err)
+			}
+
+			data := exportDocument(t,
+				ExportedBookmark{
+					Station:   radiobrowser.Station{StationUUID: "jazz", Name: "New Name", URL: "http://jazz"},
+					CreatedAt: tt.imported,
+				},
+				ExportedBookmark{
+					Station:   radiobrowser.Station{StationUUID: "rock", Name: "Rock FM", URL: "http://rock"},
+					CreatedAt: older,
+				},
+			)
+
+			result, err := store.ImportBookmarks(data, tt.policy)
+			if err != nil {
+				t.Fatalf("Failed to import bookmarks: %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Expected result %+v, got %+v", tt.want, result)
+			}
+
+			bookmarks, _ := store.GetBookmarks()
+			for _, b := range bookmarks {
+				if b.StationUUID == "jazz" && b.Name != tt.wantName {
+					t.Errorf("Expected name %q, got %q", tt.wantName, b.Name)
+				}
+				if b.URLResolved == "" {
+					t.Errorf("Expected URLResolved to default to URL for %s", b.StationUUID)
+				}
+			}
+		})
+	}
+}
+
+func TestImportRejectsInvalidDocuments(t *testing.T) {
+	store := newTestStore(t)
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not json", `bookmarks`},
+		{"missing version", `{"bookmarks": []}`},
+		{"future version", `{"version": 99, "bookmarks": []}`},
+		{"missing url", `{"version": 1, "bookmarks": [{"stationuuid": "x", "name": "X"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.ImportBookmarks([]byte(tt.data), MergeOverwrite); err == nil {
+				t.Errorf("Expected error importing %s", tt.data)
+			}
+		})
+	}
+
+	if count, _ := store.GetBookmarkCount(); count != 0 {
+		t.Errorf("Expected failed imports to leave no bookmarks, got %d", count)
+	}
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrSchemaTooNew is returned when a database was created by a newer
+// version of Terminal.FM than the one opening it.
+var ErrSchemaTooNew = errors.New("database schema is newer than this version supports")
+
+// migration is a single schema change. Migrations are applied in order of
+// version and must never be edited once released; add a new one instead.
+type migration struct {
+	version     int
+	description string
+	up          string
+}
+
+// migrations lists every schema change, oldest first.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create bookmarks",
+		// IF NOT EXISTS keeps databases created before versioning working
+		up: `
+		CREATE TABLE IF NOT EXISTS bookmarks (
+			station_uuid TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			url TEXT NOT NULL,
+			url_resolved TEXT NOT NULL,
+			homepage TEXT,
+			tags TEXT,
+			country TEXT,
+			country_code TEXT,
+			language TEXT,
+			language_codes TEXT,
+			votes INTEGER,
+			codec TEXT,
+			bitrate INTEGER,
+			last_check_ok INTEGER,
+			click_count INTEGER,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_bookmarks_name ON bookmarks(name);
+		CREATE INDEX IF NOT EXISTS idx_bookmarks_created ON bookmarks(created_at);
+		`,
+	},
+	{
+		version:     2,
+		description: "create listening_history",
+		up: `
+		CREATE TABLE IF NOT EXISTS listening_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			station_uuid TEXT NOT NULL,
+			name TEXT NOT NULL,
+			url TEXT NOT NULL,
+			url_resolved TEXT NOT NULL,
+			homepage TEXT,
+			tags TEXT,
+			country TEXT,
+			country_code TEXT,
+			language TEXT,
+			codec TEXT,
+			bitrate INTEGER,
+			started_at TIMESTAMP NOT NULL,
+			duration_seconds INTEGER
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_history_started ON listening_history(started_at);
+		CREATE INDEX IF NOT EXISTS idx_history_station ON listening_history(station_uuid);
+		`,
+	},
+	{
+		version:     3,
+		description: "store all station fields in bookmarks",
+		up: `
+		ALTER TABLE bookmarks ADD COLUMN favicon TEXT DEFAULT '';
+		ALTER TABLE bookmarks ADD COLUMN state TEXT DEFAULT '';
+		ALTER TABLE bookmarks ADD COLUMN hls INTEGER DEFAULT 0;
+		ALTER TABLE bookmarks ADD COLUMN click_trend INTEGER DEFAULT 0;
+		ALTER TABLE bookmarks ADD COLUMN geo_lat REAL DEFAULT 0;
+		ALTER TABLE bookmarks ADD COLUMN geo_long REAL DEFAULT 0;
+		`,
+	},
+	{
+		version:     4,
+		description: "scope bookmarks and history by user",
+		// Existing data belongs to the local user (id 1). SQLite cannot
+		// change a primary key in place, so bookmarks is rebuilt.
+		up: `
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ssh_fingerprint TEXT UNIQUE NOT NULL,
+			language TEXT NOT NULL DEFAULT '',
+			volume INTEGER NOT NULL DEFAULT 70,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		INSERT INTO users (id, ssh_fingerprint) VALUES (1, 'local');
+
+		CREATE TABLE bookmarks_new (
+			user_id INTEGER NOT NULL DEFAULT 1 REFERENCES users(id) ON DELETE CASCADE,
+			station_uuid TEXT NOT NULL,
+			name TEXT NOT NULL,
+			url TEXT NOT NULL,
+			url_resolved TEXT NOT NULL,
+			homepage TEXT,
+			favicon TEXT DEFAULT '',
+			tags TEXT,
+			country TEXT,
+			country_code TEXT,
+			state TEXT DEFAULT '',
+			language TEXT,
+			language_codes TEXT,
+			votes INTEGER,
+			codec TEXT,
+			bitrate INTEGER,
+			hls INTEGER DEFAULT 0,
+			last_check_ok INTEGER,
+			click_count INTEGER,
+			click_trend INTEGER DEFAULT 0,
+			geo_lat REAL DEFAULT 0,
+			geo_long REAL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, station_uuid)
+		);
+
+		INSERT INTO bookmarks_new (
+			user_id, station_uuid, name, url, url_resolved, homepage, favicon, tags,
+			country, country_code, state, language, language_codes,
+			votes, codec, bitrate, hls, last_check_ok, click_count, click_trend,
+			geo_lat, geo_long, created_at
+		)
+		SELECT
+			1, station_uuid, name, url, url_resolved, homepage, favicon, tags,
+			country, country_code, state, language, language_codes,
+			votes, codec, bitrate, hls, last_check_ok, click_count, click_trend,
+			geo_lat, geo_long, created_at
+		FROM bookmarks;
+
+		DROP TABLE bookmarks;
+		ALTER TABLE bookmarks_new RENAME TO bookmarks;
+
+		CREATE INDEX idx_bookmarks_name ON bookmarks(name);
+		CREATE INDEX idx_bookmarks_created ON bookmarks(user_id, created_at);
+
+		ALTER TABLE listening_history ADD COLUMN user_id INTEGER NOT NULL DEFAULT 1;
+		CREATE INDEX idx_history_user ON listening_history(user_id, started_at);
+		`,
+	},
+}
+
+// migrate applies every migration newer than the database's current
+// version, each in its own transaction.
+func migrate(db *sql.DB, migrations []migration) error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	current, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].version
+	}
+
+	if current > latest {
+		return fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs a single migration and records it atomically.
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback() // No-op after commit
+
+	if _, err := tx.Exec(m.up); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+	}
+
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, m.version); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+
+	return nil
+}
+
+// schemaVersion returns the highest applied migration version, or 0.
+func schemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
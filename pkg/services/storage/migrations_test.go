@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// loadFixture creates a database file from a SQL fixture in testdata.
+func loadFixture(t *testing.T, name string) string {
+	t.Helper()
+
+	script, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "fixture.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open fixture database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(string(script)); err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+
+	return dbPath
+}
+
+// latestVersion returns the version of the newest known migration.
+func latestVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func TestMigrateFromFixtures(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		bookmarks int
+	}{
+		{"v1.sql", 2},
+		{"legacy.sql", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			store, err := NewStore(loadFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("Failed to open fixture: %v", err)
+			}
+			defer store.Close()
+
+			version, err := store.SchemaVersion()
+			if err != nil {
+				t.Fatalf("Failed to read schema version: %v", err)
+			}
+			if version != latestVersion() {
+				t.Errorf("Expected schema version %d, got %d", latestVersion(), version)
+			}
+
+			// Existing data survives the upgrade
+			bookmarks, err := store.GetBookmarks()
+			if err != nil {
+				t.Fatalf("Failed to get bookmarks: %v", err)
+			}
+			if len(bookmarks) != tt.bookmarks {
+				t.Errorf("Expected %d bookmarks, got %d", tt.bookmarks, len(bookmarks))
+			}
+
+			// Tables added by later migrations are usable
+			station := &radiobrowser.Station{StationUUID: "x", Name: "X", URL: "http://x", URLResolved: "http://x"}
+			if _, err := store.StartHistoryEntry(station, time.Now()); err != nil {
+				t.Errorf("Failed to use listening_history after upgrade: %v", err)
+			}
+		})
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	for i := 0; i < 2; i++ {
+		store, err := NewStore(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to open store (attempt %d): %v", i+1, err)
+		}
+		store.Close()
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count migrations: %v", err)
+	}
+	if count != len(migrations) {
+		t.Errorf("Expected %d recorded migrations, got %d", len(migrations), count)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	dbPath := loadFixture(t, "v1.sql")
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, latestVersion()+1); err != nil {
+		t.Fatalf("Failed to bump schema version: %v", err)
+	}
+	db.Close()
+
+	_, err = NewStore(dbPath)
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestMigrateRollsBackFailedMigration(t *testing.T) {
+	db, err := sql.Open("sqlite3", loadFixture(t, "v1.sql"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	broken := []migration{
+		migrations[0],
+		{
+			version:     2,
+			description: "half applied",
+			up: `
+			CREATE TABLE partial (id INTEGER);
+			INSERT INTO no_such_table VALUES (1);
+			`,
+		},
+	}
+
+	if err := migrate(db, broken); err == nil {
+		t.Fatalf("Expected broken migration to fail")
+	}
+
+	version, err := schemaVersion(db)
+	if err != nil {
+		t.Fatalf("Failed to read schema version: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("Expected schema to stay at version 1, got %d", version)
+	}
+
+	var count int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'partial'`).Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected failed migration to be rolled back")
+	}
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fulgidus/terminal-fm/pkg/playlist"
+)
+
+// ExportPlaylist writes the user's bookmarks as an M3U, PLS or XSPF playlist.
+func (u *UserStore) ExportPlaylist(w io.Writer, format playlist.Format) error {
+	stations, err := u.GetBookmarks()
+	if err != nil {
+		return err
+	}
+
+	if err := playlist.Write(w, format, stations); err != nil {
+		return fmt.Errorf("failed to write playlist: %w", err)
+	}
+
+	return nil
+}
+
+// ImportPlaylist bookmarks every station in an M3U, PLS or XSPF playlist.
+// Playlists carry no creation times, so imported bookmarks are treated as
+// created now when merging.
+func (u *UserStore) ImportPlaylist(r io.Reader, format playlist.Format, policy MergePolicy) (ImportResult, error) {
+	stations, err := playlist.Parse(r, format)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	bookmarks := make([]ExportedBookmark, 0, len(stations))
+	for _, station := range stations {
+		bookmarks = append(bookmarks, ExportedBookmark{Station: station})
+	}
+
+	return u.importBookmarks(bookmarks, policy)
+}
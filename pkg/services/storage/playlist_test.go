@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fulgidus/terminal-fm/pkg/playlist"
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+func TestPlaylistExportImport(t *testing.T) {
+	source := newTestStore(t)
+
+	station := &radiobrowser.Station{StationUUID: "jazz", Name: "Jazz Radio", URL: "http://jazz", URLResolved: "http://jazz/live"}
+	if err := source.AddBookmark(station); err != nil {
+		t.Fatalf("Failed to add bookmark: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := source.ExportPlaylist(&buf, playlist.FormatM3U); err != nil {
+		t.Fatalf("Failed to export playlist: %v", err)
+	}
+
+	target := newTestStore(t)
+	result, err := target.ImportPlaylist(&buf, playlist.FormatM3U, MergeSkipExisting)
+	if err != nil {
+		t.Fatalf("Failed to import playlist: %v", err)
+	}
+	if result.Added != 1 {
+		t.Errorf("Expected 1 added bookmark, got %+v", result)
+	}
+
+	if ok, _ := target.IsBookmarked("jazz"); !ok {
+		t.Errorf("Expected the Radio Browser UUID to survive an M3U round trip")
+	}
+}
+
+func TestImportPlaylistSyntheticUUID(t *testing.T) {
+	store := newTestStore(t)
+
+	pls := "[playlist]\nFile1=http://rock/stream\nTitle1=Rock FM\nNumberOfEntries=1\n"
+
+	// Importing the same playlist twice must not duplicate the station
+	for i := 0; i < 2; i++ {
+		if _, err := store.ImportPlaylist(strings.NewReader(pls), playlist.FormatPLS, MergeSkipExisting); err != nil {
+			t.Fatalf("Failed to import playlist: %v", err)
+		}
+	}
+
+	bookmarks, err := store.GetBookmarks()
+	if err != nil {
+		t.Fatalf("Failed to get bookmarks: %v", err)
+	}
+	if len(bookmarks) != 1 {
+		t.Fatalf("Expected 1 bookmark, got %d", len(bookmarks))
+	}
+	if bookmarks[0].StationUUID != playlist.SyntheticUUID("http://rock/stream") {
+		t.Errorf("Expected synthetic UUID, got %q", bookmarks[0].StationUUID)
+	}
+	if bookmarks[0].URLResolved != "http://rock/stream" {
+		t.Errorf("Expected URLResolved to default to URL, got %q", bookmarks[0].URLResolved)
+	}
+}
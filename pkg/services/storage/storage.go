@@ -4,14 +4,26 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // Store handles database operations.
+//
+// Bookmarks and history belong to a user; see UserStore. Store embeds the
+// local user's view so the single-user app can use it directly.
 type Store struct {
+	UserStore
+
 	db *sql.DB
+
+	// backupDir is where Backup writes snapshots; see SetBackupDir.
+	backupDir string
+	// now returns the current time; replaced in tests.
+	now func() time.Time
 }
 
 // NewStore creates a new database store and initializes the schema.
@@ -21,17 +33,23 @@ func NewStore(dbPath string) (*Store, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	store := &Store{db: db}
+	store := &Store{db: db, now: time.Now}
+	store.UserStore = UserStore{store: store, userID: LocalUserID}
 
-	// Initialize schema
-	if err := store.initSchema(); err != nil {
+	// Bring the schema up to date
+	if err := migrate(db, migrations); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
 	}
 
 	return store, nil
 }
 
+// SchemaVersion returns the schema version of the open database.
+func (s *Store) SchemaVersion() (int, error) {
+	return schemaVersion(s.db)
+}
+
 // Close closes the database connection.
 func (s *Store) Close() error {
 	if s.db != nil {
@@ -40,68 +58,85 @@ func (s *Store) Close() error {
 	return nil
 }
 
-// initSchema creates the necessary tables.
-func (s *Store) initSchema() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS bookmarks (
-		station_uuid TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		url TEXT NOT NULL,
-		url_resolved TEXT NOT NULL,
-		homepage TEXT,
-		tags TEXT,
-		country TEXT,
-		country_code TEXT,
-		language TEXT,
-		language_codes TEXT,
-		votes INTEGER,
-		codec TEXT,
-		bitrate INTEGER,
-		last_check_ok INTEGER,
-		click_count INTEGER,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_bookmarks_name ON bookmarks(name);
-	CREATE INDEX IF NOT EXISTS idx_bookmarks_created ON bookmarks(created_at);
-	`
-
-	_, err := s.db.Exec(schema)
-	return err
-}
-
-// AddBookmark adds a station to bookmarks.
-func (s *Store) AddBookmark(station *radiobrowser.Station) error {
-	if station == nil {
-		return fmt.Errorf("station cannot be nil")
-	}
+// bookmarkColumns lists the station columns of the bookmarks table, in the
+// order used by bookmarkValues and bookmarkFields.
+const bookmarkColumns = `
+	station_uuid, name, url, url_resolved, homepage, favicon, tags,
+	country, country_code, state, language, language_codes,
+	votes, codec, bitrate, hls, last_check_ok, click_count, click_trend,
+	geo_lat, geo_long`
 
-	query := `
-	INSERT INTO bookmarks (
-		station_uuid, name, url, url_resolved, homepage, tags,
-		country, country_code, language, language_codes,
-		votes, codec, bitrate, last_check_ok, click_count
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	ON CONFLICT(station_uuid) DO NOTHING
-	`
+// bookmarkPlaceholders has one placeholder per entry in bookmarkColumns.
+var bookmarkPlaceholders = strings.TrimSuffix(strings.Repeat("?, ", 21), ", ")
 
-	_, err := s.db.Exec(query,
+// bookmarkValues returns the station fields in bookmarkColumns order.
+func bookmarkValues(station *radiobrowser.Station) []interface{} {
+	return []interface{}{
 		station.StationUUID,
 		station.Name,
 		station.URL,
 		station.URLResolved,
 		station.Homepage,
+		station.Favicon,
 		station.Tags,
 		station.Country,
 		station.CountryCode,
+		station.State,
 		station.Language,
 		station.LanguageCodes,
 		station.Votes,
 		station.Codec,
 		station.Bitrate,
+		station.HLS,
 		station.LastCheckOK,
 		station.ClickCount,
-	)
+		station.ClickTrend,
+		station.GeoLat,
+		station.GeoLong,
+	}
+}
+
+// bookmarkFields returns pointers to the station fields in bookmarkColumns
+// order, for use with Scan.
+func bookmarkFields(station *radiobrowser.Station) []interface{} {
+	return []interface{}{
+		&station.StationUUID,
+		&station.Name,
+		&station.URL,
+		&station.URLResolved,
+		&station.Homepage,
+		&station.Favicon,
+		&station.Tags,
+		&station.Country,
+		&station.CountryCode,
+		&station.State,
+		&station.Language,
+		&station.LanguageCodes,
+		&station.Votes,
+		&station.Codec,
+		&station.Bitrate,
+		&station.HLS,
+		&station.LastCheckOK,
+		&station.ClickCount,
+		&station.ClickTrend,
+		&station.GeoLat,
+		&station.GeoLong,
+	}
+}
+
+// AddBookmark adds a station to bookmarks.
+func (u *UserStore) AddBookmark(station *radiobrowser.Station) error {
+	if station == nil {
+		return fmt.Errorf("station cannot be nil")
+	}
+
+	query := `
+	INSERT INTO bookmarks (user_id, ` + bookmarkColumns + `)
+	VALUES (?, ` + bookmarkPlaceholders + `)
+	ON CONFLICT(user_id, station_uuid) DO NOTHING
+	`
+
+	_, err := u.store.db.Exec(query, append([]interface{}{u.userID}, bookmarkValues(station)...)...)
 
 	if err != nil {
 		return fmt.Errorf("failed to add bookmark: %w", err)
@@ -111,10 +146,10 @@ func (s *Store) AddBookmark(station *radiobrowser.Station) error {
 }
 
 // RemoveBookmark removes a station from bookmarks.
-func (s *Store) RemoveBookmark(stationUUID string) error {
-	query := `DELETE FROM bookmarks WHERE station_uuid = ?`
+func (u *UserStore) RemoveBookmark(stationUUID string) error {
+	query := `DELETE FROM bookmarks WHERE user_id = ? AND station_uuid = ?`
 
-	result, err := s.db.Exec(query, stationUUID)
+	result, err := u.store.db.Exec(query, u.userID, stationUUID)
 	if err != nil {
 		return fmt.Errorf("failed to remove bookmark: %w", err)
 	}
@@ -127,18 +162,16 @@ func (s *Store) RemoveBookmark(stationUUID string) error {
 	return nil
 }
 
-// GetBookmarks retrieves all bookmarked stations.
-func (s *Store) GetBookmarks() ([]radiobrowser.Station, error) {
+// GetBookmarks retrieves the user's bookmarked stations, newest first.
+func (u *UserStore) GetBookmarks() ([]radiobrowser.Station, error) {
 	query := `
-	SELECT 
-		station_uuid, name, url, url_resolved, homepage, tags,
-		country, country_code, language, language_codes,
-		votes, codec, bitrate, last_check_ok, click_count
+	SELECT ` + bookmarkColumns + `
 	FROM bookmarks
+	WHERE user_id = ?
 	ORDER BY created_at DESC
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := u.store.db.Query(query, u.userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query bookmarks: %w", err)
 	}
@@ -148,24 +181,7 @@ func (s *Store) GetBookmarks() ([]radiobrowser.Station, error) {
 
 	for rows.Next() {
 		var station radiobrowser.Station
-		err := rows.Scan(
-			&station.StationUUID,
-			&station.Name,
-			&station.URL,
-			&station.URLResolved,
-			&station.Homepage,
-			&station.Tags,
-			&station.Country,
-			&station.CountryCode,
-			&station.Language,
-			&station.LanguageCodes,
-			&station.Votes,
-			&station.Codec,
-			&station.Bitrate,
-			&station.LastCheckOK,
-			&station.ClickCount,
-		)
-		if err != nil {
+		if err := rows.Scan(bookmarkFields(&station)...); err != nil {
 			return nil, fmt.Errorf("failed to scan bookmark: %w", err)
 		}
 
@@ -180,11 +196,11 @@ func (s *Store) GetBookmarks() ([]radiobrowser.Station, error) {
 }
 
 // IsBookmarked checks if a station is bookmarked.
-func (s *Store) IsBookmarked(stationUUID string) (bool, error) {
-	query := `SELECT COUNT(*) FROM bookmarks WHERE station_uuid = ?`
+func (u *UserStore) IsBookmarked(stationUUID string) (bool, error) {
+	query := `SELECT COUNT(*) FROM bookmarks WHERE user_id = ? AND station_uuid = ?`
 
 	var count int
-	err := s.db.QueryRow(query, stationUUID).Scan(&count)
+	err := u.store.db.QueryRow(query, u.userID, stationUUID).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("failed to check bookmark: %w", err)
 	}
@@ -192,12 +208,12 @@ func (s *Store) IsBookmarked(stationUUID string) (bool, error) {
 	return count > 0, nil
 }
 
-// GetBookmarkCount returns the total number of bookmarks.
-func (s *Store) GetBookmarkCount() (int, error) {
-	query := `SELECT COUNT(*) FROM bookmarks`
+// GetBookmarkCount returns the number of bookmarks the user has.
+func (u *UserStore) GetBookmarkCount() (int, error) {
+	query := `SELECT COUNT(*) FROM bookmarks WHERE user_id = ?`
 
 	var count int
-	err := s.db.QueryRow(query).Scan(&count)
+	err := u.store.db.QueryRow(query, u.userID).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count bookmarks: %w", err)
 	}
@@ -205,20 +221,124 @@ func (s *Store) GetBookmarkCount() (int, error) {
 	return count, nil
 }
 
-// CleanOldBackups removes bookmark backups older than specified days.
-func (s *Store) CleanOldBackups(days int) error {
-	// TODO: Implement backup cleanup in future version
-	return nil
+// HistoryEntry is a single play of a station.
+type HistoryEntry struct {
+	ID        int64
+	Station   radiobrowser.Station
+	StartedAt time.Time
+	// Duration is zero while the station is still playing.
+	Duration time.Duration
 }
 
-// ExportBookmarks exports bookmarks to JSON (for future backup feature).
-func (s *Store) ExportBookmarks() ([]byte, error) {
-	// TODO: Implement JSON export in future version
-	return nil, fmt.Errorf("not implemented")
+// StartHistoryEntry records that a station started playing and returns the
+// entry ID to pass to FinishHistoryEntry when it stops.
+func (u *UserStore) StartHistoryEntry(station *radiobrowser.Station, startedAt time.Time) (int64, error) {
+	if station == nil {
+		return 0, fmt.Errorf("station cannot be nil")
+	}
+
+	query := `
+	INSERT INTO listening_history (
+		user_id, station_uuid, name, url, url_resolved, homepage, tags,
+		country, country_code, language, codec, bitrate, started_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := u.store.db.Exec(query,
+		u.userID,
+		station.StationUUID,
+		station.Name,
+		station.URL,
+		station.URLResolved,
+		station.Homepage,
+		station.Tags,
+		station.Country,
+		station.CountryCode,
+		station.Language,
+		station.Codec,
+		station.Bitrate,
+		startedAt.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add history entry: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get history entry ID: %w", err)
+	}
+
+	return id, nil
 }
 
-// ImportBookmarks imports bookmarks from JSON (for future backup feature).
-func (s *Store) ImportBookmarks(data []byte) error {
-	// TODO: Implement JSON import in future version
-	return fmt.Errorf("not implemented")
+// FinishHistoryEntry records how long a station was listened to.
+func (u *UserStore) FinishHistoryEntry(id int64, duration time.Duration) error {
+	query := `UPDATE listening_history SET duration_seconds = ? WHERE id = ? AND user_id = ?`
+
+	result, err := u.store.db.Exec(query, int64(duration.Seconds()), id, u.userID)
+	if err != nil {
+		return fmt.Errorf("failed to update history entry: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("history entry not found")
+	}
+
+	return nil
+}
+
+// GetHistory retrieves the user's most recent plays, newest first.
+func (u *UserStore) GetHistory(limit int) ([]HistoryEntry, error) {
+	query := `
+	SELECT
+		id, station_uuid, name, url, url_resolved, homepage, tags,
+		country, country_code, language, codec, bitrate,
+		started_at, duration_seconds
+	FROM listening_history
+	WHERE user_id = ?
+	ORDER BY started_at DESC, id DESC
+	LIMIT ?
+	`
+
+	rows, err := u.store.db.Query(query, u.userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []HistoryEntry
+
+	for rows.Next() {
+		var entry HistoryEntry
+		var duration sql.NullInt64
+		err := rows.Scan(
+			&entry.ID,
+			&entry.Station.StationUUID,
+			&entry.Station.Name,
+			&entry.Station.URL,
+			&entry.Station.URLResolved,
+			&entry.Station.Homepage,
+			&entry.Station.Tags,
+			&entry.Station.Country,
+			&entry.Station.CountryCode,
+			&entry.Station.Language,
+			&entry.Station.Codec,
+			&entry.Station.Bitrate,
+			&entry.StartedAt,
+			&duration,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan history entry: %w", err)
+		}
+
+		entry.Duration = time.Duration(duration.Int64) * time.Second
+		history = append(history, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating history: %w", err)
+	}
+
+	return history, nil
 }
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+// newTestStore opens a fresh store in a temporary directory.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	store, err := NewStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestListeningHistory(t *testing.T) {
+	store := newTestStore(t)
+
+	jazz := &radiobrowser.Station{StationUUID: "jazz", Name: "Jazz Radio", URL: "http://jazz", URLResolved: "http://jazz/live"}
+	rock := &radiobrowser.Station{StationUUID: "rock", Name: "Rock FM", URL: "http://rock", URLResolved: "http://rock/live"}
+
+	yesterday := time.Date(2024, 5, 1, 15, 30, 0, 0, time.UTC)
+
+	jazzID, err := store.StartHistoryEntry(jazz, yesterday)
+	if err != nil {
+		t.Fatalf("Failed to start history entry: %v", err)
+	}
+	if err := store.FinishHistoryEntry(jazzID, 42*time.Minute); err != nil {
+		t.Fatalf("Failed to finish history entry: %v", err)
+	}
+
+	// Still playing, so no duration yet
+	if _, err := store.StartHistoryEntry(rock, yesterday.Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to start history entry: %v", err)
+	}
+
+	history, err := store.GetHistory(10)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 history entries, got %d", len(history))
+	}
+
+	// Newest first
+	if history[0].Station.StationUUID != "rock" || history[0].Duration != 0 {
+		t.Errorf("Expected in-progress rock entry first, got %+v", history[0])
+	}
+	if history[1].Station.URLResolved != "http://jazz/live" {
+		t.Errorf("Expected station URL to be kept for replay, got %q", history[1].Station.URLResolved)
+	}
+	if history[1].Duration != 42*time.Minute {
+		t.Errorf("Expected duration of 42m, got %v", history[1].Duration)
+	}
+	if !history[1].StartedAt.Equal(yesterday) {
+		t.Errorf("Expected start time %v, got %v", yesterday, history[1].StartedAt)
+	}
+
+	if err := store.FinishHistoryEntry(9999, time.Minute); err == nil {
+		t.Errorf("Expected error for unknown history entry")
+	}
+}
@@ -0,0 +1,29 @@
+-- A database created before schema versioning: bookmarks and no
+-- schema_migrations table.
+CREATE TABLE bookmarks (
+	station_uuid TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	url TEXT NOT NULL,
+	url_resolved TEXT NOT NULL,
+	homepage TEXT,
+	tags TEXT,
+	country TEXT,
+	country_code TEXT,
+	language TEXT,
+	language_codes TEXT,
+	votes INTEGER,
+	codec TEXT,
+	bitrate INTEGER,
+	last_check_ok INTEGER,
+	click_count INTEGER,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_bookmarks_name ON bookmarks(name);
+CREATE INDEX idx_bookmarks_created ON bookmarks(created_at);
+
+INSERT INTO bookmarks VALUES (
+	'a1b2c3d4-1234-5678-9abc-def012345678', 'Classic Rock FM',
+	'https://rockfm.example.com/stream', 'https://rockfm.example.com/stream',
+	'https://www.rockfm.example.com', 'rock,classic rock', 'United States', 'US',
+	'english', 'en', 2456, 'MP3', 192, 1, 8901, '2023-11-02 21:15:00'
+);
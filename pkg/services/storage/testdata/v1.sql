@@ -0,0 +1,41 @@
+-- A database at schema version 1: bookmarks only.
+CREATE TABLE schema_migrations (
+	version INTEGER PRIMARY KEY,
+	applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO schema_migrations (version) VALUES (1);
+
+CREATE TABLE bookmarks (
+	station_uuid TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	url TEXT NOT NULL,
+	url_resolved TEXT NOT NULL,
+	homepage TEXT,
+	tags TEXT,
+	country TEXT,
+	country_code TEXT,
+	language TEXT,
+	language_codes TEXT,
+	votes INTEGER,
+	codec TEXT,
+	bitrate INTEGER,
+	last_check_ok INTEGER,
+	click_count INTEGER,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_bookmarks_name ON bookmarks(name);
+CREATE INDEX idx_bookmarks_created ON bookmarks(created_at);
+
+INSERT INTO bookmarks VALUES (
+	'960b51d-0601-11e8-ae97-52543be04c81', 'Jazz Radio',
+	'https://jazzradio.ice.infomaniak.ch/jazzradio-high.mp3',
+	'https://jazzradio.ice.infomaniak.ch/jazzradio-high.mp3',
+	'https://www.jazzradio.com', 'jazz,smooth jazz', 'Switzerland', 'CH',
+	'english', 'en', 1234, 'MP3', 128, 1, 5678, '2024-01-15 10:00:00'
+);
+INSERT INTO bookmarks VALUES (
+	'b2c3d4e5-2345-6789-abcd-ef0123456789', 'Radio Italia',
+	'https://radioitalia.example.com/live', 'https://radioitalia.example.com/live',
+	'https://www.radioitalia.it', 'pop,italian', 'Italy', 'IT',
+	'italian', 'it', 1890, 'AAC', 128, 1, 4567, '2024-02-20 18:30:00'
+);
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// LocalUserID is the user that owns everything saved by the local app, and
+// all data created before the database had users.
+const LocalUserID int64 = 1
+
+// User is someone with their own bookmarks, history and preferences.
+type User struct {
+	ID int64
+	// Language is the preferred UI locale, or "" to use the default.
+	Language string
+	// Volume is the playback volume (0-100) applied when a session starts.
+	Volume     int
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+// UserStore is a view of the store limited to a single user's data.
+type UserStore struct {
+	store  *Store
+	userID int64
+}
+
+// Local returns the view of the local user.
+func (s *Store) Local() *UserStore {
+	return &s.UserStore
+}
+
+// Login returns the view of the user identified by an SSH public key
+// fingerprint, creating the user on first login. Only a hash of the
+// fingerprint is stored.
+func (s *Store) Login(fingerprint string) (*UserStore, error) {
+	if fingerprint == "" {
+		return nil, fmt.Errorf("fingerprint cannot be empty")
+	}
+
+	now := s.now().UTC()
+
+	query := `
+	INSERT INTO users (ssh_fingerprint, created_at, last_seen_at)
+	VALUES (?, ?, ?)
+	ON CONFLICT(ssh_fingerprint) DO UPDATE SET last_seen_at = excluded.last_seen_at
+	RETURNING id
+	`
+
+	var id int64
+	if err := s.db.QueryRow(query, hashFingerprint(fingerprint), now, now).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to log in user: %w", err)
+	}
+
+	return &UserStore{store: s, userID: id}, nil
+}
+
+// hashFingerprint returns the value stored in users.ssh_fingerprint.
+func hashFingerprint(fingerprint string) string {
+	sum := sha256.Sum256([]byte(fingerprint))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// UserID returns the ID of the user this view is limited to.
+func (u *UserStore) UserID() int64 {
+	return u.userID
+}
+
+// User returns the user's profile and preferences.
+func (u *UserStore) User() (User, error) {
+	query := `
+	SELECT id, language, volume, created_at, last_seen_at
+	FROM users
+	WHERE id = ?
+	`
+
+	var user User
+	err := u.store.db.QueryRow(query, u.userID).
+		Scan(&user.ID, &user.Language, &user.Volume, &user.CreatedAt, &user.LastSeenAt)
+	if err == sql.ErrNoRows {
+		return User{}, fmt.Errorf("user %d not found", u.userID)
+	}
+	if err != nil {
+		return User{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// SetLanguage saves the user's preferred locale.
+func (u *UserStore) SetLanguage(locale string) error {
+	if _, err := u.store.db.Exec(`UPDATE users SET language = ? WHERE id = ?`, locale, u.userID); err != nil {
+		return fmt.Errorf("failed to save language: %w", err)
+	}
+	return nil
+}
+
+// SetVolume saves the user's playback volume (0-100).
+func (u *UserStore) SetVolume(volume int) error {
+	if volume < 0 || volume > 100 {
+		return fmt.Errorf("volume must be between 0 and 100")
+	}
+
+	if _, err := u.store.db.Exec(`UPDATE users SET volume = ? WHERE id = ?`, volume, u.userID); err != nil {
+		return fmt.Errorf("failed to save volume: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+func TestLoginCreatesAndReusesUsers(t *testing.T) {
+	store := newTestStore(t)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{t: start}
+	store.now = clock.Now
+
+	alice, err := store.Login("SHA256:alice")
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	if alice.UserID() == LocalUserID {
+		t.Errorf("Expected a new user, got the local user")
+	}
+
+	clock.Advance(time.Hour)
+
+	again, err := store.Login("SHA256:alice")
+	if err != nil {
+		t.Fatalf("Failed to log in again: %v", err)
+	}
+	if again.UserID() != alice.UserID() {
+		t.Errorf("Expected the same user on second login, got %d and %d", alice.UserID(), again.UserID())
+	}
+
+	user, err := again.User()
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if !user.LastSeenAt.Equal(clock.Now()) {
+		t.Errorf("Expected last_seen_at %v, got %v", clock.Now(), user.LastSeenAt)
+	}
+	if !user.CreatedAt.Equal(start) {
+		t.Errorf("Expected created_at to be kept, got %v", user.CreatedAt)
+	}
+
+	bob, err := store.Login("SHA256:bob")
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	if bob.UserID() == alice.UserID() {
+		t.Errorf("Expected different users for different keys")
+	}
+
+	if _, err := store.Login(""); err == nil {
+		t.Errorf("Expected an error for an empty fingerprint")
+	}
+}
+
+func TestLoginStoresOnlyHashedFingerprint(t *testing.T) {
+	store := newTestStore(t)
+
+	if _, err := store.Login("SHA256:secret"); err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+
+	rows, err := store.db.Query(`SELECT ssh_fingerprint FROM users`)
+	if err != nil {
+		t.Fatalf("Failed to query users: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stored string
+		if err := rows.Scan(&stored); err != nil {
+			t.Fatalf("Failed to scan: %v", err)
+		}
+		if strings.Contains(stored, "secret") {
+			t.Errorf("Expected the fingerprint to be hashed, got %q", stored)
+		}
+	}
+}
+
+func TestUserPreferences(t *testing.T) {
+	store := newTestStore(t)
+
+	users, err := store.Login("SHA256:alice")
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+
+	user, err := users.User()
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if user.Language != "" || user.Volume != 70 {
+		t.Errorf("Expected default language and volume, got %q and %d", user.Language, user.Volume)
+	}
+
+	if err := users.SetLanguage("it"); err != nil {
+		t.Fatalf("Failed to set language: %v", err)
+	}
+	if err := users.SetVolume(40); err != nil {
+		t.Fatalf("Failed to set volume: %v", err)
+	}
+	if err := users.SetVolume(101); err == nil {
+		t.Errorf("Expected an error for volume above 100")
+	}
+
+	// Preferences persist across logins
+	again, err := store.Login("SHA256:alice")
+	if err != nil {
+		t.Fatalf("Failed to log in again: %v", err)
+	}
+	user, err = again.User()
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if user.Language != "it" || user.Volume != 40 {
+		t.Errorf("Expected language it and volume 40, got %q and %d", user.Language, user.Volume)
+	}
+
+	// Other users are unaffected
+	local, err := store.Local().User()
+	if err != nil {
+		t.Fatalf("Failed to get local user: %v", err)
+	}
+	if local.Language != "" || local.Volume != 70 {
+		t.Errorf("Expected local user defaults, got %q and %d", local.Language, local.Volume)
+	}
+}
+
+func TestUserDataIsolated(t *testing.T) {
+	store := newTestStore(t)
+
+	alice, err := store.Login("SHA256:alice")
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	bob, err := store.Login("SHA256:bob")
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+
+	jazz := &radiobrowser.Station{StationUUID: "jazz", Name: "Jazz Radio", URL: "http://jazz", URLResolved: "http://jazz/live"}
+
+	// The same station can be bookmarked by several users
+	if err := alice.AddBookmark(jazz); err != nil {
+		t.Fatalf("Failed to add bookmark: %v", err)
+	}
+	if err := bob.AddBookmark(jazz); err != nil {
+		t.Fatalf("Failed to add bookmark: %v", err)
+	}
+	if err := bob.RemoveBookmark("jazz"); err != nil {
+		t.Fatalf("Failed to remove bookmark: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		users     *UserStore
+		bookmarks int
+	}{
+		{"alice", alice, 1},
+		{"bob", bob, 0},
+		{"local", store.Local(), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := tt.users.GetBookmarkCount()
+			if err != nil {
+				t.Fatalf("Failed to count bookmarks: %v", err)
+			}
+			if count != tt.bookmarks {
+				t.Errorf("Expected %d bookmarks, got %d", tt.bookmarks, count)
+			}
+
+			isBookmarked, err := tt.users.IsBookmarked("jazz")
+			if err != nil {
+				t.Fatalf("Failed to check bookmark: %v", err)
+			}
+			if isBookmarked != (tt.bookmarks > 0) {
+				t.Errorf("Expected IsBookmarked %v, got %v", tt.bookmarks > 0, isBookmarked)
+			}
+		})
+	}
+
+	// History is per user, and one user cannot finish another's entry
+	id, err := alice.StartHistoryEntry(jazz, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to start history entry: %v", err)
+	}
+	if err := bob.FinishHistoryEntry(id, time.Minute); err == nil {
+		t.Errorf("Expected an error finishing another user's history entry")
+	}
+
+	history, err := bob.GetHistory(10)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("Expected no history for bob, got %d entries", len(history))
+	}
+
+	history, err = alice.GetHistory(10)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 1 {
+		t.Errorf("Expected 1 history entry for alice, got %d", len(history))
+	}
+}
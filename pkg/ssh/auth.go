@@ -0,0 +1,46 @@
+package ssh
+
+import (
+	"github.com/charmbracelet/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// fingerprintExtension is the permissions extension holding the
+// fingerprint of the key a connection authenticated with.
+const fingerprintExtension = "terminal-fm-fingerprint"
+
+// publicKeyAuth accepts any public key. Keys are not checked against
+// anything; accepting them lets the server tell returning users apart by
+// fingerprint.
+//
+// The callback is installed on the SSH config directly rather than as a
+// ssh.PublicKeyHandler, which records every key offered, even those whose
+// signature is never checked. Each key gets its own permissions instead,
+// and the connection ends up with those of the method that succeeded.
+func publicKeyAuth(srv *ssh.Server) error {
+	srv.ServerConfigCallback = func(ctx ssh.Context) *gossh.ServerConfig {
+		return &gossh.ServerConfig{
+			PublicKeyCallback: func(conn gossh.ConnMetadata, key gossh.PublicKey) (*gossh.Permissions, error) {
+				return &gossh.Permissions{
+					Extensions: map[string]string{fingerprintExtension: gossh.FingerprintSHA256(key)},
+				}, nil
+			},
+		}
+	}
+	return nil
+}
+
+// anonymousKeyboardInteractiveAuth lets clients without a key in.
+func anonymousKeyboardInteractiveAuth(ctx ssh.Context, challenger gossh.KeyboardInteractiveChallenge) bool {
+	return true
+}
+
+// verifiedFingerprint returns the fingerprint of the key the session's
+// connection authenticated with, or "" if it did not use one.
+func verifiedFingerprint(sess ssh.Session) string {
+	conn, ok := sess.Context().Value(ssh.ContextKeyConn).(*gossh.ServerConn)
+	if !ok || conn.Permissions == nil {
+		return ""
+	}
+	return conn.Permissions.Extensions[fingerprintExtension]
+}
@@ -0,0 +1,107 @@
+package ssh
+
+import (
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+)
+
+// sessionLimiter enforces the concurrent session limits.
+type sessionLimiter struct {
+	maxTotal int
+	maxPerIP int
+
+	mu    sync.Mutex
+	total int
+	perIP map[string]int
+}
+
+func newSessionLimiter(maxTotal, maxPerIP int) *sessionLimiter {
+	return &sessionLimiter{
+		maxTotal: maxTotal,
+		maxPerIP: maxPerIP,
+		perIP:    make(map[string]int),
+	}
+}
+
+// acquire reserves a session slot for ip, reporting false if a limit has
+// been reached.
+func (l *sessionLimiter) acquire(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxTotal > 0 && l.total >= l.maxTotal {
+		return false
+	}
+	if l.maxPerIP > 0 && l.perIP[ip] >= l.maxPerIP {
+		return false
+	}
+
+	l.total++
+	l.perIP[ip]++
+	return true
+}
+
+// release frees a slot reserved by acquire.
+func (l *sessionLimiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total--
+	if l.perIP[ip]--; l.perIP[ip] <= 0 {
+		delete(l.perIP, ip)
+	}
+}
+
+// active returns the number of reserved slots.
+func (l *sessionLimiter) active() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.total
+}
+
+// middleware turns away sessions over the limits.
+func (l *sessionLimiter) middleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			ip := remoteIP(sess.RemoteAddr())
+
+			if !l.acquire(ip) {
+				log.Printf("Rejected session from %s: too many connections", ip)
+				wish.Fatalln(sess, "Terminal.FM is busy, too many connections. Please try again later.")
+				return
+			}
+			defer l.release(ip)
+
+			next(sess)
+		}
+	}
+}
+
+// loggingMiddleware logs when sessions start and end.
+func loggingMiddleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			start := time.Now()
+			log.Printf("Session started: %s@%s", sess.User(), sess.RemoteAddr())
+
+			next(sess)
+
+			log.Printf("Session ended: %s@%s after %s", sess.User(), sess.RemoteAddr(),
+				time.Since(start).Round(time.Second))
+		}
+	}
+}
+
+// remoteIP returns the host part of addr.
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
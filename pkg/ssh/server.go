@@ -0,0 +1,160 @@
+// Package ssh serves the Terminal.FM TUI over SSH using Charm Wish.
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+	"github.com/fulgidus/terminal-fm/internal/config"
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+	"github.com/fulgidus/terminal-fm/pkg/services/storage"
+)
+
+// Config holds SSH server settings.
+type Config struct {
+	// Address is the host:port to listen on.
+	Address string
+	// HostKeyPath is the server's private host key. An ed25519 key is
+	// generated there on first start so clients see a stable identity.
+	HostKeyPath string
+	// MaxSessions caps concurrent sessions across all clients; 0 means no limit.
+	MaxSessions int
+	// MaxSessionsPerIP caps concurrent sessions from one address; 0 means no limit.
+	MaxSessionsPerIP int
+	// IdleTimeout disconnects sessions without any input or output for this long.
+	IdleTimeout time.Duration
+	// Locale is the UI language for new sessions.
+	Locale string
+	// Columns are the columns of the station table, in order; nil shows
+	// them all.
+	Columns []string
+	// StreamAudio makes the server play the stations and stream the audio
+	// to terminal-fm-client, instead of asking the client to play them.
+	StreamAudio bool
+	// FFmpegPath is the ffmpeg that encodes streamed audio; "" looks it
+	// up in PATH.
+	FFmpegPath string
+	// StreamTitles shows the title playing on each station, which the
+	// server reads from the stream over a connection of its own for every
+	// listener.
+	StreamTitles bool
+}
+
+// DefaultConfig returns the default server settings.
+func DefaultConfig() Config {
+	homeDir, _ := os.UserHomeDir()
+
+	return Config{
+		Address:          ":2222",
+		HostKeyPath:      filepath.Join(homeDir, ".terminal-fm", "ssh", "host_ed25519"),
+		MaxSessions:      100,
+		MaxSessionsPerIP: 10,
+		IdleTimeout:      30 * time.Minute,
+		Locale:           "en",
+	}
+}
+
+// Server serves one TUI per SSH session. Each session gets its own player,
+// which sends playback commands to the client, or with StreamAudio the
+// audio itself, instead of playing it on the server.
+type Server struct {
+	cfg         Config
+	radioClient radiobrowser.Client
+	store       *storage.Store
+	limiter     *sessionLimiter
+	srv         *ssh.Server
+}
+
+// NewServer creates an SSH server. The radio client and store are shared by
+// all sessions.
+func NewServer(cfg Config, radioClient radiobrowser.Client, store *storage.Store) (*Server, error) {
+	if cfg.HostKeyPath == "" {
+		return nil, fmt.Errorf("host key path not set")
+	}
+
+	// Columns are checked once here rather than in every session
+	if cfg.Columns != nil {
+		if err := config.ValidateColumns(cfg.Columns); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.HostKeyPath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create host key directory: %w", err)
+	}
+
+	s := &Server{
+		cfg:         cfg,
+		radioClient: radioClient,
+		store:       store,
+		limiter:     newSessionLimiter(cfg.MaxSessions, cfg.MaxSessionsPerIP),
+	}
+
+	options := []ssh.Option{
+		wish.WithAddress(cfg.Address),
+		wish.WithHostKeyPath(cfg.HostKeyPath),
+		publicKeyAuth,
+		wish.WithKeyboardInteractiveAuth(anonymousKeyboardInteractiveAuth),
+		// Middleware runs last to first
+		wish.WithMiddleware(
+			s.teaMiddleware(),
+			s.limiter.middleware(),
+			loggingMiddleware(),
+		),
+	}
+	if cfg.IdleTimeout > 0 {
+		options = append(options, wish.WithIdleTimeout(cfg.IdleTimeout))
+	}
+
+	srv, err := wish.NewServer(options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH server: %w", err)
+	}
+	s.srv = srv
+
+	return s, nil
+}
+
+// ListenAndServe listens on the configured address and serves sessions
+// until Shutdown is called.
+func (s *Server) ListenAndServe() error {
+	log.Printf("Starting SSH server on %s", s.cfg.Address)
+	return s.filterClosed(s.srv.ListenAndServe())
+}
+
+// Serve serves sessions on an existing listener until Shutdown is called.
+func (s *Server) Serve(ln net.Listener) error {
+	return s.filterClosed(s.srv.Serve(ln))
+}
+
+// Shutdown stops accepting connections and waits for open sessions to end
+// or ctx to expire, whichever comes first.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// Close disconnects every session immediately.
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// ActiveSessions returns the number of connected sessions.
+func (s *Server) ActiveSessions() int {
+	return s.limiter.active()
+}
+
+// filterClosed hides the error returned after a normal shutdown.
+func (s *Server) filterClosed(err error) error {
+	if errors.Is(err, ssh.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,485 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fulgidus/terminal-fm/pkg/protocol"
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+	"github.com/fulgidus/terminal-fm/pkg/services/storage"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// startTestServer runs a server on a random local port until the test ends.
+func startTestServer(t *testing.T, cfg Config) (*Server, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	store, err := storage.NewStore(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	cfg.Address = "127.0.0.1:0"
+	cfg.HostKeyPath = filepath.Join(dir, "ssh", "host_ed25519")
+	cfg.Locale = "en"
+
+	server, err := NewServer(cfg, radiobrowser.NewMockClient(), store)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ln) }()
+	t.Cleanup(func() {
+		_ = server.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Expected Serve to return nil after Close, got %v", err)
+		}
+	})
+
+	return server, ln.Addr().String()
+}
+
+// testSession is an interactive client session whose output is collected.
+type testSession struct {
+	client  *gossh.Client
+	session *gossh.Session
+	stdin   io.WriteCloser
+
+	mu     sync.Mutex
+	output bytes.Buffer
+}
+
+// dial connects to addr without a key and opens a shell with an 80x24 pty.
+func dial(t *testing.T, addr string) *testSession {
+	t.Helper()
+	return dialAs(t, addr, nil, nil)
+}
+
+// newSigner generates a client key.
+func newSigner(t *testing.T) gossh.Signer {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer, err := gossh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	return signer
+}
+
+// dialAs is like dial, but first tries to authenticate with signer if not
+// nil, and sends env to the server.
+func dialAs(t *testing.T, addr string, signer gossh.Signer, env map[string]string) *testSession {
+	t.Helper()
+
+	auth := []gossh.AuthMethod{gossh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+		return make([]string, len(questions)), nil
+	})}
+	if signer != nil {
+		auth = append([]gossh.AuthMethod{gossh.PublicKeys(signer)}, auth...)
+	}
+
+	client, err := gossh.Dial("tcp", addr, &gossh.ClientConfig{
+		User:            "listener",
+		Auth:            auth,
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatalf("Failed to open session: %v", err)
+	}
+
+	ts := &testSession{client: client, session: session}
+
+	for name, value := range env {
+		if err := session.Setenv(name, value); err != nil {
+			t.Fatalf("Failed to set %s: %v", name, err)
+		}
+	}
+
+	ts.stdin, err = session.StdinPipe()
+	if err != nil {
+		t.Fatalf("Failed to get stdin: %v", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Failed to get stdout: %v", err)
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		t.Fatalf("Failed to get stderr: %v", err)
+	}
+	go ts.collect(stdout)
+	go ts.collect(stderr)
+
+	if err := session.RequestPty("xterm-256color", 24, 80, gossh.TerminalModes{}); err != nil {
+		t.Fatalf("Failed to request pty: %v", err)
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatalf("Failed to start shell: %v", err)
+	}
+
+	return ts
+}
+
+// collect appends everything read from r to the output.
+func (ts *testSession) collect(r io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		ts.mu.Lock()
+		ts.output.Write(buf[:n])
+		ts.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// waitFor waits until the output contains want.
+func (ts *testSession) waitFor(t *testing.T, want string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		ts.mu.Lock()
+		found := strings.Contains(ts.output.String(), want)
+		ts.mu.Unlock()
+		if found {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	t.Fatalf("Expected output to contain %q, got %q", want, ts.output.String())
+}
+
+// answerHello waits for the server's HELLO and replies as
+// terminal-fm-client would.
+func (ts *testSession) answerHello(t *testing.T) {
+	t.Helper()
+
+	ts.waitFor(t, ";HELLO;")
+	hello := protocol.Hello(protocol.CapPlay, protocol.CapPause, protocol.CapVolume, protocol.CapStatus)
+	if _, err := ts.stdin.Write(protocol.Encode(hello)); err != nil {
+		t.Fatalf("Failed to send HELLO: %v", err)
+	}
+}
+
+// press sends keys one at a time, so the TUI does not read them as a
+// single paste.
+func (ts *testSession) press(t *testing.T, keys ...string) {
+	t.Helper()
+
+	for _, key := range keys {
+		if _, err := ts.stdin.Write([]byte(key)); err != nil {
+			t.Fatalf("Failed to send %q: %v", key, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// wait waits for the server to end the session.
+func (ts *testSession) wait(t *testing.T) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		_ = ts.session.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the server to close the session")
+	}
+}
+
+// waitForSessions waits until the server reports want active sessions.
+func waitForSessions(t *testing.T, server *Server, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for server.ActiveSessions() != want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := server.ActiveSessions(); got != want {
+		t.Fatalf("Expected %d active sessions, got %d", want, got)
+	}
+}
+
+func TestServerDrivesTUI(t *testing.T) {
+	server, addr := startTestServer(t, Config{MaxSessions: 5})
+	ts := dial(t, addr)
+
+	ts.waitFor(t, "Terminal.FM")
+	ts.waitFor(t, "Jazz Radio")
+	waitForSessions(t, server, 1)
+	ts.answerHello(t)
+
+	// Playing sends the stream to the client instead of the server's speakers
+	if _, err := ts.stdin.Write([]byte("\r")); err != nil {
+		t.Fatalf("Failed to send enter: %v", err)
+	}
+	ts.waitFor(t, ";PLAY;https://electronicbeats.example.com/stream;70\a")
+
+	// Quitting stops the client's player and ends the session
+	if _, err := ts.stdin.Write([]byte("q")); err != nil {
+		t.Fatalf("Failed to send quit: %v", err)
+	}
+	ts.wait(t)
+	ts.waitFor(t, ";STOP\a")
+	waitForSessions(t, server, 0)
+}
+
+func TestServerStreamsAudio(t *testing.T) {
+	// ffmpeg stands in with a single 1024-byte frame of "MP3"
+	ffmpeg := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\nhead -c 1024 /dev/zero | tr '\\0' a\nexec sleep 30\n"
+	if err := os.WriteFile(ffmpeg, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake ffmpeg: %v", err)
+	}
+
+	server, addr := startTestServer(t, Config{StreamAudio: true, FFmpegPath: ffmpeg})
+	ts := dial(t, addr)
+
+	// The server offers codecs, and the client answers with those it decodes
+	ts.waitFor(t, ";HELLO;1;codec:opus;codec:mp3;codec:pcm\a")
+	ts.waitFor(t, "Jazz Radio")
+	hello := protocol.Hello(append([]string{protocol.CapPlay}, protocol.CodecCaps(protocol.CodecMP3)...)...)
+	if _, err := ts.stdin.Write(protocol.Encode(hello)); err != nil {
+		t.Fatalf("Failed to send HELLO: %v", err)
+	}
+
+	// Playing sends the audio rather than the station's URL
+	ts.press(t, "\r")
+	frame := protocol.Frame{
+		Format: protocol.Format{Codec: protocol.CodecMP3, SampleRate: 44100, Channels: 2},
+		Data:   bytes.Repeat([]byte("a"), 1024),
+	}.Message()
+	ts.waitFor(t, ";"+strings.Join(append([]string{frame.Type}, frame.Args...), ";")+"\a")
+
+	ts.press(t, "q")
+	ts.wait(t)
+	ts.waitFor(t, ";STOP\a")
+	waitForSessions(t, server, 0)
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if strings.Contains(ts.output.String(), ";PLAY;") {
+		t.Errorf("Expected no PLAY when streaming")
+	}
+}
+
+func TestServerWithoutClientPlayer(t *testing.T) {
+	_, addr := startTestServer(t, Config{})
+	ts := dial(t, addr)
+
+	// Plain ssh never answers the HELLO, so nothing claims to play
+	ts.waitFor(t, ";HELLO;1;")
+	ts.waitFor(t, "Jazz Radio")
+	ts.press(t, "\r")
+	ts.waitFor(t, "no player on your side")
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if strings.Contains(ts.output.String(), ";PLAY;") {
+		t.Errorf("Expected no PLAY without a client player")
+	}
+}
+
+func TestServerCleansUpOnDisconnect(t *testing.T) {
+	server, addr := startTestServer(t, Config{})
+	ts := dial(t, addr)
+
+	ts.waitFor(t, "Jazz Radio")
+	waitForSessions(t, server, 1)
+
+	ts.client.Close()
+	waitForSessions(t, server, 0)
+}
+
+func TestServerRequiresPty(t *testing.T) {
+	_, addr := startTestServer(t, Config{})
+	ts := dial(t, addr)
+	ts.waitFor(t, "Jazz Radio")
+
+	session, err := ts.client.NewSession()
+	if err != nil {
+		t.Fatalf("Failed to open session: %v", err)
+	}
+	out, _ := session.CombinedOutput("")
+	if !strings.Contains(string(out), "needs an interactive terminal") {
+		t.Errorf("Expected a pty error, got %q", out)
+	}
+}
+
+func TestServerSessionLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"total", Config{MaxSessions: 1}},
+		{"per IP", Config{MaxSessions: 10, MaxSessionsPerIP: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, addr := startTestServer(t, tt.cfg)
+
+			first := dial(t, addr)
+			first.waitFor(t, "Jazz Radio")
+
+			second := dial(t, addr)
+			second.waitFor(t, "too many connections")
+			second.wait(t)
+
+			if got := server.ActiveSessions(); got != 1 {
+				t.Errorf("Expected the rejected session not to count, got %d active", got)
+			}
+
+			// The slot frees up once the first session ends
+			if _, err := first.stdin.Write([]byte("q")); err != nil {
+				t.Fatalf("Failed to send quit: %v", err)
+			}
+			first.wait(t)
+			waitForSessions(t, server, 0)
+
+			third := dial(t, addr)
+			third.waitFor(t, "Jazz Radio")
+		})
+	}
+}
+
+func TestServerReleasesSessions(t *testing.T) {
+	server, addr := startTestServer(t, Config{})
+	run := func() {
+		ts := dial(t, addr)
+		ts.waitFor(t, "Jazz Radio")
+		if _, err := ts.stdin.Write([]byte("q")); err != nil {
+			t.Fatalf("Failed to send quit: %v", err)
+		}
+		ts.wait(t)
+		ts.client.Close()
+		waitForSessions(t, server, 0)
+	}
+
+	// The first session starts what lives as long as the server
+	run()
+	baseline := runtime.NumGoroutine()
+
+	for i := 0; i < 5; i++ {
+		run()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := runtime.NumGoroutine(); got > baseline {
+		t.Errorf("Expected ended sessions to leave nothing running, got %d goroutines, up from %d", got, baseline)
+	}
+}
+
+func TestServerColumns(t *testing.T) {
+	_, addr := startTestServer(t, Config{Columns: []string{"votes", "name"}})
+	ts := dial(t, addr)
+	ts.waitFor(t, "Votes▼  Name")
+
+	// The same rules as in the config file
+	for _, columns := range [][]string{{"name", "flag"}, {"votes"}, {"name", "votes", "name"}} {
+		_, err := NewServer(Config{HostKeyPath: filepath.Join(t.TempDir(), "key"), Columns: columns}, radiobrowser.NewMockClient(), nil)
+		if err == nil {
+			t.Errorf("Expected columns %v to be rejected", columns)
+		}
+	}
+}
+
+// spoofSigner offers another user's public key without its private key.
+// Its signatures are in a format the server turns down, so the client goes
+// on to the next way of authenticating.
+type spoofSigner struct {
+	key gossh.PublicKey
+}
+
+func (s spoofSigner) PublicKey() gossh.PublicKey {
+	return s.key
+}
+
+func (s spoofSigner) Sign(rand io.Reader, data []byte) (*gossh.Signature, error) {
+	return &gossh.Signature{Format: "forged", Blob: []byte("forged")}, nil
+}
+
+func TestServerDoesNotTrustUnsignedKeys(t *testing.T) {
+	_, addr := startTestServer(t, Config{})
+	alice := newSigner(t)
+
+	victim := dialAs(t, addr, alice, map[string]string{"LANG": "it_IT.UTF-8"})
+	victim.waitFor(t, "Trovate 5 stazioni")
+
+	// Offering Alice's key is not enough to be taken for her
+	attacker := dialAs(t, addr, spoofSigner{key: alice.PublicKey()}, nil)
+	attacker.waitFor(t, "Found 5 stations")
+}
+
+func TestServerRemembersUsers(t *testing.T) {
+	_, addr := startTestServer(t, Config{})
+	alice := newSigner(t)
+
+	// The first session picks up the client's language and saves the volume
+	first := dialAs(t, addr, alice, map[string]string{"LANG": "it_IT.UTF-8"})
+	first.waitFor(t, "Trovate 5 stazioni")
+	first.waitFor(t, "Jazz Radio")
+	first.press(t, "-", "q")
+	first.wait(t)
+
+	// Both apply again on the next login, without LANG
+	second := dialAs(t, addr, alice, nil)
+	second.waitFor(t, "Trovate 5 stazioni")
+	second.waitFor(t, "Jazz Radio")
+	second.answerHello(t)
+	if _, err := second.stdin.Write([]byte("\r")); err != nil {
+		t.Fatalf("Failed to send enter: %v", err)
+	}
+	second.waitFor(t, "electronicbeats.example.com/stream;60\a")
+
+	// Other users keep the defaults
+	bob := dialAs(t, addr, newSigner(t), nil)
+	bob.waitFor(t, "Found 5 stations")
+	bob.waitFor(t, "Jazz Radio")
+	bob.answerHello(t)
+	if _, err := bob.stdin.Write([]byte("\r")); err != nil {
+		t.Fatalf("Failed to send enter: %v", err)
+	}
+	bob.waitFor(t, "electronicbeats.example.com/stream;70\a")
+}
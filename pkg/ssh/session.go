@@ -0,0 +1,169 @@
+package ssh
+
+import (
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+	"github.com/fulgidus/terminal-fm/pkg/protocol"
+	"github.com/fulgidus/terminal-fm/pkg/services/player"
+	"github.com/fulgidus/terminal-fm/pkg/services/storage"
+	"github.com/fulgidus/terminal-fm/pkg/ui"
+)
+
+// teaMiddleware runs a TUI for each interactive session. Unlike the Wish
+// bubbletea middleware, it keeps the final model so the session's player
+// and history are cleaned up however the session ends.
+func (s *Server) teaMiddleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			pty, windowChanges, ok := sess.Pty()
+			if !ok {
+				wish.Fatalln(sess, "Terminal.FM needs an interactive terminal. Try: ssh -t")
+				return
+			}
+
+			// The TUI and the player share the session output, so writes
+			// must not interleave mid-frame
+			out := &syncWriter{w: sess}
+
+			audioPlayer := s.newPlayer(out)
+			model := ui.NewModel(s.radioClient, audioPlayer, s.login(sess), s.cfg.Locale)
+			if s.cfg.Columns != nil {
+				// Checked in NewServer
+				_ = model.SetColumns(s.cfg.Columns)
+			}
+			model.SetStreamTitles(s.cfg.StreamTitles)
+
+			// Replies from terminal-fm-client arrive mixed with key presses
+			in := protocol.NewReader(sess, func(payload string) {
+				if err := audioPlayer.HandleReply(payload); err != nil {
+					log.Printf("Session %s: bad reply from client: %v", sess.RemoteAddr(), err)
+				}
+			})
+
+			// Ask for a player on the client's side. Plain ssh ignores this
+			if err := audioPlayer.Hello(); err != nil {
+				log.Printf("Session %s: %v", sess.RemoteAddr(), err)
+			}
+
+			program := tea.NewProgram(model,
+				tea.WithInput(in),
+				tea.WithOutput(out),
+				tea.WithAltScreen(),
+			)
+
+			// Forward the terminal size, and quit when the client goes away
+			go func() {
+				program.Send(tea.WindowSizeMsg{Width: pty.Window.Width, Height: pty.Window.Height})
+				for {
+					select {
+					case <-sess.Context().Done():
+						program.Quit()
+						return
+					case w := <-windowChanges:
+						program.Send(tea.WindowSizeMsg{Width: w.Width, Height: w.Height})
+					}
+				}
+			}()
+
+			finalModel, err := program.Run()
+			if err != nil {
+				log.Printf("Session %s: TUI exited with error: %v", sess.RemoteAddr(), err)
+			}
+			program.Kill()
+
+			// Cleaning up also ends the model's subscription to the
+			// player's events, so nothing of the session lingers
+			if m, ok := finalModel.(ui.Model); ok {
+				m.Cleanup()
+			} else {
+				model.Cleanup()
+			}
+
+			next(sess)
+		}
+	}
+}
+
+// sessionPlayer is a player that works through terminal-fm-client on the
+// other end of the session.
+type sessionPlayer interface {
+	player.Player
+	// Hello starts the handshake with the client.
+	Hello() error
+	// HandleReply processes a message payload sent by the client.
+	HandleReply(payload string) error
+}
+
+// newPlayer creates the player of a session writing to out.
+func (s *Server) newPlayer(out io.Writer) sessionPlayer {
+	if s.cfg.StreamAudio {
+		return player.NewStreamingPlayer(out, s.cfg.FFmpegPath)
+	}
+	return player.NewRemotePlayer(out)
+}
+
+// login returns the storage of the user behind the key the session
+// authenticated with. Sessions that logged in without a key, or whose user
+// cannot be loaded, run as guests without bookmarks or history.
+func (s *Server) login(sess ssh.Session) *storage.UserStore {
+	fingerprint := verifiedFingerprint(sess)
+	if fingerprint == "" || s.store == nil {
+		return nil
+	}
+
+	users, err := s.store.Login(fingerprint)
+	if err != nil {
+		log.Printf("Session %s: %v", sess.RemoteAddr(), err)
+		return nil
+	}
+
+	// Remember the client's language the first time it tells us
+	if user, err := users.User(); err == nil && user.Language == "" {
+		if locale := localeFromEnv(sess.Environ()); locale != "" {
+			_ = users.SetLanguage(locale)
+		}
+	}
+
+	return users
+}
+
+// localeFromEnv returns the supported locale matching the LC_ALL or LANG
+// variable sent by the client, or "" if there is none.
+func localeFromEnv(environ []string) string {
+	vars := make(map[string]string)
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			vars[name] = value
+		}
+	}
+
+	for _, name := range []string{"LC_ALL", "LANG"} {
+		value := vars[name]
+		for _, locale := range []string{"en", "it"} {
+			if strings.HasPrefix(value, locale) {
+				return locale
+			}
+		}
+	}
+
+	return ""
+}
+
+// syncWriter serializes writes to w.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write implements io.Writer.
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
@@ -2,11 +2,18 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fulgidus/terminal-fm/pkg/fuzzy"
 	"github.com/fulgidus/terminal-fm/pkg/i18n"
+	"github.com/fulgidus/terminal-fm/pkg/query"
 	"github.com/fulgidus/terminal-fm/pkg/services/player"
 	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
 	"github.com/fulgidus/terminal-fm/pkg/services/storage"
@@ -26,45 +33,166 @@ const (
 	ViewHelp
 	// ViewAbout shows application info and credits.
 	ViewAbout
+	// ViewHistory shows recently played stations.
+	ViewHistory
+	// ViewFacets browses stations by country, then tag.
+	ViewFacets
+)
+
+// facetStep is a step of browsing by country and tag.
+type facetStep int
+
+const (
+	// stepCountry picks a country.
+	stepCountry facetStep = iota
+	// stepTag picks a tag.
+	stepTag
+	// stepStations lists the stations matching both.
+	stepStations
+)
+
+// searchField is a field of the search form, or the result list below it.
+type searchField int
+
+const (
+	// fieldQuery is the query; the fields after it up to fieldResults are
+	// the advanced filters.
+	fieldQuery searchField = iota
+	fieldCountryCode
+	fieldLanguage
+	fieldTag
+	fieldCodec
+	fieldBitrateMin
+	fieldBitrateMax
+	fieldOrder
+	fieldReverse
+	fieldHTTPS
+	// fieldResults is the result list.
+	fieldResults
+)
+
+// searchOrders are the orders the search form cycles through.
+var searchOrders = []string{"votes", "clickcount", "clicktrend", "bitrate", "name", "random"}
+
+// historyLimit is the number of recent plays shown in the history view.
+const historyLimit = 100
+
+// Paging of the station list and search results.
+const (
+	// pageSize is how many stations are fetched at a time.
+	pageSize = 50
+	// loadMoreThreshold is how close to the end of a list the cursor gets
+	// before the next page is fetched.
+	loadMoreThreshold = 10
+	// maxBarrenPages is how many pages in a row may add nothing new, e.g.
+	// when filtered out, before paging stops.
+	maxBarrenPages = 5
 )
 
 // Model holds the application state for the TUI.
 type Model struct {
 	// Core dependencies
-	radioClient radiobrowser.Client
-	player      player.Player
-	store       *storage.Store
-	locale      string
-	tr          *i18n.SimpleTranslator
+	radioClient  radiobrowser.Client
+	player       player.Player
+	playerEvents <-chan player.Event
+	store        *storage.UserStore
+	locale       string
+	tr           *i18n.SimpleTranslator
+
+	// Now playing metadata
+	metadataReader *player.MetadataReader
+	metadataCancel context.CancelFunc
+	nowPlaying     string
 
 	// UI state
 	view   ViewState
 	width  int
 	height int
 
+	// columns are the columns of the station table, in order.
+	columns []column
+
 	// Station browsing
-	stations     []radiobrowser.Station
+	stations      []radiobrowser.Station
+	stationsPager pager
+	// browseFilter narrows the stations shown; while it is on, cursor and
+	// scrollOffset are rows of its matches.
+	browseFilter listFilter
+	browseSort   sortKey
 	cursor       int
 	scrollOffset int
 	loading      bool
 	errorMsg     string
 
 	// Search
-	searchInput        textinput.Model
+	searchInput textinput.Model
+	// searchFilters are the text fields of the advanced search form, from
+	// fieldCountryCode on; it is shown, and applies, when searchAdvanced is
+	// set.
+	searchFilters      [fieldOrder - fieldCountryCode]textinput.Model
+	searchOrder        int
+	searchReverse      bool
+	searchHTTPS        bool
+	searchAdvanced     bool
+	searchFocus        searchField
 	searchResults      []radiobrowser.Station
+	searchPager        pager
 	searchCursor       int
 	searchScrollOffset int
 	searching          bool
+	// searchCancel cancels the search in flight, if any; searchSeq numbers
+	// searches so that results of a superseded one are ignored.
+	searchCancel context.CancelFunc
+	searchSeq    int
+	// lastSearch is the search the results are for, as made.
+	lastSearch query.Query
+	// searchErrColumn is where in the query the last search's mistake is,
+	// counting runes from 1, or 0.
+	searchErrColumn int
 
 	// Bookmarks
-	bookmarks             []radiobrowser.Station
+	bookmarks       []radiobrowser.Station
+	bookmarksFilter listFilter
+	// bookmarksSort is the order bookmarks are sorted in, or zero to keep
+	// them as added.
+	bookmarksSort         sortKey
 	bookmarksCursor       int
 	bookmarksScrollOffset int
 	bookmarksLoading      bool
+
+	// Listening history
+	history             []storage.HistoryEntry
+	historyCursor       int
+	historyScrollOffset int
+	historyLoading      bool
+	historyEntryID      int64
+	historyStartedAt    time.Time
+
+	// Browsing by country and tag; each step keeps its own cursor and
+	// scroll offset so going back returns to the same place
+	facetStep     facetStep
+	countries     []radiobrowser.Country
+	tags          []radiobrowser.Tag
+	facetCountry  string
+	facetTag      string
+	facetStations []radiobrowser.Station
+	facetCursors  [3]int
+	facetOffsets  [3]int
+	facetLoading  bool
 }
 
-// NewModel creates a new Model with initial state.
-func NewModel(radioClient radiobrowser.Client, audioPlayer player.Player, store *storage.Store, locale string) Model {
+// NewModel creates a new Model with initial state. The user's saved locale
+// and volume, if any, take precedence over locale and the player's default.
+func NewModel(radioClient radiobrowser.Client, audioPlayer player.Player, store *storage.UserStore, locale string) Model {
+	if store != nil {
+		if user, err := store.User(); err == nil {
+			if user.Language != "" {
+				locale = user.Language
+			}
+			_ = audioPlayer.SetVolume(user.Volume)
+		}
+	}
+
 	// Initialize translator
 	tr := i18n.NewSimpleTranslator(locale)
 
@@ -74,39 +202,97 @@ func NewModel(radioClient radiobrowser.Client, audioPlayer player.Player, store
 	ti.CharLimit = 100
 	ti.Width = 50
 
+	// Initialize the advanced search form
+	var filters [fieldOrder - fieldCountryCode]textinput.Model
+	placeholders := []string{"e.g. IT", "e.g. italian", "e.g. jazz", "e.g. AAC", "kbps", "kbps"}
+	for i := range filters {
+		filters[i] = textinput.New()
+		filters[i].Placeholder = placeholders[i]
+		filters[i].CharLimit = 40
+		filters[i].Width = 20
+	}
+
 	return Model{
-		radioClient:   radioClient,
-		player:        audioPlayer,
-		store:         store,
-		locale:        locale,
-		tr:            tr,
-		view:          ViewBrowse,
-		stations:      []radiobrowser.Station{},
-		cursor:        0,
-		scrollOffset:  0,
-		loading:       true,
-		bookmarks:     []radiobrowser.Station{},
-		searchInput:   ti,
-		searchResults: []radiobrowser.Station{},
+		radioClient:     radioClient,
+		player:          audioPlayer,
+		playerEvents:    audioPlayer.Events(),
+		store:           store,
+		locale:          locale,
+		tr:              tr,
+		metadataReader:  player.NewMetadataReader(),
+		view:            ViewBrowse,
+		stations:        []radiobrowser.Station{},
+		cursor:          0,
+		scrollOffset:    0,
+		loading:         true,
+		bookmarks:       []radiobrowser.Station{},
+		searchInput:     ti,
+		searchFilters:   filters,
+		searchReverse:   true,
+		searchResults:   []radiobrowser.Station{},
+		browseFilter:    newListFilter(),
+		bookmarksFilter: newListFilter(),
+		browseSort:      sortKeys[0],
+		columns:         columns,
+	}
+}
+
+// SetColumns sets the columns of the station table, by name, in order, as
+// configured in config.UIConfig.
+func (m *Model) SetColumns(names []string) error {
+	cols, err := parseColumns(names)
+	if err != nil {
+		return err
+	}
+	m.columns = cols
+	return nil
+}
+
+// SetStreamTitles turns on or off showing the title playing, read from
+// the station's stream. It is on by default. Reading titles takes a
+// connection to the stream of its own, besides the player's.
+func (m *Model) SetStreamTitles(enabled bool) {
+	if !enabled {
+		m.metadataReader = nil
+	} else if m.metadataReader == nil {
+		m.metadataReader = player.NewMetadataReader()
 	}
 }
 
 // Init initializes the model (required by Bubbletea).
 func (m Model) Init() tea.Cmd {
 	// Load stations on startup
-	return m.loadStations
+	return tea.Batch(m.loadStations(0), waitForPlayerEvent(m.playerEvents))
 }
 
-// loadStations is a command that fetches stations from the API.
-func (m Model) loadStations() tea.Msg {
-	stations, err := m.radioClient.Search(radiobrowser.SearchParams{
-		Limit: 50,
-		Order: "votes",
-	})
-	if err != nil {
-		return errMsg{err}
+// loadStations returns a command that fetches the page of stations from
+// the API starting at offset, in the order browsed in.
+func (m Model) loadStations(offset int) tea.Cmd {
+	client, sort := m.radioClient, m.browseSort
+	return func() tea.Msg {
+		stations, err := client.Search(radiobrowser.SearchParams{
+			Limit:   pageSize,
+			Offset:  offset,
+			Order:   sort.order,
+			Reverse: sort.reverse,
+		})
+		if err != nil {
+			return errMsg{err}
+		}
+		return stationsLoadedMsg{stations: stations, offset: offset, sort: sort}
+	}
+}
+
+// loadMoreStations returns a command that fetches the next page of
+// stations, if the cursor is near the end of those loaded.
+func (m *Model) loadMoreStations() tea.Cmd {
+	// The filter narrows the stations loaded; it does not fetch more
+	if m.loading || m.browseFilter.on() || !m.stationsPager.due(m.cursor, len(m.stations)) {
+		return nil
 	}
-	return stationsLoadedMsg{stations}
+
+	m.stationsPager.loading = true
+	return m.loadStations(m.stationsPager.next)
 }
 
 // loadBookmarks is a command that loads bookmarks from storage.
@@ -122,65 +308,290 @@ func (m Model) loadBookmarks() tea.Msg {
 	return bookmarksLoadedMsg{bookmarks}
 }
 
-// performSearch executes a search query.
-func (m Model) performSearch(query string) tea.Msg {
-	if query == "" {
-		return searchResultsMsg{[]radiobrowser.Station{}}
+// loadHistory is a command that loads recent plays from storage.
+func (m Model) loadHistory() tea.Msg {
+	if m.store == nil {
+		return errMsg{fmt.Errorf("storage not available")}
 	}
 
-	// Search by name first (most common use case)
-	params := radiobrowser.SearchParams{
-		Name:  query,
-		Limit: 100, // Get more results for better filtering
-		Order: "votes",
+	history, err := m.store.GetHistory(historyLimit)
+	if err != nil {
+		return errMsg{err}
 	}
+	return historyLoadedMsg{history}
+}
 
-	stations, err := m.radioClient.Search(params)
+// loadCountries is a command that lists the countries to browse by.
+func (m Model) loadCountries() tea.Msg {
+	countries, err := m.radioClient.ListCountries(context.Background())
 	if err != nil {
 		return errMsg{err}
 	}
+	return countriesLoadedMsg{countries}
+}
 
-	// If we got results, return them
-	if len(stations) > 0 {
-		// Limit to 50 best results
-		if len(stations) > 50 {
-			stations = stations[:50]
+// loadTags is a command that lists the tags to browse by.
+func (m Model) loadTags() tea.Msg {
+	tags, err := m.radioClient.ListTags(context.Background())
+	if err != nil {
+		return errMsg{err}
+	}
+	return tagsLoadedMsg{tags}
+}
+
+// loadFacetStations returns a command that fetches the stations in country
+// tagged with tag. Empty values match any.
+func (m Model) loadFacetStations(country, tag string) tea.Cmd {
+	client := m.radioClient
+	return func() tea.Msg {
+		stations, err := client.SearchContext(context.Background(), radiobrowser.SearchParams{
+			Country: country,
+			Tag:     tag,
+			Limit:   100,
+			Order:   "votes",
+			Reverse: true,
+		})
+		if err != nil {
+			return errMsg{err}
 		}
-		return searchResultsMsg{stations}
+		return facetStationsLoadedMsg{country: country, tag: tag, stations: stations}
 	}
+}
 
-	// No results by name, try by country
-	params = radiobrowser.SearchParams{
-		Country: query,
-		Limit:   50,
-		Order:   "votes",
+// startSearch cancels the search in flight, if any, and returns a command
+// that runs q.
+func (m *Model) startSearch(q query.Query) tea.Cmd {
+	m.searchSeq++
+	m.searchPager = pager{}
+	return m.fetchSearch(q)
+}
+
+// loadMoreSearchResults returns a command that fetches the next page of
+// search results, if the cursor is near the end of those loaded.
+func (m *Model) loadMoreSearchResults() tea.Cmd {
+	if m.searching || !m.searchPager.due(m.searchCursor, len(m.searchResults)) {
+		return nil
+	}
+
+	m.searchPager.loading = true
+	q := m.lastSearch
+	q.Params.Offset = m.searchPager.next
+	return m.fetchSearch(q)
+}
+
+// fetchSearch cancels the search in flight, if any, and returns a command
+// that runs q as part of the current search.
+func (m *Model) fetchSearch(q query.Query) tea.Cmd {
+	m.cancelSearch()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.searchCancel = cancel
+
+	client, seq := m.radioClient, m.searchSeq
+	return func() tea.Msg {
+		defer cancel()
+
+		msg := performSearch(ctx, client, q)
+		if ctx.Err() != nil {
+			// Superseded by a newer search, or abandoned
+			return nil
+		}
+		if results, ok := msg.(searchResultsMsg); ok {
+			results.seq = seq
+			return results
+		}
+		return msg
 	}
+}
 
-	stations, err = m.radioClient.Search(params)
+// cancelSearch cancels the search in flight, if any.
+func (m *Model) cancelSearch() {
+	if m.searchCancel != nil {
+		m.searchCancel()
+		m.searchCancel = nil
+	}
+}
+
+// searchQuery returns the search the search form describes: the query
+// typed, with the advanced filters filling in what it leaves out.
+func (m Model) searchQuery() (query.Query, error) {
+	params, err := m.searchFormParams()
 	if err != nil {
-		return errMsg{err}
+		return query.Query{}, err
 	}
 
-	// If still no results, try by tag
-	if len(stations) == 0 {
-		params = radiobrowser.SearchParams{
-			Tag:   query,
-			Limit: 50,
-			Order: "votes",
+	q, err := query.Parse(m.searchInput.Value(), params)
+	if err != nil {
+		return query.Query{}, err
+	}
+	if len(q.Filters) > 0 {
+		// Fetch more, as some results will be filtered out
+		q.Params.Limit = 100
+	}
+	return q, nil
+}
+
+// searchFormParams returns the search the advanced filters describe.
+func (m Model) searchFormParams() (radiobrowser.SearchParams, error) {
+	params := radiobrowser.SearchParams{
+		Limit:   50,
+		Order:   "votes",
+		Reverse: true,
+	}
+	if !m.searchAdvanced {
+		return params, nil
+	}
+
+	filter := func(field searchField) string {
+		return strings.TrimSpace(m.searchFilters[field-fieldCountryCode].Value())
+	}
+	bitrate := func(field searchField, name string) (int, error) {
+		value := filter(field)
+		if value == "" {
+			return 0, nil
+		}
+		kbps, err := strconv.Atoi(value)
+		if err != nil || kbps < 0 {
+			return 0, fmt.Errorf("%s bitrate must be a number of kbps, not %q", name, value)
 		}
+		return kbps, nil
+	}
+
+	var err error
+	if params.BitrateMin, err = bitrate(fieldBitrateMin, "minimum"); err != nil {
+		return params, err
+	}
+	if params.BitrateMax, err = bitrate(fieldBitrateMax, "maximum"); err != nil {
+		return params, err
+	}
+	if params.BitrateMax > 0 && params.BitrateMin > params.BitrateMax {
+		return params, fmt.Errorf("minimum bitrate is above the maximum")
+	}
+
+	params.CountryCode = strings.ToUpper(filter(fieldCountryCode))
+	params.Language = filter(fieldLanguage)
+	params.Tag = filter(fieldTag)
+	params.Codec = filter(fieldCodec)
+	params.HTTPSOnly = m.searchHTTPS
+	params.Order = searchOrders[m.searchOrder]
+	params.Reverse = m.searchReverse
+	return params, nil
+}
+
+// nameOnly reports whether params search by nothing but a name.
+func nameOnly(params radiobrowser.SearchParams) bool {
+	unfiltered := radiobrowser.SearchParams{
+		Name:    params.Name,
+		Limit:   params.Limit,
+		Offset:  params.Offset,
+		Order:   params.Order,
+		Reverse: params.Reverse,
+	}
+	return params == unfiltered
+}
+
+// performSearch executes a search. A bare query is guessed at: it is tried
+// as a name, then a country, then a tag. Later pages use whichever found
+// stations.
+func performSearch(ctx context.Context, client radiobrowser.Client, q query.Query) tea.Msg {
+	params := q.Params
+	if params.Name == "" && nameOnly(params) && len(q.Filters) == 0 {
+		return searchResultsMsg{results: []radiobrowser.Station{}, query: q}
+	}
 
-		stations, err = m.radioClient.Search(params)
+	attempts := []radiobrowser.SearchParams{params}
+	if params.Name != "" && nameOnly(params) && params.Offset == 0 {
+		byCountry, byTag := params, params
+		byCountry.Name, byCountry.Country = "", params.Name
+		byTag.Name, byTag.Tag = "", params.Name
+		attempts = append(attempts, byCountry, byTag)
+	}
+
+	var msg searchResultsMsg
+	for _, attempt := range attempts {
+		found, err := client.SearchContext(ctx, attempt)
 		if err != nil {
 			return errMsg{err}
 		}
+
+		q.Params = attempt
+		msg = searchResultsMsg{results: q.Apply(found), query: q, fetched: len(found)}
+		if len(msg.results) > 0 {
+			break
+		}
+	}
+
+	return msg
+}
+
+// watchMetadata starts reading stream metadata for station, replacing any
+// previous watcher, and returns a command that delivers the first update.
+func (m *Model) watchMetadata(station *radiobrowser.Station) tea.Cmd {
+	m.stopMetadata()
+
+	if m.metadataReader == nil || station == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.metadataCancel = cancel
+
+	reader := m.metadataReader
+	stationUUID := station.StationUUID
+
+	return func() tea.Msg {
+		updates, err := reader.Watch(ctx, station)
+		if err != nil {
+			// Many streams carry no metadata; the station name is enough
+			return nil
+		}
+		return waitForMetadata(stationUUID, updates)()
+	}
+}
+
+// stopMetadata cancels the current metadata watcher, if any.
+func (m *Model) stopMetadata() {
+	if m.metadataCancel != nil {
+		m.metadataCancel()
+		m.metadataCancel = nil
 	}
+	m.nowPlaying = ""
+}
 
-	return searchResultsMsg{stations}
+// waitForMetadata returns a command that waits for the next metadata update.
+func waitForMetadata(stationUUID string, updates <-chan player.Metadata) tea.Cmd {
+	return func() tea.Msg {
+		md, ok := <-updates
+		if !ok {
+			return nil
+		}
+		return metadataMsg{stationUUID: stationUUID, metadata: md, updates: updates}
+	}
+}
+
+// waitForPlayerEvent returns a command that waits for the next player
+// state change, so the status bar updates without a key press.
+func waitForPlayerEvent(events <-chan player.Event) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-events
+		if !ok {
+			return nil
+		}
+		return playerEventMsg{event}
+	}
 }
 
 // Message types for async operations.
 type stationsLoadedMsg struct {
 	stations []radiobrowser.Station
+	// offset is where in the list the page starts, and sort the order
+	// of the list.
+	offset int
+	sort   sortKey
+}
+
+type playerEventMsg struct {
+	event player.Event
 }
 
 type bookmarksLoadedMsg struct {
@@ -195,8 +606,39 @@ type bookmarkRemovedMsg struct {
 	stationUUID string
 }
 
+type historyLoadedMsg struct {
+	history []storage.HistoryEntry
+}
+
+type countriesLoadedMsg struct {
+	countries []radiobrowser.Country
+}
+
+type tagsLoadedMsg struct {
+	tags []radiobrowser.Tag
+}
+
+type facetStationsLoadedMsg struct {
+	// country and tag are the facets the stations were fetched for.
+	country  string
+	tag      string
+	stations []radiobrowser.Station
+}
+
 type searchResultsMsg struct {
 	results []radiobrowser.Station
+	// seq is the number of the search the results are for.
+	seq int
+	// query is the search made, and fetched how many stations it
+	// returned before filtering.
+	query   query.Query
+	fetched int
+}
+
+type metadataMsg struct {
+	stationUUID string
+	metadata    player.Metadata
+	updates     <-chan player.Metadata
 }
 
 type errMsg struct {
@@ -209,6 +651,217 @@ func (e errMsg) Error() string {
 
 // Helper methods for list navigation.
 
+// pager tracks fetching a list page by page. The zero value has nothing
+// more to fetch.
+type pager struct {
+	// next is the offset of the next page.
+	next int
+	// more is set while there may be more pages.
+	more bool
+	// loading is set while a page is being fetched.
+	loading bool
+	// barren counts the pages in a row that added no stations.
+	barren int
+}
+
+// due reports whether to fetch the next page of a list of n entries with
+// the cursor at cursor.
+func (p pager) due(cursor, n int) bool {
+	return p.more && !p.loading && n-1-cursor < loadMoreThreshold
+}
+
+// loaded records a page of fetched stations, starting at offset and asked
+// for with limit, that added added stations to the list.
+func (p *pager) loaded(offset, fetched, limit, added int) {
+	p.loading = false
+	p.next = offset + fetched
+	p.more = fetched >= limit
+
+	if added > 0 {
+		p.barren = 0
+	} else if p.barren++; p.barren >= maxBarrenPages {
+		p.more = false
+	}
+}
+
+// appendNew appends the stations in page not already in list.
+func appendNew(list, page []radiobrowser.Station) []radiobrowser.Station {
+	seen := make(map[string]bool, len(list))
+	for _, station := range list {
+		seen[station.StationUUID] = true
+	}
+
+	for _, station := range page {
+		if !seen[station.StationUUID] {
+			seen[station.StationUUID] = true
+			list = append(list, station)
+		}
+	}
+	return list
+}
+
+// listFilter narrows a list of stations to those fuzzy matching a query on
+// their name, tags or country, best matches first.
+type listFilter struct {
+	input textinput.Model
+	// typing is set while keys go to input.
+	typing bool
+	// matches are the stations matching, best first, while the filter is on.
+	matches []stationMatch
+}
+
+// stationMatch is a station matching a filter.
+type stationMatch struct {
+	// index is where the station is in the list filtered.
+	index int
+	score int
+	// name, tags and country are the runes of each field that matched.
+	name, tags, country []int
+}
+
+// newListFilter creates a filter that is off.
+func newListFilter() listFilter {
+	input := textinput.New()
+	input.Prompt = "Filter: "
+	input.Placeholder = "name, tag or country"
+	input.CharLimit = 50
+	input.Width = 30
+	return listFilter{input: input}
+}
+
+// on reports whether the filter narrows the list.
+func (f listFilter) on() bool {
+	return strings.TrimSpace(f.input.Value()) != ""
+}
+
+// len returns the number of rows shown of a list of n stations.
+func (f listFilter) len(n int) int {
+	if !f.on() {
+		return n
+	}
+	return len(f.matches)
+}
+
+// index returns where in the list the station shown in row is.
+func (f listFilter) index(row int) int {
+	if !f.on() {
+		return row
+	}
+	return f.matches[row].index
+}
+
+// match returns how the station shown in row matched, or nil.
+func (f listFilter) match(row int) *stationMatch {
+	if !f.on() || row >= len(f.matches) {
+		return nil
+	}
+	return &f.matches[row]
+}
+
+// update passes a key typed into the filter to its input, reporting whether
+// the query changed.
+func (f *listFilter) update(msg tea.KeyMsg) (bool, tea.Cmd) {
+	before := f.input.Value()
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	return f.input.Value() != before, cmd
+}
+
+// stopTyping keeps the filter on but sends keys back to the list.
+func (f *listFilter) stopTyping() {
+	f.typing = false
+	f.input.Blur()
+}
+
+// clear turns the filter off.
+func (f *listFilter) clear() {
+	f.stopTyping()
+	f.input.SetValue("")
+	f.matches = nil
+}
+
+// apply matches the stations against the query. Each word of the query
+// must match the name, tags or country; stations score the sum of their
+// best match for each word.
+func (f *listFilter) apply(stations []radiobrowser.Station) {
+	f.matches = nil
+	words := strings.Fields(f.input.Value())
+	if len(words) == 0 {
+		return
+	}
+
+	f.matches = []stationMatch{}
+	for i, station := range stations {
+		if match, ok := matchStation(words, station); ok {
+			match.index = i
+			f.matches = append(f.matches, match)
+		}
+	}
+
+	sort.SliceStable(f.matches, func(i, j int) bool {
+		return f.matches[i].score > f.matches[j].score
+	})
+}
+
+// matchStation matches each word against the station's name, tags and
+// country, reporting false if a word matches none of them.
+func matchStation(words []string, station radiobrowser.Station) (stationMatch, bool) {
+	var match stationMatch
+	fields := []struct {
+		text string
+		hits *[]int
+	}{
+		{station.Name, &match.name},
+		{station.Tags, &match.tags},
+		{station.Country, &match.country},
+	}
+
+	for _, word := range words {
+		best := -1
+		var score int
+		var positions []int
+		for i, field := range fields {
+			// Ties go to the earlier field
+			if s, found, ok := fuzzy.Match(word, field.text); ok && (best < 0 || s > score) {
+				best, score, positions = i, s, found
+			}
+		}
+		if best < 0 {
+			return stationMatch{}, false
+		}
+
+		match.score += score
+		*fields[best].hits = mergePositions(*fields[best].hits, positions)
+	}
+	return match, true
+}
+
+// row returns the row showing the station with uuid, if any.
+func (f listFilter) row(stations []radiobrowser.Station, uuid string) (int, bool) {
+	for row := 0; row < f.len(len(stations)); row++ {
+		if stations[f.index(row)].StationUUID == uuid {
+			return row, true
+		}
+	}
+	return 0, false
+}
+
+// mergePositions returns the sorted union of two sorted lists of positions.
+func mergePositions(a, b []int) []int {
+	merged := make([]int, 0, len(a)+len(b))
+	for len(a) > 0 || len(b) > 0 {
+		switch {
+		case len(b) == 0 || len(a) > 0 && a[0] < b[0]:
+			merged, a = append(merged, a[0]), a[1:]
+		case len(a) == 0 || b[0] < a[0]:
+			merged, b = append(merged, b[0]), b[1:]
+		default:
+			merged, a, b = append(merged, a[0]), a[1:], b[1:]
+		}
+	}
+	return merged
+}
+
 // VisibleStations returns the number of stations that fit on screen.
 func (m Model) VisibleStations() int {
 	// Reserve space for:
@@ -216,7 +869,7 @@ func (m Model) VisibleStations() int {
 	// - Status bar with top/bottom borders (3 lines)
 	// - Status bar margin bottom (1 line)
 	// - Header info (1 line)
-	// - Spacing before list (1 line)
+	// - Column titles (1 line)
 	// - Spacing after list (1 line)
 	// - Footer with border (2 lines)
 	reserved := 10
@@ -241,7 +894,7 @@ func (m Model) CanScrollUp() bool {
 // CanScrollDown returns true if we can scroll down.
 func (m Model) CanScrollDown() bool {
 	visible := m.VisibleStations()
-	return m.scrollOffset+visible < len(m.stations)
+	return m.scrollOffset+visible < m.browseLen()
 }
 
 // UpdateScroll adjusts scrollOffset based on cursor position.
@@ -259,32 +912,83 @@ func (m *Model) UpdateScroll() {
 	}
 }
 
+// browseLen returns the number of stations shown in the browse view.
+func (m Model) browseLen() int {
+	return m.browseFilter.len(len(m.stations))
+}
+
+// bookmarksLen returns the number of bookmarks shown.
+func (m Model) bookmarksLen() int {
+	return m.bookmarksFilter.len(len(m.bookmarks))
+}
+
+// selectedBookmark returns the currently selected bookmark, or nil.
+func (m Model) selectedBookmark() *radiobrowser.Station {
+	if m.bookmarksCursor < 0 || m.bookmarksCursor >= m.bookmarksLen() {
+		return nil
+	}
+	return &m.bookmarks[m.bookmarksFilter.index(m.bookmarksCursor)]
+}
+
+// typingFilter reports whether keys go to the current view's filter.
+func (m Model) typingFilter() bool {
+	return m.view == ViewBrowse && m.browseFilter.typing ||
+		m.view == ViewBookmarks && m.bookmarksFilter.typing
+}
+
 // SelectedStation returns the currently selected station, or nil.
 func (m Model) SelectedStation() *radiobrowser.Station {
-	if len(m.stations) == 0 || m.cursor < 0 || m.cursor >= len(m.stations) {
+	if m.cursor < 0 || m.cursor >= m.browseLen() {
 		return nil
 	}
-	return &m.stations[m.cursor]
+	return &m.stations[m.browseFilter.index(m.cursor)]
 }
 
 // VisibleStationList returns the slice of stations currently visible.
 func (m Model) VisibleStationList() []radiobrowser.Station {
-	if len(m.stations) == 0 {
+	n := m.browseLen()
+	if n == 0 {
 		return []radiobrowser.Station{}
 	}
 
 	visible := m.VisibleStations()
 	end := m.scrollOffset + visible
-	if end > len(m.stations) {
-		end = len(m.stations)
+	if end > n {
+		end = n
 	}
 
-	return m.stations[m.scrollOffset:end]
+	if !m.browseFilter.on() {
+		return m.stations[m.scrollOffset:end]
+	}
+	stations := make([]radiobrowser.Station, 0, end-m.scrollOffset)
+	for row := m.scrollOffset; row < end; row++ {
+		stations = append(stations, m.stations[m.browseFilter.index(row)])
+	}
+	return stations
+}
+
+// facetLen returns the number of entries in the current facet step,
+// including the entry matching any country or tag.
+func (m Model) facetLen() int {
+	switch m.facetStep {
+	case stepCountry:
+		return len(m.countries) + 1
+	case stepTag:
+		return len(m.tags) + 1
+	default:
+		return len(m.facetStations)
+	}
 }
 
 // Cleanup stops playback and cleans up resources.
 func (m *Model) Cleanup() {
+	m.stopMetadata()
+	m.cancelSearch()
+	m.finishHistoryEntry()
+
 	if m.player != nil {
+		// Ends waitForPlayerEvent and lets go of the player
+		m.player.Unsubscribe(m.playerEvents)
 		_ = m.player.Stop()
 		// If player implements Cleanup interface, call it
 		if cleaner, ok := m.player.(interface{ Cleanup() error }); ok {
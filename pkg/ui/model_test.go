@@ -0,0 +1,689 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fulgidus/terminal-fm/pkg/query"
+	"github.com/fulgidus/terminal-fm/pkg/services/player"
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+)
+
+func TestSearchCancelsPreviousSearch(t *testing.T) {
+	cancelled := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "slow" {
+			select {
+			case <-time.After(10 * time.Second):
+			case <-r.Context().Done():
+				cancelled <- name
+				return
+			}
+		}
+		fmt.Fprintf(w, `[{"stationuuid": %q, "name": %q, "url_resolved": "http://x", "lastcheckok": 1}]`, name, name)
+	}))
+	defer srv.Close()
+
+	m := NewModel(radiobrowser.NewAPIClientForServer(srv.URL), player.NewRemotePlayer(nil), nil, "en")
+
+	slow := m.startSearch(query.Query{Params: radiobrowser.SearchParams{Name: "slow"}})
+	slowResult := make(chan tea.Msg, 1)
+	go func() { slowResult <- slow() }()
+
+	// Give the slow search time to reach the server
+	time.Sleep(100 * time.Millisecond)
+
+	fast := m.startSearch(query.Query{Params: radiobrowser.SearchParams{Name: "fast"}})
+	msg := fast()
+
+	select {
+	case name := <-cancelled:
+		if name != "slow" {
+			t.Errorf("Expected the slow search to be cancelled, got %q", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the server to see the slow search cancelled")
+	}
+
+	select {
+	case old := <-slowResult:
+		if old != nil {
+			t.Errorf("Expected no message from the cancelled search, got %#v", old)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the cancelled search to return")
+	}
+
+	results, ok := msg.(searchResultsMsg)
+	if !ok || len(results.results) != 1 || results.results[0].Name != "fast" {
+		t.Fatalf("Expected results for the fast search, got %#v", msg)
+	}
+
+	updated, _ := m.Update(results)
+	if got := updated.(Model).searchResults; len(got) != 1 || got[0].Name != "fast" {
+		t.Errorf("Expected the fast results to be shown, got %+v", got)
+	}
+
+	// Results that arrive for a replaced search are ignored
+	stale := searchResultsMsg{results: []radiobrowser.Station{{Name: "stale"}}, seq: results.seq - 1}
+	updated, _ = updated.Update(stale)
+	if got := updated.(Model).searchResults; len(got) != 1 || got[0].Name != "fast" {
+		t.Errorf("Expected stale results to be ignored, got %+v", got)
+	}
+}
+
+// press sends key to m, running the commands that follow, if any, and
+// feeding their messages back.
+func press(t *testing.T, m Model, key tea.KeyMsg) Model {
+	t.Helper()
+
+	updated, cmd := m.Update(key)
+	m = updated.(Model)
+	for cmd != nil {
+		msg := cmd()
+		if msg == nil {
+			break
+		}
+		updated, cmd = m.Update(msg)
+		m = updated.(Model)
+	}
+	return m
+}
+
+func TestCleanupReleasesPlayerEvents(t *testing.T) {
+	m := NewModel(radiobrowser.NewMockClient(), player.NewRemotePlayer(nil), nil, "en")
+	wait := waitForPlayerEvent(m.playerEvents)
+
+	done := make(chan tea.Msg, 1)
+	go func() { done <- wait() }()
+	m.Cleanup()
+
+	select {
+	case msg := <-done:
+		if msg != nil {
+			t.Errorf("Expected no message once cleaned up, got %#v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected waiting for player events to end on cleanup")
+	}
+}
+
+func TestStreamTitlesCanBeTurnedOff(t *testing.T) {
+	var opened atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opened.Add(1)
+		http.Error(w, "no metadata here", http.StatusNotFound)
+	}))
+	defer srv.Close()
+	station := &radiobrowser.Station{StationUUID: "jazz", Name: "Jazz", URLResolved: srv.URL}
+
+	m := NewModel(radiobrowser.NewMockClient(), player.NewRemotePlayer(nil), nil, "en")
+	defer m.Cleanup()
+
+	// Titles are read by default, over a connection of their own
+	cmd := m.watchMetadata(station)
+	if cmd == nil {
+		t.Fatalf("Expected stream titles to be read by default")
+	}
+	cmd()
+	if opened.Load() != 1 {
+		t.Errorf("Expected the stream to be opened for its titles, got %d connections", opened.Load())
+	}
+
+	m.SetStreamTitles(false)
+	if cmd := m.watchMetadata(station); cmd != nil {
+		cmd()
+		t.Errorf("Expected no stream to be opened with titles turned off, got %d connections", opened.Load())
+	}
+}
+
+func TestHistoryPageDownWhenEmpty(t *testing.T) {
+	m := NewModel(radiobrowser.NewMockClient(), player.NewRemotePlayer(nil), nil, "en")
+	m.width, m.height = 80, 30
+	m.view = ViewHistory
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyPgDown})
+	m = updated.(Model)
+	if m.historyCursor != 0 {
+		t.Errorf("Expected the cursor to stay at 0, got %d", m.historyCursor)
+	}
+	if !strings.Contains(m.View(), "Nothing played yet") {
+		t.Errorf("Expected the empty history to render")
+	}
+}
+
+func TestBrowseByCountryAndTag(t *testing.T) {
+	m := NewModel(radiobrowser.NewMockClient(), player.NewRemotePlayer(nil), nil, "en")
+	m.width, m.height = 80, 30
+
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+	down := tea.KeyMsg{Type: tea.KeyDown}
+	esc := tea.KeyMsg{Type: tea.KeyEsc}
+
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	if m.view != ViewFacets || m.facetStep != stepCountry || len(m.countries) == 0 {
+		t.Fatalf("Expected the country list, got view %d step %d with %d countries", m.view, m.facetStep, len(m.countries))
+	}
+
+	// The first entry is any country; move to Italy
+	for m.facetCursors[stepCountry] == 0 || m.countries[m.facetCursors[stepCountry]-1].Name != "Italy" {
+		before := m.facetCursors[stepCountry]
+		m = press(t, m, down)
+		if m.facetCursors[stepCountry] == before {
+			t.Fatalf("Expected Italy among the countries, got %+v", m.countries)
+		}
+	}
+
+	m = press(t, m, enter)
+	if m.facetStep != stepTag || m.facetCountry != "Italy" || len(m.tags) == 0 {
+		t.Fatalf("Expected the tag list for Italy, got step %d for %q with %d tags", m.facetStep, m.facetCountry, len(m.tags))
+	}
+	if view := m.View(); !strings.Contains(view, "Italy › pick a tag") || !strings.Contains(view, "All tags") {
+		t.Errorf("Expected the tag step to be rendered, got:\n%s", view)
+	}
+
+	// Every tag, in Italy
+	m = press(t, m, enter)
+	if m.facetStep != stepStations || m.facetLoading {
+		t.Fatalf("Expected the station list, got step %d", m.facetStep)
+	}
+	if len(m.facetStations) != 1 || m.facetStations[0].Country != "Italy" {
+		t.Errorf("Expected the Italian station, got %+v", m.facetStations)
+	}
+
+	// Going back keeps each step's cursor
+	m = press(t, m, esc)
+	m = press(t, m, esc)
+	if m.facetStep != stepCountry || m.countries[m.facetCursors[stepCountry]-1].Name != "Italy" {
+		t.Errorf("Expected to be back on Italy, got step %d cursor %d", m.facetStep, m.facetCursors[stepCountry])
+	}
+	m = press(t, m, esc)
+	if m.view != ViewBrowse {
+		t.Errorf("Expected to be back browsing, got view %d", m.view)
+	}
+
+	// Stations for a tag no longer picked are ignored
+	stale := facetStationsLoadedMsg{country: "Austria", stations: []radiobrowser.Station{{Name: "stale"}}}
+	updated, _ := m.Update(stale)
+	if got := updated.(Model).facetStations; len(got) != 1 || got[0].Name == "stale" {
+		t.Errorf("Expected stale stations to be ignored, got %+v", got)
+	}
+}
+
+// recordingClient is a mock client remembering the searches made.
+type recordingClient struct {
+	*radiobrowser.MockClient
+	searches []radiobrowser.SearchParams
+}
+
+func (c *recordingClient) Search(params radiobrowser.SearchParams) ([]radiobrowser.Station, error) {
+	return c.SearchContext(context.Background(), params)
+}
+
+func (c *recordingClient) SearchContext(ctx context.Context, params radiobrowser.SearchParams) ([]radiobrowser.Station, error) {
+	c.searches = append(c.searches, params)
+	return c.MockClient.SearchContext(ctx, params)
+}
+
+// typeText sends text to m one key at a time. The commands returned only
+// make the cursor blink, so they are dropped.
+func typeText(m Model, text string) Model {
+	for _, r := range text {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = updated.(Model)
+	}
+	return m
+}
+
+func TestAdvancedSearchForm(t *testing.T) {
+	client := &recordingClient{MockClient: radiobrowser.NewMockClient()}
+	m := NewModel(client, player.NewRemotePlayer(nil), nil, "en")
+	m.width, m.height = 100, 40
+
+	tab := tea.KeyMsg{Type: tea.KeyTab}
+	down := tea.KeyMsg{Type: tea.KeyDown}
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyCtrlT})
+	if !m.searchAdvanced || !strings.Contains(m.View(), "Min bitrate") {
+		t.Fatalf("Expected the advanced filters to be shown")
+	}
+
+	m = press(t, m, tab)
+	m = typeText(m, "it")
+	m = press(t, m, down)
+	m = press(t, m, down)
+	m = press(t, m, down)
+	m = typeText(m, "aac")
+	m = press(t, m, down)
+	m = typeText(m, "96")
+	m = press(t, m, down)
+	m = typeText(m, "256")
+	m = press(t, m, down)
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyRight}) // clickcount
+	m = press(t, m, down)
+	m = press(t, m, space) // ascending
+	m = press(t, m, down)
+	m = press(t, m, space) // HTTPS only
+	m = press(t, m, enter)
+
+	expected := radiobrowser.SearchParams{
+		CountryCode: "IT",
+		Codec:       "aac",
+		BitrateMin:  96,
+		BitrateMax:  256,
+		HTTPSOnly:   true,
+		Limit:       50,
+		Order:       "clickcount",
+	}
+	if len(client.searches) != 1 || client.searches[0] != expected {
+		t.Fatalf("Expected a search for %+v, got %+v", expected, client.searches)
+	}
+	if len(m.searchResults) != 1 || m.searchResults[0].CountryCode != "IT" {
+		t.Errorf("Expected the Italian station, got %+v", m.searchResults)
+	}
+
+	// Invalid numbers are reported instead of searched for
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyShiftTab})
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyShiftTab})
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyShiftTab})
+	m = typeText(m, "x")
+	m = press(t, m, enter)
+	if len(client.searches) != 1 || !strings.Contains(m.errorMsg, `maximum bitrate must be a number of kbps, not "256x"`) {
+		t.Errorf("Expected an error for the maximum bitrate, got %q after %d searches", m.errorMsg, len(client.searches))
+	}
+
+	// Without the filters a bare query is tried as a name, country and tag
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyCtrlT})
+	m = typeText(m, "austria")
+	m = press(t, m, enter)
+	if len(client.searches) != 3 || client.searches[2].Country != "austria" || !client.searches[2].Reverse {
+		t.Errorf("Expected a name then a country search, got %+v", client.searches[1:])
+	}
+	if len(m.searchResults) != 1 || m.searchResults[0].Country != "Austria" {
+		t.Errorf("Expected the Austrian station, got %+v", m.searchResults)
+	}
+}
+
+func TestSearchQueryLanguage(t *testing.T) {
+	client := &recordingClient{MockClient: radiobrowser.NewMockClient()}
+	m := NewModel(client, player.NewRemotePlayer(nil), nil, "en")
+	m.width, m.height = 100, 40
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+
+	// Mistakes are shown under the query, and nothing is searched
+	m = typeText(m, "jazz tag>smooth")
+	m = press(t, m, enter)
+	if len(client.searches) != 0 || m.searchErrColumn != 6 {
+		t.Fatalf("Expected a mistake at column 6 and no search, got column %d after %d searches", m.searchErrColumn, len(client.searches))
+	}
+	view := m.View()
+	if !strings.Contains(view, "column 6: tag cannot be compared with >") || !strings.Contains(view, "\n"+strings.Repeat(" ", 7)+"^") {
+		t.Errorf("Expected the mistake to be marked, got:\n%s", view)
+	}
+
+	// Fields become parameters, and the rest filter the results
+	m.searchInput.SetValue("lang:german -tag:techno bitrate>=128 sort:clicks")
+	m = press(t, m, enter)
+	if m.searchErrColumn != 0 || m.errorMsg != "" {
+		t.Errorf("Expected the mistake to be cleared, got %q", m.errorMsg)
+	}
+
+	expected := radiobrowser.SearchParams{Language: "german", BitrateMin: 128, Limit: 100, Order: "clickcount", Reverse: true}
+	if len(client.searches) != 1 || client.searches[0] != expected {
+		t.Fatalf("Expected a search for %+v, got %+v", expected, client.searches)
+	}
+	if len(m.searchResults) != 1 || m.searchResults[0].Country != "Austria" {
+		t.Errorf("Expected only the Austrian station, got %+v", m.searchResults)
+	}
+}
+
+func TestBrowseLoadsMorePages(t *testing.T) {
+	client := &recordingClient{MockClient: radiobrowser.NewGeneratedMockClient(1000)}
+	m := NewModel(client, player.NewRemotePlayer(nil), nil, "en")
+	m.width, m.height = 80, 30
+	end := tea.KeyMsg{Type: tea.KeyEnd}
+
+	updated, _ := m.Update(m.loadStations(0)())
+	m = updated.(Model)
+	if len(m.stations) != pageSize || !m.stationsPager.more {
+		t.Fatalf("Expected a first page of %d stations, got %d", pageSize, len(m.stations))
+	}
+
+	// Near the end, the next page is fetched and shown as loading
+	updated, cmd := m.Update(end)
+	loading := updated.(Model)
+	if cmd == nil || !strings.Contains(loading.View(), "Loading more stations...") {
+		t.Fatalf("Expected the next page to be loading")
+	}
+
+	for i := 0; m.stationsPager.more; i++ {
+		if i == 100 {
+			t.Fatalf("Expected paging to stop, got %d stations", len(m.stations))
+		}
+		m = press(t, m, end)
+	}
+
+	// 5 sample and 1000 generated stations, 50 at a time
+	if len(m.stations) != 1005 || len(client.searches) != 21 {
+		t.Errorf("Expected 1005 stations in 21 pages, got %d in %d", len(m.stations), len(client.searches))
+	}
+	seen := make(map[string]bool)
+	for i, station := range m.stations {
+		if seen[station.StationUUID] {
+			t.Fatalf("Expected unique stations, got %s twice", station.Name)
+		}
+		seen[station.StationUUID] = true
+		if i > 0 && client.searches[i/pageSize].Offset != i/pageSize*pageSize {
+			t.Fatalf("Expected page %d at offset %d, got %d", i/pageSize, i/pageSize*pageSize, client.searches[i/pageSize].Offset)
+		}
+	}
+
+	// Nothing more is fetched at the end
+	m = press(t, m, end)
+	if len(client.searches) != 21 || m.cursor != 1004 || strings.Contains(m.View(), "Loading more") {
+		t.Errorf("Expected to rest at the last station without more requests, got cursor %d and %d requests", m.cursor, len(client.searches))
+	}
+
+	// Stations already listed are skipped
+	m.stationsPager = pager{next: 1005, more: true}
+	updated, _ = m.Update(stationsLoadedMsg{stations: m.stations[1000:], offset: 1005})
+	if got := len(updated.(Model).stations); got != 1005 {
+		t.Errorf("Expected duplicates to be dropped, got %d stations", got)
+	}
+}
+
+func TestBrowsePagesSkipBrokenStations(t *testing.T) {
+	// Every third station failed its last check; the API leaves those out
+	// before paging when asked to hide them, as the real one does
+	var all []string
+	for i := 0; i < 3*pageSize; i++ {
+		all = append(all, fmt.Sprintf(`{"stationuuid": "s%d", "name": "Station %d", "url_resolved": "http://x/%d", "lastcheckok": %d}`, i, i, i, min(i%3, 1)))
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		rows := all
+		if q.Get("hidebroken") == "true" {
+			rows = nil
+			for _, row := range all {
+				if !strings.Contains(row, `"lastcheckok": 0`) {
+					rows = append(rows, row)
+				}
+			}
+		}
+		var offset, limit int
+		fmt.Sscan(q.Get("offset"), &offset)
+		fmt.Sscan(q.Get("limit"), &limit)
+		rows = rows[min(offset, len(rows)):min(offset+limit, len(rows))]
+		fmt.Fprint(w, "["+strings.Join(rows, ",")+"]")
+	}))
+	defer srv.Close()
+
+	m := NewModel(radiobrowser.NewAPIClientForServer(srv.URL), player.NewRemotePlayer(nil), nil, "en")
+	m.width, m.height = 80, 30
+	updated, _ := m.Update(m.loadStations(0)())
+	m = updated.(Model)
+	for i := 0; m.stationsPager.more; i++ {
+		if i == 10 {
+			t.Fatalf("Expected paging to stop, got %d stations", len(m.stations))
+		}
+		m = press(t, m, tea.KeyMsg{Type: tea.KeyEnd})
+	}
+
+	// Full pages keep paging going, and each station comes once
+	if len(m.stations) != 2*pageSize {
+		t.Fatalf("Expected all %d working stations, got %d", 2*pageSize, len(m.stations))
+	}
+	seen := make(map[string]bool)
+	for _, station := range m.stations {
+		if seen[station.StationUUID] || station.LastCheckOK != 1 {
+			t.Fatalf("Expected each working station once, got %s", station.Name)
+		}
+		seen[station.StationUUID] = true
+	}
+}
+
+func TestSearchLoadsMorePages(t *testing.T) {
+	client := &recordingClient{MockClient: radiobrowser.NewGeneratedMockClient(1000)}
+	m := NewModel(client, player.NewRemotePlayer(nil), nil, "en")
+	m.width, m.height = 80, 30
+	tab := tea.KeyMsg{Type: tea.KeyTab}
+	down := tea.KeyMsg{Type: tea.KeyDown}
+
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	m.searchInput.SetValue("tag:mock cc:JP")
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = press(t, m, tab)
+	if len(m.searchResults) != pageSize {
+		t.Fatalf("Expected a first page of %d results, got %d", pageSize, len(m.searchResults))
+	}
+
+	for i := 0; i < 45; i++ {
+		m = press(t, m, down)
+	}
+	if len(m.searchResults) != 2*pageSize || m.searchCursor != 45 {
+		t.Fatalf("Expected a second page with the cursor kept at 45, got %d results and cursor %d", len(m.searchResults), m.searchCursor)
+	}
+	last := client.searches[len(client.searches)-1]
+	if last.Offset != pageSize || last.Tag != "mock" || last.CountryCode != "JP" {
+		t.Errorf("Expected the same search at offset %d, got %+v", pageSize, last)
+	}
+
+	// Every sixth generated station is Japanese
+	for i := 0; m.searchPager.more; i++ {
+		if i == 1000 {
+			t.Fatalf("Expected paging to stop, got %d results", len(m.searchResults))
+		}
+		m = press(t, m, down)
+	}
+	if len(m.searchResults) != 166 {
+		t.Errorf("Expected 166 results, got %d", len(m.searchResults))
+	}
+
+	// Pages filtered to nothing are fetched only so many times in a row
+	client.searches = nil
+	m = press(t, m, tab)
+	m.searchInput.SetValue("tag:mock votes>5000")
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if len(client.searches) != maxBarrenPages || m.errorMsg != "No stations found" {
+		t.Errorf("Expected %d requests and no results, got %d and %q", maxBarrenPages, len(client.searches), m.errorMsg)
+	}
+}
+
+func TestFilterStations(t *testing.T) {
+	m := NewModel(radiobrowser.NewMockClient(), player.NewRemotePlayer(nil), nil, "en")
+	m.width, m.height = 80, 30
+	updated, _ := m.Update(m.loadStations(0)())
+	m = updated.(Model)
+
+	// Radio Italia matches by name, Jazz Radio by its instrumental tag
+	m = typeText(m, "Fitalq")
+	if !strings.Contains(m.browseFilter.input.Value(), "q") {
+		t.Fatalf("Expected q to be typed into the filter")
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	m = updated.(Model)
+	if m.browseLen() != 2 || m.SelectedStation().Name != "Radio Italia" || m.VisibleStationList()[1].Name != "Jazz Radio" {
+		t.Fatalf("Expected Radio Italia then Jazz Radio, got %+v", m.VisibleStationList())
+	}
+	match := m.browseFilter.match(0)
+	if fmt.Sprint(match.name) != "[6 7 8 9]" || match.country != nil {
+		t.Errorf("Expected the name to match at 6-9, got %v and %v", match.name, match.country)
+	}
+	if !strings.Contains(m.View(), "2 of 5 stations") {
+		t.Errorf("Expected the filter header to count the matches")
+	}
+
+	// The list is navigable while typing and after
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.browseFilter.typing || m.SelectedStation().Name != "Jazz Radio" {
+		t.Fatalf("Expected Jazz Radio selected after typing, got %+v", m.SelectedStation())
+	}
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")})
+	if m.cursor != 0 {
+		t.Errorf("Expected k to move up the matches, got cursor %d", m.cursor)
+	}
+
+	// Clearing the filter keeps the station selected
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.browseFilter.on() || m.browseLen() != 5 || m.cursor != 2 || m.SelectedStation().Name != "Radio Italia" {
+		t.Errorf("Expected Radio Italia selected in the full list, got cursor %d", m.cursor)
+	}
+
+	// A filter that matches nothing
+	m = typeText(m, "Fqqq")
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyPgDown})
+	if m.browseLen() != 0 || m.SelectedStation() != nil || m.cursor != 0 {
+		t.Errorf("Expected no matches and no selection, got cursor %d", m.cursor)
+	}
+}
+
+func TestFilterBookmarks(t *testing.T) {
+	bookmarks, _ := radiobrowser.NewMockClient().Search(radiobrowser.SearchParams{})
+	m := NewModel(radiobrowser.NewMockClient(), player.NewRemotePlayer(nil), nil, "en")
+	m.width, m.height = 80, 30
+	m.view = ViewBookmarks
+	updated, _ := m.Update(bookmarksLoadedMsg{bookmarks: bookmarks})
+	m = updated.(Model)
+
+	// Each word may match a different field
+	m = typeText(m, "Fclassic aus")
+	if m.bookmarksLen() != 1 || m.selectedBookmark().Name != "Classical Vienna" {
+		t.Fatalf("Expected Classical Vienna alone, got %d matches", m.bookmarksLen())
+	}
+	if match := m.bookmarksFilter.match(0); fmt.Sprint(match.name, match.country) != "[0 1 2 3 4 5 6] [0 1 2]" {
+		t.Errorf("Expected the name to match at 0-6 and the country at 0-2, got %v and %v", match.name, match.country)
+	}
+
+	for i := 0; i < 4; i++ {
+		updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+		m = updated.(Model)
+	}
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	if m.bookmarksLen() != 2 || m.selectedBookmark().Name != "Classical Vienna" {
+		t.Fatalf("Expected Classical Vienna second of two matches, got %d", m.bookmarksLen())
+	}
+
+	// Removing a bookmark keeps the one selected
+	remaining := append([]radiobrowser.Station{bookmarks[0]}, bookmarks[2:]...)
+	updated, _ = m.Update(bookmarksLoadedMsg{bookmarks: remaining})
+	m = updated.(Model)
+	if m.bookmarksLen() != 1 || m.bookmarksCursor != 0 || m.selectedBookmark().Name != "Classical Vienna" {
+		t.Fatalf("Expected Classical Vienna still selected, got cursor %d", m.bookmarksCursor)
+	}
+	if !strings.Contains(m.View(), "1 of 4 stations") {
+		t.Errorf("Expected the filter header to count the matches")
+	}
+
+	// esc clears the filter, then leaves
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.bookmarksFilter.on() || m.view != ViewBookmarks || m.bookmarksCursor != 3 {
+		t.Errorf("Expected the filter cleared with Classical Vienna selected, got cursor %d", m.bookmarksCursor)
+	}
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.view != ViewBrowse {
+		t.Errorf("Expected esc to go back to browsing")
+	}
+}
+
+func TestFilterDoesNotLoadMore(t *testing.T) {
+	client := &recordingClient{MockClient: radiobrowser.NewGeneratedMockClient(200)}
+	m := NewModel(client, player.NewRemotePlayer(nil), nil, "en")
+	m.width, m.height = 80, 30
+	updated, _ := m.Update(m.loadStations(0)())
+	m = updated.(Model)
+
+	m = typeText(m, "Fjazz")
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEnd})
+	if len(client.searches) != 1 || len(m.stations) != pageSize {
+		t.Errorf("Expected no more pages while filtering, got %d requests", len(client.searches))
+	}
+
+	// A page arriving while filtering is filtered too
+	selected := m.SelectedStation().StationUUID
+	updated, _ = m.Update(m.loadStations(pageSize)())
+	m = updated.(Model)
+	if m.SelectedStation().StationUUID != selected {
+		t.Errorf("Expected the selection kept as a page arrived")
+	}
+	for row := 0; row < m.browseLen(); row++ {
+		if station := m.stations[m.browseFilter.index(row)]; !strings.Contains(strings.ToLower(station.Name+station.Tags), "jazz") {
+			t.Errorf("Expected only jazz stations, got %s", station.Name)
+		}
+	}
+}
+
+func TestCycleSort(t *testing.T) {
+	client := &recordingClient{MockClient: radiobrowser.NewMockClient()}
+	m := NewModel(client, player.NewRemotePlayer(nil), nil, "en")
+	m.width, m.height = 100, 30
+	sorts := []sortKey{m.browseSort}
+	updated, _ := m.Update(m.loadStations(0)())
+	m = updated.(Model)
+
+	// Browsing fetches the stations anew in each order
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	sorts = append(sorts, m.browseSort)
+	last := client.searches[len(client.searches)-1]
+	if last.Order != "clickcount" || !last.Reverse || m.loading || len(m.stations) != 5 {
+		t.Fatalf("Expected the stations by clicks, got %+v", last)
+	}
+	for i := 1; i < len(m.stations); i++ {
+		if m.stations[i-1].ClickCount < m.stations[i].ClickCount {
+			t.Errorf("Expected the most clicked first, got %+v", m.stations)
+		}
+	}
+	if !strings.Contains(m.View(), "Clicks▼") {
+		t.Errorf("Expected the clicks column marked as sorted by")
+	}
+
+	// A page in the order browsed before is ignored
+	updated, _ = m.Update(stationsLoadedMsg{stations: []radiobrowser.Station{{StationUUID: "old"}}, sort: sorts[0]})
+	if got := updated.(Model).stations; len(got) != 5 {
+		t.Errorf("Expected a page in the old order to be ignored, got %d stations", len(got))
+	}
+
+	for len(sorts) < len(sortKeys)+1 {
+		m = press(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+		sorts = append(sorts, m.browseSort)
+	}
+	if sorts[len(sortKeys)] != sorts[0] || sorts[3].column != "name" || !strings.Contains(m.View(), "Votes▼") {
+		t.Errorf("Expected the orders to come round again, got %v", sorts)
+	}
+
+	// Bookmarks are sorted as loaded, keeping the one selected
+	bookmarks, _ := radiobrowser.NewMockClient().Search(radiobrowser.SearchParams{})
+	m.view = ViewBookmarks
+	updated, _ = m.Update(bookmarksLoadedMsg{bookmarks: append([]radiobrowser.Station(nil), bookmarks...)})
+	m = updated.(Model)
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	selected := m.selectedBookmark().Name
+
+	var names []string
+	for i := 0; i <= len(sortKeys); i++ {
+		updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+		updated, _ = updated.Update(bookmarksLoadedMsg{bookmarks: append([]radiobrowser.Station(nil), bookmarks...)})
+		m = updated.(Model)
+		if m.selectedBookmark().Name != selected {
+			t.Errorf("Expected %s to stay selected %s, got %s", selected, m.bookmarksSort.label(), m.selectedBookmark().Name)
+		}
+		names = append(names, m.bookmarks[0].Name)
+	}
+	if names[3] != "Classic Rock FM" || m.errorMsg != "Bookmarks sorted as added" || m.bookmarks[0].Name != bookmarks[0].Name {
+		t.Errorf("Expected the bookmarks by name, then as added again, got %v and %q", names, m.errorMsg)
+	}
+}
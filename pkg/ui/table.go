@@ -0,0 +1,425 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+	"github.com/mattn/go-runewidth"
+)
+
+// Station table layout.
+const (
+	// rowIndent is the width before the first column: padding, the
+	// cursor and a space.
+	rowIndent = 4
+	// rowPadding is the width after the last column.
+	rowPadding = 2
+	// columnGap is the width between columns.
+	columnGap = 2
+)
+
+// column is a column of the station table.
+type column struct {
+	// name identifies the column in the configuration.
+	name  string
+	title string
+	// width is the least the column needs, title and sort mark included.
+	width int
+	// fill columns share the width the others leave, each up to
+	// maxWidth, if set.
+	fill     bool
+	maxWidth int
+	// right aligns the column to the right, as for numbers.
+	right bool
+	value func(radiobrowser.Station) string
+	// hits returns which runes of the value matched the list filter.
+	hits func(*stationMatch) []int
+}
+
+// columns are the columns the station table can show, in their default
+// order.
+var columns = []column{
+	{
+		name: "name", title: "Name", width: 12, fill: true, maxWidth: 40,
+		value: func(s radiobrowser.Station) string { return s.Name },
+		hits:  func(m *stationMatch) []int { return m.name },
+	},
+	{
+		name: "country", title: "Country", width: 8,
+		value: func(s radiobrowser.Station) string {
+			return countryFlag(s.CountryCode) + " " + strings.ToUpper(s.CountryCode)
+		},
+		hits: func(m *stationMatch) []int {
+			// The country matched; the code stands for it after the flag
+			if len(m.country) == 0 {
+				return nil
+			}
+			return []int{3, 4}
+		},
+	},
+	{
+		name: "codec", title: "Codec", width: 6,
+		value: func(s radiobrowser.Station) string { return s.Codec },
+	},
+	{
+		name: "bitrate", title: "kbps", width: 5, right: true,
+		value: func(s radiobrowser.Station) string { return formatCount(s.Bitrate) },
+	},
+	{
+		name: "votes", title: "Votes", width: 6, right: true,
+		value: func(s radiobrowser.Station) string { return formatCount(s.Votes) },
+	},
+	{
+		name: "clicks", title: "Clicks", width: 7, right: true,
+		value: func(s radiobrowser.Station) string { return formatCount(s.ClickCount) },
+	},
+	{
+		name: "tags", title: "Tags", width: 10, fill: true,
+		value: func(s radiobrowser.Station) string { return s.Tags },
+		hits:  func(m *stationMatch) []int { return m.tags },
+	},
+}
+
+// hideOrder lists the columns to hide, first to last, when the terminal
+// is too narrow for them all. The name is always shown.
+var hideOrder = []string{"tags", "clicks", "votes", "codec", "bitrate", "country"}
+
+// parseColumns returns the columns with names, in that order. The names
+// are those of config.ColumnNames, checked by config.ValidateColumns.
+func parseColumns(names []string) ([]column, error) {
+	var cols []column
+	for _, name := range names {
+		i := slices.IndexFunc(columns, func(col column) bool { return col.name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown column: %s", name)
+		}
+		cols = append(cols, columns[i])
+	}
+	return cols, nil
+}
+
+// sortKey is an order the station table can be sorted in.
+type sortKey struct {
+	// column is the name of the column sorted by.
+	column string
+	// order is the API's name for the order; reverse sorts it descending.
+	order   string
+	reverse bool
+}
+
+// sortKeys are the orders cycled through, in turn.
+var sortKeys = []sortKey{
+	{"votes", "votes", true},
+	{"clicks", "clickcount", true},
+	{"bitrate", "bitrate", true},
+	{"name", "name", false},
+	{"country", "country", false},
+	{"codec", "codec", false},
+}
+
+// next returns the order after k. With unsorted set, the zero sortKey,
+// meaning no order, comes between the last and the first.
+func (k sortKey) next(unsorted bool) sortKey {
+	for i, key := range sortKeys {
+		if key != k {
+			continue
+		}
+		if i+1 < len(sortKeys) {
+			return sortKeys[i+1]
+		}
+		if unsorted {
+			return sortKey{}
+		}
+	}
+	return sortKeys[0]
+}
+
+// label describes the order for the user.
+func (k sortKey) label() string {
+	if k.column == "" {
+		return "as added"
+	}
+	return "by " + k.column
+}
+
+// layoutColumns returns the widths of cols in a row width wide, 0 for the
+// columns hidden to make room.
+func layoutColumns(cols []column, width int) []int {
+	widths := make([]int, len(cols))
+	for i, col := range cols {
+		widths[i] = col.width
+	}
+
+	used := func() int {
+		total, shown := rowIndent+rowPadding, 0
+		for _, w := range widths {
+			if w > 0 {
+				total += w
+				shown++
+			}
+		}
+		if shown > 1 {
+			total += (shown - 1) * columnGap
+		}
+		return total
+	}
+
+	for _, name := range hideOrder {
+		if used() <= width {
+			break
+		}
+		for i, col := range cols {
+			if col.name == name {
+				widths[i] = 0
+			}
+		}
+	}
+
+	// Share out the width left, first up to each column's most...
+	spare := width - used()
+	for i, col := range cols {
+		if widths[i] == 0 || !col.fill || spare <= 0 {
+			continue
+		}
+		grow := spare
+		if col.maxWidth > 0 {
+			grow = min(grow, max(col.maxWidth-widths[i], 0))
+		}
+		widths[i] += grow
+		spare -= grow
+	}
+
+	// ...then the rest, or what is missing, to the name
+	for i, col := range cols {
+		if col.name == "name" {
+			widths[i] = max(widths[i]+spare, 1)
+		}
+	}
+	return widths
+}
+
+// renderTableHeader renders the column titles, marking the one sorted by,
+// if any.
+func (m Model) renderTableHeader(sorted sortKey) string {
+	widths := layoutColumns(m.columns, m.width)
+
+	var cells []string
+	for i, col := range m.columns {
+		if widths[i] == 0 {
+			continue
+		}
+		title := col.title
+		if col.name == sorted.column {
+			if sorted.reverse {
+				title += "▼"
+			} else {
+				title += "▲"
+			}
+		}
+		cells = append(cells, pad(truncate(title, widths[i]), widths[i], col.right))
+	}
+
+	row := strings.Repeat(" ", rowIndent) + strings.Join(cells, strings.Repeat(" ", columnGap)) + strings.Repeat(" ", rowPadding)
+	return styleColumnHeader.Render(row)
+}
+
+// renderStation renders a station as a row of the table, highlighting the
+// characters matching the list filter, if any.
+func (m Model) renderStation(station radiobrowser.Station, selected bool, match *stationMatch) string {
+	style, detail := styleStation, styleStationDetail
+	cursor := " "
+	if selected {
+		style, detail = styleStationSelected, styleStationDetail.Background(colorSelected)
+		cursor = "►"
+	}
+	// The parts are rendered separately so that the highlights keep the
+	// row's colors; the padding is the style's
+	plain := style.UnsetPadding()
+
+	var b strings.Builder
+	b.WriteString(plain.Render("  " + cursor + " "))
+
+	widths := layoutColumns(m.columns, m.width)
+	first := true
+	for i, col := range m.columns {
+		if widths[i] == 0 {
+			continue
+		}
+		if !first {
+			b.WriteString(detail.Render(strings.Repeat(" ", columnGap)))
+		}
+		first = false
+
+		cellStyle := detail
+		if col.name == "name" {
+			cellStyle = plain
+		}
+		var hits []int
+		if match != nil && col.hits != nil {
+			hits = col.hits(match)
+		}
+		b.WriteString(renderCell(col.value(station), widths[i], col.right, hits, cellStyle))
+	}
+
+	b.WriteString(detail.Render(strings.Repeat(" ", rowPadding)))
+	return b.String()
+}
+
+// renderCell renders text in a cell width wide, cut short if need be, with
+// the runes at hits highlighted.
+func renderCell(text string, width int, right bool, hits []int, style lipgloss.Style) string {
+	cut := truncate(text, width)
+	if cut != text {
+		// Only the runes kept can be highlighted, not the ellipsis
+		kept := utf8.RuneCountInString(cut) - 1
+		for len(hits) > 0 && hits[len(hits)-1] >= kept {
+			hits = hits[:len(hits)-1]
+		}
+	}
+
+	var spaces string
+	if n := width - textWidth(cut); n > 0 {
+		spaces = style.Render(strings.Repeat(" ", n))
+	}
+	if right {
+		return spaces + highlight(cut, hits, style)
+	}
+	return highlight(cut, hits, style) + spaces
+}
+
+// highlight renders text in style, with the runes at positions, which are
+// sorted, in styleMatch.
+func highlight(text string, positions []int, style lipgloss.Style) string {
+	if text == "" {
+		return ""
+	}
+	if len(positions) == 0 {
+		return style.Render(text)
+	}
+	matched := styleMatch.Inherit(style)
+
+	var b, run strings.Builder
+	inMatch := false
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		if inMatch {
+			b.WriteString(matched.Render(run.String()))
+		} else {
+			b.WriteString(style.Render(run.String()))
+		}
+		run.Reset()
+	}
+
+	i := 0
+	for _, r := range text {
+		for len(positions) > 0 && positions[0] < i {
+			positions = positions[1:]
+		}
+		hit := len(positions) > 0 && positions[0] == i
+		if hit != inMatch {
+			flush()
+			inMatch = hit
+		}
+		run.WriteRune(r)
+		i++
+	}
+	flush()
+	return b.String()
+}
+
+// sanitize turns C0 and C1 control characters into spaces. Station names,
+// stream titles and error messages come from remote servers; passed
+// through, their control characters would upset the layout or be run by
+// the terminal as escape sequences.
+func sanitize(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text)
+}
+
+// truncate cuts text to width, ending it with an ellipsis if cut. It
+// sanitizes text first.
+func truncate(text string, width int) string {
+	text = sanitize(text)
+
+	if textWidth(text) <= width {
+		return text
+	}
+	var b strings.Builder
+	w := 0
+	for _, r := range text {
+		rw := textWidth(string(r))
+		if w+rw > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		w += rw
+	}
+	b.WriteString("…")
+	return b.String()
+}
+
+// pad fills text out to width with spaces.
+func pad(text string, width int, right bool) string {
+	spaces := strings.Repeat(" ", max(width-textWidth(text), 0))
+	if right {
+		return spaces + text
+	}
+	return text + spaces
+}
+
+// textWidth returns how many cells text takes up in a terminal. Each half
+// of a flag counts as a cell of its own, as terminals show flags two cells
+// wide.
+func textWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		if isRegionalIndicator(r) {
+			width++
+			continue
+		}
+		width += runewidth.RuneWidth(r)
+	}
+	return width
+}
+
+// countryFlag returns the flag emoji for a two-letter country code, or two spaces.
+func countryFlag(code string) string {
+	if len(code) != 2 || !isLetter(code[0]) || !isLetter(code[1]) {
+		return "  "
+	}
+	code = strings.ToUpper(code)
+	return string([]rune{rune(code[0]-'A') + 0x1F1E6, rune(code[1]-'A') + 0x1F1E6})
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// formatCount formats a count to fit a narrow column: 1234, 123456,
+// 12.3M, or nothing for 0.
+func formatCount(n int) string {
+	switch {
+	case n <= 0:
+		return ""
+	case n < 1_000_000:
+		return strconv.Itoa(n)
+	default:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	}
+}
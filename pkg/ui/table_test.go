@@ -0,0 +1,226 @@
+package ui
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fulgidus/terminal-fm/internal/config"
+	"github.com/fulgidus/terminal-fm/pkg/services/player"
+	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+	"github.com/fulgidus/terminal-fm/pkg/services/storage"
+	"github.com/muesli/termenv"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// tableStations are stations with names and values that test the table's
+// limits.
+var tableStations = []radiobrowser.Station{
+	{StationUUID: "1", Name: "Radio Italia", CountryCode: "IT", Country: "Italy", Codec: "MP3", Bitrate: 128, Votes: 3421, ClickCount: 12034, Tags: "pop,italian,hits"},
+	{StationUUID: "2", Name: "東京 ジャズ ラジオ Tokyo Jazz Radio", CountryCode: "JP", Country: "Japan", Codec: "AAC", Bitrate: 320, Votes: 1234567, ClickCount: 98, Tags: "jazz,ジャズ,smooth jazz"},
+	{StationUUID: "3", Name: "Ünïcödé Rädiö Ñandú — the very long name of a station that goes on", CountryCode: "es", Country: "Spain", Codec: "OGG", Bitrate: 64, Tags: "latin,world,talk,news,community radio"},
+	{StationUUID: "4", Name: "No\tCountry\nAt All", Codec: "", Bitrate: 0, Votes: 7},
+}
+
+// renderTable renders the header and a row for each station, the second
+// selected, as seen in a terminal without colors.
+func renderTable(m Model, sorted sortKey) string {
+	lines := []string{m.renderTableHeader(sorted)}
+	for i, station := range tableStations {
+		lines = append(lines, m.renderStation(station, i == 1, nil))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestStationTableGolden(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.Ascii)
+
+	tests := []struct {
+		width   int
+		columns []string
+		sorted  sortKey
+	}{
+		{width: 30},
+		{width: 40},
+		{width: 60},
+		{width: 80, sorted: sortKeys[0]},
+		{width: 120, sorted: sortKeys[3]},
+		{width: 160, sorted: sortKeys[1]},
+		{width: 80, columns: []string{"votes", "name", "bitrate"}},
+	}
+
+	for _, tt := range tests {
+		name := fmt.Sprintf("width-%d", tt.width)
+		if tt.columns != nil {
+			name += "-" + strings.Join(tt.columns, "-")
+		}
+
+		t.Run(name, func(t *testing.T) {
+			m := NewModel(radiobrowser.NewMockClient(), player.NewRemotePlayer(nil), nil, "en")
+			m.width, m.height = tt.width, 30
+			if tt.columns != nil {
+				if err := m.SetColumns(tt.columns); err != nil {
+					t.Fatalf("Failed to set columns: %v", err)
+				}
+			}
+
+			got := renderTable(m, tt.sorted)
+			for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+				if w := textWidth(line); w != tt.width {
+					t.Errorf("Expected the header and rows %d wide, got %d: %q", tt.width, w, line)
+				}
+			}
+
+			path := filepath.Join("testdata", "table-"+name+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatalf("Failed to write %s: %v", path, err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read %s (run with -update to create it): %v", path, err)
+			}
+			if got != string(want) {
+				t.Errorf("Table at width %d differs from %s:\n%s", tt.width, path, got)
+			}
+		})
+	}
+}
+
+func TestLayoutColumns(t *testing.T) {
+	tests := []struct {
+		width int
+		want  []int
+	}{
+		// Everything at its least, with the name taking what is left
+		{72, []int{12, 8, 6, 5, 6, 7, 10}},
+		{80, []int{20, 8, 6, 5, 6, 7, 10}},
+		// The name stops growing at 40; the tags take the rest
+		{120, []int{40, 8, 6, 5, 6, 7, 30}},
+		// Tags go first, then clicks, votes and codec
+		{71, []int{23, 8, 6, 5, 6, 7, 0}},
+		{51, []int{12, 8, 6, 5, 6, 0, 0}},
+		{37, []int{14, 8, 0, 5, 0, 0, 0}},
+		// Too narrow for anything but a short name
+		{10, []int{4, 0, 0, 0, 0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.width), func(t *testing.T) {
+			got := layoutColumns(columns, tt.width)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSetColumns(t *testing.T) {
+	m := NewModel(radiobrowser.NewMockClient(), player.NewRemotePlayer(nil), nil, "en")
+
+	if err := m.SetColumns([]string{"name", "flag"}); err == nil {
+		t.Errorf("Expected an unknown column to be rejected")
+	}
+	if err := m.SetColumns([]string{"tags", "name"}); err != nil || len(m.columns) != 2 || m.columns[1].name != "name" {
+		t.Errorf("Expected tags then name, got %v", err)
+	}
+
+	// The configuration's default lists every column, in the same order
+	if err := m.SetColumns(config.New().UI.Columns); err != nil || len(m.columns) != len(columns) {
+		t.Fatalf("Expected the default columns to be all the table's, got %v", err)
+	}
+	for i, col := range m.columns {
+		if col.name != columns[i].name {
+			t.Errorf("Expected column %d to be %s, got %s", i, columns[i].name, col.name)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"Radio", 5, "Radio"},
+		{"Radio Italia", 8, "Radio I…"},
+		{"東京ジャズ", 6, "東京…"},
+		{"東京ジャズ", 5, "東京…"},
+		{"Ñandú Rädiö", 7, "Ñandú …"},
+		{"a\tb\nc", 5, "a b c"},
+		{"a\x1b[2Jb\u009bc", 8, "a [2Jb c"},
+		{"🇯🇵 JP", 5, "🇯🇵 JP"},
+	}
+
+	for _, tt := range tests {
+		got := truncate(tt.text, tt.width)
+		if got != tt.want || textWidth(got) > tt.width {
+			t.Errorf("Expected %q cut to %d to be %q, got %q", tt.text, tt.width, tt.want, got)
+		}
+	}
+}
+
+func TestViewStripsControlCharacters(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.Ascii)
+	evil := "Jazz\x1b]8888;1;9;PLAY;file:///etc/passwd\a\u009b2J"
+	m := NewModel(radiobrowser.NewMockClient(), player.NewRemotePlayer(nil), nil, "en")
+	m.width, m.height = 100, 30
+	m.loading = false
+	m.errorMsg = evil
+	m.facetCountry = evil
+	m.countries = []radiobrowser.Country{{Name: evil, Code: evil}}
+
+	for _, view := range []ViewState{ViewBrowse, ViewSearch, ViewBookmarks, ViewHistory, ViewFacets} {
+		m.view = view
+		out := m.View()
+		if strings.ContainsAny(out, "\x1b\a\u009b") {
+			t.Errorf("Expected view %v to hold no control characters, got %q", view, out)
+		}
+		if !strings.Contains(out, "Jazz ]8888;1;9;PLAY;file:///etc/passwd  2J") {
+			t.Errorf("Expected view %v to show the text with spaces for control characters, got %q", view, out)
+		}
+	}
+}
+
+func TestEntriesCutLongNames(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.Ascii)
+	name := strings.Repeat("東京ジャズ", 10)
+	m := NewModel(radiobrowser.NewMockClient(), player.NewRemotePlayer(nil), nil, "en")
+	m.countries = []radiobrowser.Country{{Name: name, Code: "JP"}}
+
+	for _, line := range []string{
+		m.renderHistoryEntry(storage.HistoryEntry{Station: radiobrowser.Station{Name: name}}, false),
+		m.renderFacetEntry(1, false),
+	} {
+		if !utf8.ValidString(line) || !strings.Contains(line, "東京ジャズ東京ジャズ東京ジャズ東京ジャ…") {
+			t.Errorf("Expected the name cut to 40 cells, got %q", line)
+		}
+	}
+}
+
+func TestRenderCellHighlights(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	defer lipgloss.SetColorProfile(termenv.Ascii)
+	style := lipgloss.NewStyle().Foreground(colorText)
+	matched := styleMatch.Inherit(style)
+
+	got := renderCell("smooth jazz", 12, false, []int{7, 8}, style)
+	want := style.Render("smooth ") + matched.Render("ja") + style.Render("zz") + style.Render(" ")
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// Matches cut off are not highlighted, nor is the ellipsis in their place
+	got = renderCell("smooth jazz", 8, true, []int{0, 7, 8}, style)
+	want = matched.Render("s") + style.Render("mooth …")
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -1,10 +1,14 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fulgidus/terminal-fm/pkg/query"
+	"github.com/fulgidus/terminal-fm/pkg/services/player"
 	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
 )
 
@@ -20,25 +24,96 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// Stations loaded successfully
 	case stationsLoadedMsg:
-		m.stations = msg.stations
-		m.loading = false
-		m.errorMsg = ""
-		if len(m.stations) > 0 {
+		if msg.sort != m.browseSort {
+			// A page in an order no longer browsed in
+			return m, nil
+		}
+		var selected string
+		if station := m.SelectedStation(); station != nil {
+			selected = station.StationUUID
+		}
+		if msg.offset == 0 {
+			m.stations = nil
+			m.stationsPager = pager{}
 			m.cursor = 0
+			m.scrollOffset = 0
+		} else if msg.offset != m.stationsPager.next {
+			// A page already loaded
+			return m, nil
 		}
-		return m, nil
+		before := len(m.stations)
+		m.stations = appendNew(m.stations, msg.stations)
+		m.stationsPager.loaded(msg.offset, len(msg.stations), pageSize, len(m.stations)-before)
+		if m.browseFilter.on() {
+			m.refilterBrowse(selected)
+		}
+		m.loading = false
+		m.errorMsg = ""
+		// Keep going if the page did not take the cursor far from the end
+		return m, m.loadMoreStations()
 
 	// Bookmarks loaded successfully
 	case bookmarksLoadedMsg:
+		var selected string
+		if station := m.selectedBookmark(); station != nil {
+			selected = station.StationUUID
+		}
 		m.bookmarks = msg.bookmarks
 		m.bookmarksLoading = false
+		radiobrowser.SortStations(m.bookmarks, m.bookmarksSort.order, m.bookmarksSort.reverse)
+		if m.bookmarksFilter.on() {
+			m.bookmarksFilter.apply(m.bookmarks)
+		}
+		// Keep the selected bookmark selected if it is still shown
+		if row, ok := m.bookmarksFilter.row(m.bookmarks, selected); ok {
+			m.bookmarksCursor = row
+			m.updateBookmarksScroll()
+		}
 		// Reset cursor if it's out of bounds
-		if m.bookmarksCursor >= len(m.bookmarks) {
+		if m.bookmarksCursor >= m.bookmarksLen() {
 			m.bookmarksCursor = 0
 			m.bookmarksScrollOffset = 0
 		}
 		return m, nil
 
+	// History loaded successfully
+	case historyLoadedMsg:
+		m.history = msg.history
+		m.historyLoading = false
+		// Reset cursor if it's out of bounds
+		if m.historyCursor >= len(m.history) {
+			m.historyCursor = 0
+			m.historyScrollOffset = 0
+		}
+		return m, nil
+
+	// Countries to browse by loaded
+	case countriesLoadedMsg:
+		m.countries = msg.countries
+		m.facetLoading = false
+		return m, nil
+
+	// Tags to browse by loaded
+	case tagsLoadedMsg:
+		m.tags = msg.tags
+		m.facetLoading = false
+		return m, nil
+
+	// Stations matching a country and tag loaded
+	case facetStationsLoadedMsg:
+		if msg.country != m.facetCountry || msg.tag != m.facetTag {
+			// Stations for a country and tag no longer picked
+			return m, nil
+		}
+		m.facetStations = msg.stations
+		m.facetLoading = false
+		if len(msg.stations) == 0 {
+			m.errorMsg = "No stations found"
+		} else {
+			m.errorMsg = ""
+		}
+		return m, nil
+
 	// Bookmark added
 	case bookmarkAddedMsg:
 		m.errorMsg = fmt.Sprintf("Added '%s' to bookmarks", msg.station.Name)
@@ -53,22 +128,61 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// Search results received
 	case searchResultsMsg:
-		m.searchResults = msg.results
+		if msg.seq != m.searchSeq {
+			// Results of a search that has been replaced
+			return m, nil
+		}
+		m.searchCancel = nil
 		m.searching = false
-		m.searchCursor = 0
-		m.searchScrollOffset = 0
-		if len(msg.results) == 0 {
+		offset := msg.query.Params.Offset
+		if offset == 0 {
+			m.searchResults = nil
+			m.searchCursor = 0
+			m.searchScrollOffset = 0
+		}
+		before := len(m.searchResults)
+		m.searchResults = appendNew(m.searchResults, msg.results)
+		m.searchPager.loaded(offset, msg.fetched, msg.query.Params.Limit, len(m.searchResults)-before)
+		m.lastSearch = msg.query
+
+		// Keep going if the page did not take the cursor far from the end
+		cmd := m.loadMoreSearchResults()
+		if len(m.searchResults) == 0 && cmd == nil {
 			m.errorMsg = "No stations found"
 		} else {
 			m.errorMsg = ""
 		}
-		return m, nil
+		return m, cmd
+
+	// Stream metadata changed
+	case metadataMsg:
+		current := m.player.GetCurrentStation()
+		if current == nil || current.StationUUID != msg.stationUUID {
+			// Stale update from a station that is no longer playing
+			return m, nil
+		}
+		m.nowPlaying = msg.metadata.StreamTitle
+		return m, waitForMetadata(msg.stationUUID, msg.updates)
+
+	// Player state changed; returning re-renders the status bar
+	case playerEventMsg:
+		if msg.event.Err != nil {
+			// Playback stopped on its own, e.g. the stream dropped
+			m.stopMetadata()
+			m.finishHistoryEntry()
+			m.errorMsg = m.tr.Tf("error.stream_lost", msg.event.Err)
+		}
+		return m, waitForPlayerEvent(m.playerEvents)
 
 	// Error occurred
 	case errMsg:
 		m.loading = false
 		m.bookmarksLoading = false
+		m.historyLoading = false
+		m.facetLoading = false
 		m.searching = false
+		m.stationsPager.loading = false
+		m.searchPager.loading = false
 		m.errorMsg = msg.Error()
 		return m, nil
 
@@ -85,6 +199,10 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Global shortcuts (work in all views)
 	switch msg.String() {
 	case "ctrl+c", "q":
+		if msg.String() == "q" && m.typingFilter() {
+			// A q typed into the filter
+			break
+		}
 		// Cleanup before quitting
 		m.Cleanup()
 		return m, tea.Quit
@@ -102,6 +220,10 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleHelpKeys(msg)
 	case ViewAbout:
 		return m.handleAboutKeys(msg)
+	case ViewHistory:
+		return m.handleHistoryKeys(msg)
+	case ViewFacets:
+		return m.handleFacetsKeys(msg)
 	}
 
 	return m, nil
@@ -109,6 +231,15 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // handleBrowseKeys handles keyboard input in the browse view.
 func (m Model) handleBrowseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.browseFilter.typing {
+		switch msg.String() {
+		case "up", "down", "pgup", "pgdown":
+			// Move through the matches while typing
+		default:
+			return m.handleBrowseFilterKeys(msg)
+		}
+	}
+
 	switch msg.String() {
 
 	// Navigation
@@ -120,11 +251,11 @@ func (m Model) handleBrowseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "down", "j":
-		if m.cursor < len(m.stations)-1 {
+		if m.cursor < m.browseLen()-1 {
 			m.cursor++
 			m.UpdateScroll()
 		}
-		return m, nil
+		return m, m.loadMoreStations()
 
 	case "pgup":
 		visible := m.VisibleStations()
@@ -138,11 +269,11 @@ func (m Model) handleBrowseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "pgdown":
 		visible := m.VisibleStations()
 		m.cursor += visible
-		if m.cursor >= len(m.stations) {
-			m.cursor = len(m.stations) - 1
+		if m.cursor >= m.browseLen() {
+			m.cursor = max(m.browseLen()-1, 0)
 		}
 		m.UpdateScroll()
-		return m, nil
+		return m, m.loadMoreStations()
 
 	case "home", "g":
 		m.cursor = 0
@@ -150,11 +281,11 @@ func (m Model) handleBrowseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "end", "G":
-		if len(m.stations) > 0 {
-			m.cursor = len(m.stations) - 1
+		if m.browseLen() > 0 {
+			m.cursor = m.browseLen() - 1
 		}
 		m.UpdateScroll()
-		return m, nil
+		return m, m.loadMoreStations()
 
 	// Actions
 	case "enter", " ":
@@ -164,68 +295,38 @@ func (m Model) handleBrowseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			currentStation := m.player.GetCurrentStation()
 			if currentStation != nil && currentStation.StationUUID == station.StationUUID {
 				// Stop if already playing this station
-				_ = m.player.Stop()
+				m.stopPlayback()
 			} else {
 				// Play the selected station
-				if err := m.player.Play(station); err != nil {
-					m.errorMsg = fmt.Sprintf("Failed to play: %v", err)
-				} else {
-					m.errorMsg = ""
-				}
+				return m, m.playStation(station)
 			}
 		}
 		return m, nil
 
 	case "s":
 		// Stop playback
-		_ = m.player.Stop()
+		m.stopPlayback()
 		m.errorMsg = ""
 		return m, nil
 
+	case "p":
+		// Pause/resume playback
+		m.togglePause()
+		return m, nil
+
 	case "=", "+":
-		// Increase volume
-		currentVol := m.player.GetVolume()
-		if currentVol < 100 {
-			_ = m.player.SetVolume(currentVol + 10)
-		}
+		m.adjustVolume(10)
 		return m, nil
 
 	case "-", "_":
-		// Decrease volume
-		currentVol := m.player.GetVolume()
-		if currentVol > 0 {
-			_ = m.player.SetVolume(currentVol - 10)
-		}
+		m.adjustVolume(-10)
 		return m, nil
 
 	case "a":
 		// Add/remove bookmark
 		station := m.SelectedStation()
-		if station != nil && m.store != nil {
-			// Check if already bookmarked
-			isBookmarked, err := m.store.IsBookmarked(station.StationUUID)
-			if err != nil {
-				m.errorMsg = fmt.Sprintf("Error checking bookmark: %v", err)
-				return m, nil
-			}
-
-			if isBookmarked {
-				// Remove bookmark
-				return m, func() tea.Msg {
-					if err := m.store.RemoveBookmark(station.StationUUID); err != nil {
-						return errMsg{err}
-					}
-					return bookmarkRemovedMsg{station.StationUUID}
-				}
-			} else {
-				// Add bookmark
-				return m, func() tea.Msg {
-					if err := m.store.AddBookmark(station); err != nil {
-						return errMsg{err}
-					}
-					return bookmarkAddedMsg{*station}
-				}
-			}
+		if station != nil {
+			return m, m.toggleBookmark(station)
 		}
 		return m, nil
 
@@ -236,12 +337,50 @@ func (m Model) handleBrowseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Load bookmarks when switching to bookmarks view
 		return m, m.loadBookmarks
 
+	case "r":
+		m.view = ViewHistory
+		m.historyLoading = true
+		// Load history when switching to history view
+		return m, m.loadHistory
+
+	case "c":
+		// Browse by country, then tag
+		m.view = ViewFacets
+		m.facetStep = stepCountry
+		m.errorMsg = ""
+		if m.countries == nil {
+			m.facetLoading = true
+			return m, m.loadCountries
+		}
+		return m, nil
+
+	case "o":
+		// Sort by the next column, fetching the stations anew in that order
+		m.browseSort = m.browseSort.next(false)
+		m.loading = true
+		m.errorMsg = ""
+		return m, m.loadStations(0)
+
+	case "F":
+		// Filter the stations loaded
+		m.browseFilter.typing = true
+		return m, m.browseFilter.input.Focus()
+
+	case "esc":
+		if m.browseFilter.on() {
+			m.clearBrowseFilter()
+			return m, m.loadMoreStations()
+		}
+		return m, nil
+
 	case "f", "/":
 		// 'f' for find (international keyboard friendly), '/' still works
 		m.view = ViewSearch
-		m.searchInput.Focus()
+		m.focusSearchField(fieldQuery)
 		m.searchInput.SetValue("")
+		m.searchErrColumn = 0
 		m.searchResults = []radiobrowser.Station{}
+		m.searchPager = pager{}
 		m.errorMsg = ""
 		return m, textinput.Blink
 
@@ -259,42 +398,144 @@ func (m Model) handleBrowseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// handleBrowseFilterKeys handles keys typed into the browse filter.
+func (m Model) handleBrowseFilterKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.clearBrowseFilter()
+		return m, m.loadMoreStations()
+	case "enter":
+		// Done typing; keys work on the matches again
+		m.browseFilter.stopTyping()
+		return m, nil
+	}
+
+	changed, cmd := m.browseFilter.update(msg)
+	if changed {
+		// Start from the best match
+		m.browseFilter.apply(m.stations)
+		m.cursor, m.scrollOffset = 0, 0
+	}
+	return m, cmd
+}
+
+// refilterBrowse re-applies the browse filter after the stations changed,
+// keeping the station with uuid selected if it still matches.
+func (m *Model) refilterBrowse(uuid string) {
+	m.browseFilter.apply(m.stations)
+	if row, ok := m.browseFilter.row(m.stations, uuid); ok {
+		m.cursor = row
+	} else if m.cursor >= m.browseLen() {
+		m.cursor = max(m.browseLen()-1, 0)
+	}
+	m.UpdateScroll()
+}
+
+// clearBrowseFilter turns the browse filter off, keeping the selected
+// station selected.
+func (m *Model) clearBrowseFilter() {
+	index := 0
+	if m.cursor < m.browseLen() {
+		index = m.browseFilter.index(m.cursor)
+	}
+	m.browseFilter.clear()
+	m.cursor = index
+	m.UpdateScroll()
+}
+
 // handleSearchKeys handles keyboard input in the search view.
 func (m Model) handleSearchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
-	// Handle text input first if focused (except for special keys)
-	if m.searchInput.Focused() {
+	// Keys that work wherever the focus is
+	switch msg.String() {
+	case "ctrl+t":
+		// Show/hide the advanced filters
+		m.searchAdvanced = !m.searchAdvanced
+		if !m.searchAdvanced && m.searchFocus != fieldResults {
+			return m, m.focusSearchField(fieldQuery)
+		}
+		return m, nil
+	case "tab":
+		return m, m.focusSearchField(m.nextSearchField(1))
+	case "shift+tab":
+		return m, m.focusSearchField(m.nextSearchField(-1))
+	}
+
+	// Handle the form first if focused (except for special keys)
+	if m.searchFocus != fieldResults {
 		switch msg.String() {
 		case "esc":
-			m.searchInput.Blur()
+			m.focusSearchField(fieldResults)
+			m.cancelSearch()
+			m.searching = false
 			m.view = ViewBrowse
 			return m, nil
 		case "enter":
 			// Execute search
-			query := m.searchInput.Value()
-			if query != "" {
+			q, err := m.searchQuery()
+			m.searchErrColumn = 0
+			if err != nil {
+				var queryErr *query.Error
+				if errors.As(err, &queryErr) {
+					m.searchErrColumn = queryErr.Column
+				}
+				m.errorMsg = err.Error()
+				return m, nil
+			}
+			if q.Params.Name != "" || !nameOnly(q.Params) || len(q.Filters) > 0 {
 				m.searching = true
 				m.errorMsg = ""
-				return m, func() tea.Msg {
-					return m.performSearch(query)
-				}
+				return m, m.startSearch(q)
 			}
 			return m, nil
-		case "tab":
-			// Switch focus to results
-			m.searchInput.Blur()
+		case "up", "down":
+			// Move between the fields of the form
+			if m.searchAdvanced {
+				direction := 1
+				if msg.String() == "up" {
+					direction = -1
+				}
+				if next := m.nextSearchField(direction); next != fieldResults {
+					return m, m.focusSearchField(next)
+				}
+			}
 			return m, nil
+		}
+
+		switch m.searchFocus {
+		case fieldOrder:
+			switch msg.String() {
+			case "right", " ":
+				m.searchOrder = (m.searchOrder + 1) % len(searchOrders)
+			case "left":
+				m.searchOrder = (m.searchOrder + len(searchOrders) - 1) % len(searchOrders)
+			}
+		case fieldReverse:
+			if msg.String() == " " || msg.String() == "left" || msg.String() == "right" {
+				m.searchReverse = !m.searchReverse
+			}
+		case fieldHTTPS:
+			if msg.String() == " " || msg.String() == "left" || msg.String() == "right" {
+				m.searchHTTPS = !m.searchHTTPS
+			}
 		default:
 			// Pass all other keys to the text input
-			m.searchInput, cmd = m.searchInput.Update(msg)
-			return m, cmd
+			input := m.searchFieldInput(m.searchFocus)
+			*input, cmd = input.Update(msg)
+			if m.searchFocus == fieldQuery {
+				// The mistake marked may have been edited away
+				m.searchErrColumn = 0
+			}
 		}
+		return m, cmd
 	}
 
-	// Handle navigation and commands when input is NOT focused
+	// Handle navigation and commands when the form is NOT focused
 	switch msg.String() {
 	case "esc":
+		m.cancelSearch()
+		m.searching = false
 		m.view = ViewBrowse
 		return m, nil
 
@@ -302,20 +543,10 @@ func (m Model) handleSearchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Play selected station from results
 		if len(m.searchResults) > 0 {
 			station := &m.searchResults[m.searchCursor]
-			if err := m.player.Play(station); err != nil {
-				m.errorMsg = fmt.Sprintf("Failed to play: %v", err)
-			} else {
-				m.errorMsg = ""
-			}
+			return m, m.playStation(station)
 		}
 		return m, nil
 
-	case "tab":
-		// Switch focus back to input
-		m.searchInput.Focus()
-		cmd = textinput.Blink
-		return m, cmd
-
 	case "up", "k":
 		if m.searchCursor > 0 {
 			m.searchCursor--
@@ -328,64 +559,204 @@ func (m Model) handleSearchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.searchCursor++
 			m.updateSearchScroll()
 		}
-		return m, nil
+		return m, m.loadMoreSearchResults()
 
 	case "s":
 		// Stop playback
-		_ = m.player.Stop()
+		m.stopPlayback()
 		m.errorMsg = ""
 		return m, nil
 
+	case "p":
+		// Pause/resume playback
+		m.togglePause()
+		return m, nil
+
 	case "=", "+":
-		// Increase volume
-		currentVol := m.player.GetVolume()
-		if currentVol < 100 {
-			_ = m.player.SetVolume(currentVol + 10)
-		}
+		m.adjustVolume(10)
 		return m, nil
 
 	case "-", "_":
-		// Decrease volume
-		currentVol := m.player.GetVolume()
-		if currentVol > 0 {
-			_ = m.player.SetVolume(currentVol - 10)
-		}
+		m.adjustVolume(-10)
 		return m, nil
 
 	case "a":
 		// Add/remove bookmark for selected result
-		if len(m.searchResults) > 0 && m.store != nil {
-			station := &m.searchResults[m.searchCursor]
+		if len(m.searchResults) > 0 {
+			return m, m.toggleBookmark(&m.searchResults[m.searchCursor])
+		}
+		return m, nil
+	}
 
-			// Check if already bookmarked
-			isBookmarked, err := m.store.IsBookmarked(station.StationUUID)
-			if err != nil {
-				m.errorMsg = fmt.Sprintf("Error checking bookmark: %v", err)
-				return m, nil
-			}
+	return m, cmd
+}
 
-			if isBookmarked {
-				// Remove bookmark
-				return m, func() tea.Msg {
-					if err := m.store.RemoveBookmark(station.StationUUID); err != nil {
-						return errMsg{err}
-					}
-					return bookmarkRemovedMsg{station.StationUUID}
-				}
-			} else {
-				// Add bookmark
-				return m, func() tea.Msg {
-					if err := m.store.AddBookmark(station); err != nil {
-						return errMsg{err}
-					}
-					return bookmarkAddedMsg{*station}
-				}
+// nextSearchField returns the field after the focused one in direction, 1
+// or -1, wrapping around. The advanced filters are skipped when hidden.
+func (m Model) nextSearchField(direction int) searchField {
+	next := m.searchFocus + searchField(direction)
+	switch {
+	case next < fieldQuery:
+		return fieldResults
+	case next > fieldResults:
+		return fieldQuery
+	case !m.searchAdvanced && next > fieldQuery && next < fieldResults:
+		if direction > 0 {
+			return fieldResults
+		}
+		return fieldQuery
+	}
+	return next
+}
+
+// focusSearchField moves the focus to field, returning a command to make
+// its cursor blink if it is a text input.
+func (m *Model) focusSearchField(field searchField) tea.Cmd {
+	m.searchFocus = field
+
+	m.searchInput.Blur()
+	for i := range m.searchFilters {
+		m.searchFilters[i].Blur()
+	}
+
+	if input := m.searchFieldInput(field); input != nil {
+		input.Focus()
+		return textinput.Blink
+	}
+	return nil
+}
+
+// searchFieldInput returns the text input of field, or nil if field is not
+// one.
+func (m *Model) searchFieldInput(field searchField) *textinput.Model {
+	switch {
+	case field == fieldQuery:
+		return &m.searchInput
+	case field >= fieldCountryCode && field < fieldOrder:
+		return &m.searchFilters[field-fieldCountryCode]
+	}
+	return nil
+}
+
+// toggleBookmark returns a command that bookmarks station, or removes its
+// bookmark if it has one.
+func (m *Model) toggleBookmark(station *radiobrowser.Station) tea.Cmd {
+	if m.store == nil {
+		return nil
+	}
+
+	// Check if already bookmarked
+	isBookmarked, err := m.store.IsBookmarked(station.StationUUID)
+	if err != nil {
+		m.errorMsg = fmt.Sprintf("Error checking bookmark: %v", err)
+		return nil
+	}
+
+	store := m.store
+	if isBookmarked {
+		// Remove bookmark
+		return func() tea.Msg {
+			if err := store.RemoveBookmark(station.StationUUID); err != nil {
+				return errMsg{err}
 			}
+			return bookmarkRemovedMsg{station.StationUUID}
 		}
-		return m, nil
 	}
 
-	return m, cmd
+	// Add bookmark
+	return func() tea.Msg {
+		if err := store.AddBookmark(station); err != nil {
+			return errMsg{err}
+		}
+		return bookmarkAddedMsg{*station}
+	}
+}
+
+// playStation starts playing station, records it in the listening history
+// and begins watching its stream metadata.
+func (m *Model) playStation(station *radiobrowser.Station) tea.Cmd {
+	m.finishHistoryEntry()
+
+	if err := m.player.Play(station); err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to play: %v", err)
+		return nil
+	}
+
+	m.errorMsg = ""
+	m.startHistoryEntry(station)
+	return m.watchMetadata(station)
+}
+
+// stopPlayback stops the player, its metadata watcher and the current
+// history entry.
+func (m *Model) stopPlayback() {
+	_ = m.player.Stop()
+	m.stopMetadata()
+	m.finishHistoryEntry()
+}
+
+// startHistoryEntry records that station started playing.
+// History is best effort and never blocks playback.
+func (m *Model) startHistoryEntry(station *radiobrowser.Station) {
+	if m.store == nil {
+		return
+	}
+
+	startedAt := time.Now()
+	id, err := m.store.StartHistoryEntry(station, startedAt)
+	if err != nil {
+		return
+	}
+
+	m.historyEntryID = id
+	m.historyStartedAt = startedAt
+}
+
+// finishHistoryEntry records the duration of the current history entry.
+func (m *Model) finishHistoryEntry() {
+	if m.store == nil || m.historyEntryID == 0 {
+		return
+	}
+
+	_ = m.store.FinishHistoryEntry(m.historyEntryID, time.Since(m.historyStartedAt))
+	m.historyEntryID = 0
+}
+
+// togglePause pauses the current playback, or resumes it if paused.
+func (m *Model) togglePause() {
+	var err error
+
+	switch m.player.GetState() {
+	case player.StatePlaying:
+		err = m.player.Pause()
+	case player.StatePaused:
+		err = m.player.Resume()
+	default:
+		return
+	}
+
+	if err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to pause: %v", err)
+	} else {
+		m.errorMsg = ""
+	}
+}
+
+// adjustVolume changes the volume by delta, within 0-100, and remembers it
+// for the user's next session.
+func (m *Model) adjustVolume(delta int) {
+	volume := m.player.GetVolume() + delta
+	if volume < 0 || volume > 100 {
+		return
+	}
+
+	if err := m.player.SetVolume(volume); err != nil {
+		return
+	}
+
+	if m.store != nil {
+		_ = m.store.SetVolume(volume)
+	}
 }
 
 // updateSearchScroll adjusts search scroll offset based on cursor position.
@@ -405,11 +776,35 @@ func (m *Model) updateSearchScroll() {
 
 // handleBookmarksKeys handles keyboard input in the bookmarks view.
 func (m Model) handleBookmarksKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.bookmarksFilter.typing {
+		switch msg.String() {
+		case "up", "down", "pgup", "pgdown":
+			// Move through the matches while typing
+		default:
+			return m.handleBookmarksFilterKeys(msg)
+		}
+	}
+
 	switch msg.String() {
 	case "esc", "b":
+		if msg.String() == "esc" && m.bookmarksFilter.on() {
+			m.clearBookmarksFilter()
+			return m, nil
+		}
 		m.view = ViewBrowse
 		return m, nil
 
+	case "o":
+		// Sort by the next column, or as added
+		m.bookmarksSort = m.bookmarksSort.next(true)
+		m.errorMsg = "Bookmarks sorted " + m.bookmarksSort.label()
+		return m, m.loadBookmarks
+
+	case "F":
+		// Filter the bookmarks
+		m.bookmarksFilter.typing = true
+		return m, m.bookmarksFilter.input.Focus()
+
 	// Navigation
 	case "up", "k":
 		if m.bookmarksCursor > 0 {
@@ -419,7 +814,7 @@ func (m Model) handleBookmarksKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "down", "j":
-		if m.bookmarksCursor < len(m.bookmarks)-1 {
+		if m.bookmarksCursor < m.bookmarksLen()-1 {
 			m.bookmarksCursor++
 			m.updateBookmarksScroll()
 		}
@@ -437,8 +832,8 @@ func (m Model) handleBookmarksKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "pgdown":
 		visible := m.VisibleStations()
 		m.bookmarksCursor += visible
-		if m.bookmarksCursor >= len(m.bookmarks) {
-			m.bookmarksCursor = len(m.bookmarks) - 1
+		if m.bookmarksCursor >= m.bookmarksLen() {
+			m.bookmarksCursor = max(m.bookmarksLen()-1, 0)
 		}
 		m.updateBookmarksScroll()
 		return m, nil
@@ -449,8 +844,8 @@ func (m Model) handleBookmarksKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "end", "G":
-		if len(m.bookmarks) > 0 {
-			m.bookmarksCursor = len(m.bookmarks) - 1
+		if m.bookmarksLen() > 0 {
+			m.bookmarksCursor = m.bookmarksLen() - 1
 		}
 		m.updateBookmarksScroll()
 		return m, nil
@@ -458,50 +853,40 @@ func (m Model) handleBookmarksKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Actions
 	case "enter", " ":
 		// Play selected bookmark
-		if len(m.bookmarks) > 0 && m.bookmarksCursor < len(m.bookmarks) {
-			station := &m.bookmarks[m.bookmarksCursor]
+		if station := m.selectedBookmark(); station != nil {
 			currentStation := m.player.GetCurrentStation()
 			if currentStation != nil && currentStation.StationUUID == station.StationUUID {
 				// Stop if already playing this station
-				_ = m.player.Stop()
+				m.stopPlayback()
 			} else {
 				// Play the selected station
-				if err := m.player.Play(station); err != nil {
-					m.errorMsg = fmt.Sprintf("Failed to play: %v", err)
-				} else {
-					m.errorMsg = ""
-				}
+				return m, m.playStation(station)
 			}
 		}
 		return m, nil
 
 	case "s":
 		// Stop playback
-		_ = m.player.Stop()
+		m.stopPlayback()
 		m.errorMsg = ""
 		return m, nil
 
+	case "p":
+		// Pause/resume playback
+		m.togglePause()
+		return m, nil
+
 	case "=", "+":
-		// Increase volume
-		currentVol := m.player.GetVolume()
-		if currentVol < 100 {
-			_ = m.player.SetVolume(currentVol + 10)
-		}
+		m.adjustVolume(10)
 		return m, nil
 
 	case "-", "_":
-		// Decrease volume
-		currentVol := m.player.GetVolume()
-		if currentVol > 0 {
-			_ = m.player.SetVolume(currentVol - 10)
-		}
+		m.adjustVolume(-10)
 		return m, nil
 
 	case "a", "d":
 		// Remove bookmark (a for add/remove toggle, d for delete)
-		if len(m.bookmarks) > 0 && m.bookmarksCursor < len(m.bookmarks) && m.store != nil {
-			station := &m.bookmarks[m.bookmarksCursor]
-
+		if station := m.selectedBookmark(); station != nil && m.store != nil {
 			return m, func() tea.Msg {
 				if err := m.store.RemoveBookmark(station.StationUUID); err != nil {
 					return errMsg{err}
@@ -515,6 +900,39 @@ func (m Model) handleBookmarksKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// handleBookmarksFilterKeys handles keys typed into the bookmarks filter.
+func (m Model) handleBookmarksFilterKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.clearBookmarksFilter()
+		return m, nil
+	case "enter":
+		// Done typing; keys work on the matches again
+		m.bookmarksFilter.stopTyping()
+		return m, nil
+	}
+
+	changed, cmd := m.bookmarksFilter.update(msg)
+	if changed {
+		// Start from the best match
+		m.bookmarksFilter.apply(m.bookmarks)
+		m.bookmarksCursor, m.bookmarksScrollOffset = 0, 0
+	}
+	return m, cmd
+}
+
+// clearBookmarksFilter turns the bookmarks filter off, keeping the
+// selected bookmark selected.
+func (m *Model) clearBookmarksFilter() {
+	index := 0
+	if m.bookmarksCursor < m.bookmarksLen() {
+		index = m.bookmarksFilter.index(m.bookmarksCursor)
+	}
+	m.bookmarksFilter.clear()
+	m.bookmarksCursor = index
+	m.updateBookmarksScroll()
+}
+
 // updateBookmarksScroll adjusts bookmarks scroll offset based on cursor position.
 func (m *Model) updateBookmarksScroll() {
 	visible := m.VisibleStations()
@@ -530,6 +948,269 @@ func (m *Model) updateBookmarksScroll() {
 	}
 }
 
+// handleHistoryKeys handles keyboard input in the history view.
+func (m Model) handleHistoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "r":
+		m.view = ViewBrowse
+		return m, nil
+
+	// Navigation
+	case "up", "k":
+		if m.historyCursor > 0 {
+			m.historyCursor--
+			m.updateHistoryScroll()
+		}
+		return m, nil
+
+	case "down", "j":
+		if m.historyCursor < len(m.history)-1 {
+			m.historyCursor++
+			m.updateHistoryScroll()
+		}
+		return m, nil
+
+	case "pgup":
+		visible := m.VisibleStations()
+		m.historyCursor -= visible
+		if m.historyCursor < 0 {
+			m.historyCursor = 0
+		}
+		m.updateHistoryScroll()
+		return m, nil
+
+	case "pgdown":
+		visible := m.VisibleStations()
+		m.historyCursor += visible
+		if m.historyCursor >= len(m.history) {
+			m.historyCursor = max(len(m.history)-1, 0)
+		}
+		m.updateHistoryScroll()
+		return m, nil
+
+	case "home", "g":
+		m.historyCursor = 0
+		m.updateHistoryScroll()
+		return m, nil
+
+	case "end", "G":
+		if len(m.history) > 0 {
+			m.historyCursor = len(m.history) - 1
+		}
+		m.updateHistoryScroll()
+		return m, nil
+
+	// Actions
+	case "enter", " ":
+		// Replay selected station
+		if len(m.history) > 0 && m.historyCursor < len(m.history) {
+			station := m.history[m.historyCursor].Station
+			cmd := m.playStation(&station)
+			// Reload so the new play shows up at the top
+			return m, tea.Batch(cmd, m.loadHistory)
+		}
+		return m, nil
+
+	case "s":
+		// Stop playback
+		m.stopPlayback()
+		m.errorMsg = ""
+		return m, m.loadHistory
+
+	case "p":
+		// Pause/resume playback
+		m.togglePause()
+		return m, nil
+
+	case "=", "+":
+		m.adjustVolume(10)
+		return m, nil
+
+	case "-", "_":
+		m.adjustVolume(-10)
+		return m, nil
+	}
+
+	return m, nil
+}
+
+// updateHistoryScroll adjusts history scroll offset based on cursor position.
+func (m *Model) updateHistoryScroll() {
+	visible := m.VisibleStations()
+
+	// Scroll down if cursor is below visible area
+	if m.historyCursor >= m.historyScrollOffset+visible {
+		m.historyScrollOffset = m.historyCursor - visible + 1
+	}
+
+	// Scroll up if cursor is above visible area
+	if m.historyCursor < m.historyScrollOffset {
+		m.historyScrollOffset = m.historyCursor
+	}
+}
+
+// handleFacetsKeys handles keyboard input when browsing by country and tag.
+func (m Model) handleFacetsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	cursor := &m.facetCursors[m.facetStep]
+
+	switch msg.String() {
+	case "esc":
+		// Back to the previous step
+		m.errorMsg = ""
+		m.facetLoading = false
+		if m.facetStep == stepCountry {
+			m.view = ViewBrowse
+		} else {
+			m.facetStep--
+		}
+		return m, nil
+
+	// Navigation
+	case "up", "k":
+		if *cursor > 0 {
+			*cursor--
+			m.updateFacetScroll()
+		}
+		return m, nil
+
+	case "down", "j":
+		if *cursor < m.facetLen()-1 {
+			*cursor++
+			m.updateFacetScroll()
+		}
+		return m, nil
+
+	case "pgup":
+		visible := m.VisibleStations()
+		*cursor -= visible
+		if *cursor < 0 {
+			*cursor = 0
+		}
+		m.updateFacetScroll()
+		return m, nil
+
+	case "pgdown":
+		visible := m.VisibleStations()
+		*cursor += visible
+		if *cursor >= m.facetLen() {
+			*cursor = m.facetLen() - 1
+		}
+		if *cursor < 0 {
+			*cursor = 0
+		}
+		m.updateFacetScroll()
+		return m, nil
+
+	case "home", "g":
+		*cursor = 0
+		m.updateFacetScroll()
+		return m, nil
+
+	case "end", "G":
+		if m.facetLen() > 0 {
+			*cursor = m.facetLen() - 1
+		}
+		m.updateFacetScroll()
+		return m, nil
+
+	// Actions
+	case "enter", " ":
+		if m.facetLoading {
+			return m, nil
+		}
+
+		switch m.facetStep {
+		case stepCountry:
+			// The first entry is any country
+			m.facetCountry = ""
+			if *cursor > 0 {
+				m.facetCountry = m.countries[*cursor-1].Name
+			}
+			m.facetStep = stepTag
+			if m.tags == nil {
+				m.facetLoading = true
+				return m, m.loadTags
+			}
+			return m, nil
+
+		case stepTag:
+			// The first entry is every tag
+			m.facetTag = ""
+			if *cursor > 0 {
+				m.facetTag = m.tags[*cursor-1].Name
+			}
+			m.facetStep = stepStations
+			m.facetStations = nil
+			m.facetCursors[stepStations] = 0
+			m.facetOffsets[stepStations] = 0
+			m.facetLoading = true
+			m.errorMsg = ""
+			return m, m.loadFacetStations(m.facetCountry, m.facetTag)
+
+		case stepStations:
+			// Play/stop selected station
+			if *cursor < len(m.facetStations) {
+				station := &m.facetStations[*cursor]
+				currentStation := m.player.GetCurrentStation()
+				if currentStation != nil && currentStation.StationUUID == station.StationUUID {
+					// Stop if already playing this station
+					m.stopPlayback()
+				} else {
+					// Play the selected station
+					return m, m.playStation(station)
+				}
+			}
+		}
+		return m, nil
+
+	case "s":
+		// Stop playback
+		m.stopPlayback()
+		m.errorMsg = ""
+		return m, nil
+
+	case "p":
+		// Pause/resume playback
+		m.togglePause()
+		return m, nil
+
+	case "=", "+":
+		m.adjustVolume(10)
+		return m, nil
+
+	case "-", "_":
+		m.adjustVolume(-10)
+		return m, nil
+
+	case "a":
+		// Add/remove bookmark for selected station
+		if m.facetStep == stepStations && *cursor < len(m.facetStations) {
+			return m, m.toggleBookmark(&m.facetStations[*cursor])
+		}
+		return m, nil
+	}
+
+	return m, nil
+}
+
+// updateFacetScroll adjusts the current facet step's scroll offset based on
+// its cursor position.
+func (m *Model) updateFacetScroll() {
+	visible := m.VisibleStations()
+	cursor := m.facetCursors[m.facetStep]
+	offset := &m.facetOffsets[m.facetStep]
+
+	// Scroll down if cursor is below visible area
+	if cursor >= *offset+visible {
+		*offset = cursor - visible + 1
+	}
+
+	// Scroll up if cursor is above visible area
+	if cursor < *offset {
+		*offset = cursor
+	}
+}
+
 // handleHelpKeys handles keyboard input in the help view.
 func (m Model) handleHelpKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
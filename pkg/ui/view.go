@@ -3,10 +3,12 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/fulgidus/terminal-fm/pkg/services/player"
 	"github.com/fulgidus/terminal-fm/pkg/services/radiobrowser"
+	"github.com/fulgidus/terminal-fm/pkg/services/storage"
 )
 
 // Color scheme using Lipgloss.
@@ -58,6 +60,16 @@ var (
 	styleStationDetail = lipgloss.NewStyle().
 				Foreground(colorTextDim)
 
+	// Characters matching the list filter
+	styleMatch = lipgloss.NewStyle().
+			Foreground(colorWarning).
+			Bold(true)
+
+	// Station table column titles
+	styleColumnHeader = lipgloss.NewStyle().
+				Foreground(colorTextDim).
+				Bold(true)
+
 	// Footer with shortcuts
 	styleFooter = lipgloss.NewStyle().
 			Foreground(colorTextDim).
@@ -93,6 +105,10 @@ var (
 	styleStatusStopped = lipgloss.NewStyle().
 				Foreground(colorTextDim)
 
+	styleStatusPaused = lipgloss.NewStyle().
+				Foreground(colorAccent).
+				Bold(true)
+
 	styleStatusBuffering = lipgloss.NewStyle().
 				Foreground(colorWarning).
 				Bold(true)
@@ -119,6 +135,10 @@ func (m Model) View() string {
 		return m.viewHelp()
 	case ViewAbout:
 		return m.viewAbout()
+	case ViewHistory:
+		return m.viewHistory()
+	case ViewFacets:
+		return m.viewFacets()
 	default:
 		return "Unknown view"
 	}
@@ -142,6 +162,9 @@ func (m Model) viewBrowse() string {
 		header := styleLoading.Render(m.tr.T("station.loading"))
 		b.WriteString(header)
 		b.WriteString("\n")
+	} else if m.browseFilter.typing || m.browseFilter.on() {
+		b.WriteString(renderFilterHeader(m.browseFilter, len(m.stations)))
+		b.WriteString("\n")
 	} else {
 		header := styleHeader.Render(m.tr.Tf("station.found", len(m.stations)))
 		b.WriteString(header)
@@ -150,10 +173,11 @@ func (m Model) viewBrowse() string {
 
 	// Error message if any
 	if m.errorMsg != "" {
-		b.WriteString(styleError.Render(m.errorMsg))
+		b.WriteString(styleError.Render(sanitize(m.errorMsg)))
 		b.WriteString("\n")
 	}
 
+	b.WriteString(m.renderTableHeader(m.browseSort))
 	b.WriteString("\n")
 
 	// Station list
@@ -161,7 +185,8 @@ func (m Model) viewBrowse() string {
 		b.WriteString(m.renderStationList())
 	}
 
-	// Footer
+	// Footer, after a line showing whether more stations are on the way
+	b.WriteString(renderLoadingMore(m.stationsPager))
 	b.WriteString("\n")
 	b.WriteString(m.renderFooter())
 
@@ -178,35 +203,27 @@ func (m Model) renderStationList() string {
 		absoluteIndex := m.scrollOffset + i
 		isSelected := absoluteIndex == m.cursor
 
-		b.WriteString(m.renderStation(station, isSelected))
+		b.WriteString(m.renderStation(station, isSelected, m.browseFilter.match(absoluteIndex)))
 		b.WriteString("\n")
 	}
 
 	return b.String()
 }
 
-// renderStation renders a single station item.
-func (m Model) renderStation(station radiobrowser.Station, selected bool) string {
-	// Format: "► Station Name - Country | Bitrate kbps"
-	name := station.Name
-	if len(name) > 40 {
-		name = name[:37] + "..."
-	}
-
-	details := fmt.Sprintf("%s | %d kbps", station.Country, station.Bitrate)
-
-	cursor := " "
-	if selected {
-		cursor = "►"
+// renderLoadingMore renders a row saying the next page is being fetched,
+// or nothing.
+func renderLoadingMore(p pager) string {
+	if !p.loading {
+		return ""
 	}
+	return styleLoading.Render("   Loading more stations...")
+}
 
-	line := fmt.Sprintf("%s %s", cursor, name)
-	detailsPart := styleStationDetail.Render(details)
-
-	if selected {
-		return styleStationSelected.Render(line) + " " + detailsPart
-	}
-	return styleStation.Render(line) + " " + detailsPart
+// renderFilterHeader renders the header line of a filtered list of n
+// stations: the filter and how many stations match.
+func renderFilterHeader(f listFilter, n int) string {
+	count := fmt.Sprintf("%d of %d stations", f.len(n), n)
+	return " " + f.input.View() + "  " + styleStationDetail.Render(count)
 }
 
 // renderFooter renders the keyboard shortcuts footer.
@@ -216,9 +233,14 @@ func (m Model) renderFooter() string {
 		"↓/j down",
 		"enter play",
 		"s stop",
+		"p pause",
 		"+/- vol",
 		"b bookmarks",
+		"r history",
+		"c countries",
 		"f find",
+		"F filter",
+		"o sort",
 		"h help",
 		"i about",
 		"q quit",
@@ -245,8 +267,17 @@ func (m Model) viewSearch() string {
 	b.WriteString("\n")
 	b.WriteString(m.searchInput.View())
 	b.WriteString("\n")
-	b.WriteString(styleStationDetail.Render("Tip: Search by name, country (e.g., 'Italy', 'US'), or genre tag"))
-	b.WriteString("\n\n")
+	if m.searchErrColumn > 0 {
+		b.WriteString(m.renderQueryErrorMarker())
+		b.WriteString("\n")
+	}
+	if m.searchAdvanced {
+		b.WriteString(m.renderSearchFilters())
+	} else {
+		b.WriteString(styleStationDetail.Render("Tip: Search by name, or by fields like tag:jazz cc:IT codec:aac bitrate>=192 sort:clicks; ctrl+t for filters"))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
 
 	// Show searching status
 	if m.searching {
@@ -256,10 +287,18 @@ func (m Model) viewSearch() string {
 		// Show results count
 		header := styleHeader.Render(fmt.Sprintf("Found %d stations (Tab to navigate results)", len(m.searchResults)))
 		b.WriteString(header)
-		b.WriteString("\n\n")
+		b.WriteString("\n")
+		b.WriteString(m.renderTableHeader(sortKey{}))
+		b.WriteString("\n")
 
 		// Render results list
 		visible := m.VisibleStations() - 8 // Reserve space for input area
+		if m.searchAdvanced {
+			visible -= searchFilterLines
+		}
+		if m.searchErrColumn > 0 {
+			visible--
+		}
 		if visible < 1 {
 			visible = 1
 		}
@@ -271,8 +310,8 @@ func (m Model) viewSearch() string {
 
 		for i := m.searchScrollOffset; i < end; i++ {
 			station := m.searchResults[i]
-			isSelected := i == m.searchCursor && !m.searchInput.Focused()
-			b.WriteString(m.renderStation(station, isSelected))
+			isSelected := i == m.searchCursor && m.searchFocus == fieldResults
+			b.WriteString(m.renderStation(station, isSelected, nil))
 			b.WriteString("\n")
 		}
 	} else if m.searchInput.Value() != "" && !m.searching {
@@ -283,18 +322,77 @@ func (m Model) viewSearch() string {
 	// Error message if any
 	if m.errorMsg != "" {
 		b.WriteString("\n")
-		b.WriteString(styleError.Render(m.errorMsg))
+		b.WriteString(styleError.Render(sanitize(m.errorMsg)))
 		b.WriteString("\n")
 	}
 
 	// Footer
+	b.WriteString(renderLoadingMore(m.searchPager))
 	b.WriteString("\n")
-	shortcuts := "enter search/play • tab switch • ↑/↓ nav • s stop • +/- vol • a bookmark • h help • i about • esc back"
+	shortcuts := "enter search/play • tab switch • ↑/↓ nav • ctrl+t filters • s stop • p pause • +/- vol • a bookmark • esc back"
 	b.WriteString(styleFooter.Width(m.width).Render(shortcuts))
 
 	return b.String()
 }
 
+// renderQueryErrorMarker renders a caret under the mistake in the query.
+func (m Model) renderQueryErrorMarker() string {
+	query := []rune(m.searchInput.Value())
+	column := m.searchErrColumn - 1
+	if column > len(query) {
+		column = len(query)
+	}
+
+	// Line up with the input, past its prompt, unless it has scrolled
+	width := lipgloss.Width(string(query[:column]))
+	if width > m.searchInput.Width {
+		return ""
+	}
+	offset := lipgloss.Width(m.searchInput.Prompt) + width
+	return strings.Repeat(" ", offset) + lipgloss.NewStyle().Foreground(colorError).Bold(true).Render("^")
+}
+
+// searchFilterLines is the height of the advanced search filters.
+const searchFilterLines = int(fieldResults - fieldCountryCode)
+
+// renderSearchFilters renders the advanced search form below the query,
+// one field per line.
+func (m Model) renderSearchFilters() string {
+	var b strings.Builder
+
+	labels := []string{"Country code", "Language", "Tag", "Codec", "Min bitrate", "Max bitrate", "Order", "Descending", "HTTPS only"}
+	checkbox := func(checked bool) string {
+		if checked {
+			return "[x]"
+		}
+		return "[ ]"
+	}
+
+	for field := fieldCountryCode; field < fieldResults; field++ {
+		var value string
+		switch field {
+		case fieldOrder:
+			value = fmt.Sprintf("‹ %s ›", searchOrders[m.searchOrder])
+		case fieldReverse:
+			value = checkbox(m.searchReverse)
+		case fieldHTTPS:
+			value = checkbox(m.searchHTTPS)
+		default:
+			value = m.searchFilters[field-fieldCountryCode].View()
+		}
+
+		label := fmt.Sprintf("%-13s", labels[field-fieldCountryCode])
+		if field == m.searchFocus {
+			label = lipgloss.NewStyle().Foreground(colorPrimary).Bold(true).Render(label)
+		} else {
+			label = styleStationDetail.Render(label)
+		}
+		b.WriteString("  " + label + " " + value + "\n")
+	}
+
+	return b.String()
+}
+
 // viewBookmarks renders the bookmarks view.
 func (m Model) viewBookmarks() string {
 	var b strings.Builder
@@ -315,31 +413,234 @@ func (m Model) viewBookmarks() string {
 		b.WriteString(styleStationDetail.Render("Press 'a' on any station to bookmark it"))
 		b.WriteString("\n")
 	} else {
-		b.WriteString(styleHeader.Render(fmt.Sprintf("%d bookmarked stations", len(m.bookmarks))))
-		b.WriteString("\n\n")
+		if m.bookmarksFilter.typing || m.bookmarksFilter.on() {
+			b.WriteString(renderFilterHeader(m.bookmarksFilter, len(m.bookmarks)))
+		} else {
+			b.WriteString(styleHeader.Render(fmt.Sprintf("%d bookmarked stations", len(m.bookmarks))))
+		}
+		b.WriteString("\n")
+		b.WriteString(m.renderTableHeader(m.bookmarksSort))
+		b.WriteString("\n")
 
 		// Render bookmark list with scrolling
 		visible := m.VisibleStations()
 		end := m.bookmarksScrollOffset + visible
-		if end > len(m.bookmarks) {
-			end = len(m.bookmarks)
+		if end > m.bookmarksLen() {
+			end = m.bookmarksLen()
 		}
 
 		for i := m.bookmarksScrollOffset; i < end; i++ {
-			station := m.bookmarks[i]
+			station := m.bookmarks[m.bookmarksFilter.index(i)]
 			isSelected := i == m.bookmarksCursor
-			b.WriteString(m.renderStation(station, isSelected))
+			b.WriteString(m.renderStation(station, isSelected, m.bookmarksFilter.match(i)))
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\n")
+	shortcuts := "↑/↓ navigate • enter play • s stop • p pause • +/- vol • a/d remove • F filter • o sort • h help • i about • esc back"
+	b.WriteString(styleFooter.Width(m.width).Render(shortcuts))
+
+	return b.String()
+}
+
+// viewHistory renders the listening history view.
+func (m Model) viewHistory() string {
+	var b strings.Builder
+
+	b.WriteString(styleTitle.Render("♫ Recently Played"))
+	b.WriteString("\n")
+
+	// Status bar
+	b.WriteString(m.renderStatusBar())
+	b.WriteString("\n\n")
+
+	if m.historyLoading {
+		b.WriteString(styleLoading.Render("Loading history..."))
+		b.WriteString("\n")
+	} else if len(m.history) == 0 {
+		b.WriteString(styleHeader.Render("Nothing played yet"))
+		b.WriteString("\n")
+		b.WriteString(styleStationDetail.Render("Stations you play will show up here"))
+		b.WriteString("\n")
+	} else {
+		b.WriteString(styleHeader.Render(fmt.Sprintf("%d recent plays", len(m.history))))
+		b.WriteString("\n\n")
+
+		// Render history list with scrolling
+		visible := m.VisibleStations()
+		end := m.historyScrollOffset + visible
+		if end > len(m.history) {
+			end = len(m.history)
+		}
+
+		for i := m.historyScrollOffset; i < end; i++ {
+			isSelected := i == m.historyCursor
+			b.WriteString(m.renderHistoryEntry(m.history[i], isSelected))
+			b.WriteString("\n")
+		}
+	}
+
+	// Error message if any
+	if m.errorMsg != "" {
+		b.WriteString("\n")
+		b.WriteString(styleError.Render(sanitize(m.errorMsg)))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	shortcuts := "↑/↓ navigate • enter replay • s stop • p pause • +/- vol • esc back"
+	b.WriteString(styleFooter.Width(m.width).Render(shortcuts))
+
+	return b.String()
+}
+
+// viewFacets renders browsing by country and tag.
+func (m Model) viewFacets() string {
+	var b strings.Builder
+
+	b.WriteString(styleTitle.Render("♫ Browse by Country and Tag"))
+	b.WriteString("\n")
+
+	// Status bar
+	b.WriteString(m.renderStatusBar())
+	b.WriteString("\n\n")
+
+	// Where we are: the country and tag picked so far
+	country, tag := "Any country", "All tags"
+	if m.facetCountry != "" {
+		country = sanitize(m.facetCountry)
+	}
+	if m.facetTag != "" {
+		tag = sanitize(m.facetTag)
+	}
+
+	var header string
+	switch m.facetStep {
+	case stepCountry:
+		header = "Pick a country"
+	case stepTag:
+		header = fmt.Sprintf("%s › pick a tag", country)
+	case stepStations:
+		header = fmt.Sprintf("%s › %s › %d stations", country, tag, len(m.facetStations))
+	}
+
+	if m.facetLoading {
+		b.WriteString(styleLoading.Render("Loading..."))
+	} else {
+		b.WriteString(styleHeader.Render(header))
+	}
+	b.WriteString("\n")
+	if m.facetStep == stepStations && !m.facetLoading {
+		b.WriteString(m.renderTableHeader(sortKey{}))
+	}
+	b.WriteString("\n")
+
+	if !m.facetLoading {
+		// Render the current step's list with scrolling
+		visible := m.VisibleStations()
+		offset := m.facetOffsets[m.facetStep]
+		end := offset + visible
+		if end > m.facetLen() {
+			end = m.facetLen()
+		}
+
+		for i := offset; i < end; i++ {
+			isSelected := i == m.facetCursors[m.facetStep]
+			b.WriteString(m.renderFacetEntry(i, isSelected))
 			b.WriteString("\n")
 		}
 	}
 
+	// Error message if any
+	if m.errorMsg != "" {
+		b.WriteString("\n")
+		b.WriteString(styleError.Render(sanitize(m.errorMsg)))
+		b.WriteString("\n")
+	}
+
 	b.WriteString("\n")
-	shortcuts := "↑/↓ navigate • enter play • s stop • +/- vol • a/d remove • h help • i about • esc back"
+	shortcuts := "↑/↓ navigate • enter pick • esc back"
+	if m.facetStep == stepStations {
+		shortcuts = "↑/↓ navigate • enter play • s stop • p pause • +/- vol • a bookmark • esc back"
+	}
 	b.WriteString(styleFooter.Width(m.width).Render(shortcuts))
 
 	return b.String()
 }
 
+// renderFacetEntry renders entry i of the current facet step.
+func (m Model) renderFacetEntry(i int, selected bool) string {
+	if m.facetStep == stepStations {
+		return m.renderStation(m.facetStations[i], selected, nil)
+	}
+
+	// Format: "► Country Name CC | 1234 stations"
+	var name, details string
+	switch {
+	case i == 0 && m.facetStep == stepCountry:
+		name = "Any country"
+	case i == 0:
+		name = "All tags"
+	case m.facetStep == stepCountry:
+		country := m.countries[i-1]
+		name = country.Name
+		details = fmt.Sprintf("%s | %d stations", sanitize(country.Code), country.StationCount)
+	default:
+		tag := m.tags[i-1]
+		name = tag.Name
+		details = fmt.Sprintf("%d stations", tag.StationCount)
+	}
+
+	name = truncate(name, 40)
+
+	cursor := " "
+	if selected {
+		cursor = "►"
+	}
+
+	line := fmt.Sprintf("%s %s", cursor, name)
+	detailsPart := styleStationDetail.Render(details)
+
+	if selected {
+		return styleStationSelected.Render(line) + " " + detailsPart
+	}
+	return styleStation.Render(line) + " " + detailsPart
+}
+
+// renderHistoryEntry renders a single history item.
+func (m Model) renderHistoryEntry(entry storage.HistoryEntry, selected bool) string {
+	// Format: "► Station Name - Mon Jan 2 15:04 | 42m"
+	name := entry.Station.Name
+	name = truncate(name, 40)
+
+	duration := "playing"
+	if entry.Duration > 0 {
+		duration = entry.Duration.Round(time.Minute).String()
+		if entry.Duration < time.Minute {
+			duration = "<1m"
+		}
+		duration = strings.TrimSuffix(duration, "0s")
+	} else if entry.ID != m.historyEntryID {
+		duration = "-"
+	}
+
+	details := fmt.Sprintf("%s | %s", entry.StartedAt.Local().Format("Mon Jan 2 15:04"), duration)
+
+	cursor := " "
+	if selected {
+		cursor = "►"
+	}
+
+	line := fmt.Sprintf("%s %s", cursor, name)
+	detailsPart := styleStationDetail.Render(details)
+
+	if selected {
+		return styleStationSelected.Render(line) + " " + detailsPart
+	}
+	return styleStation.Render(line) + " " + detailsPart
+}
+
 // renderStatusBar renders the player status indicator.
 func (m Model) renderStatusBar() string {
 	var statusText, statusIcon string
@@ -352,7 +653,16 @@ func (m Model) renderStatusBar() string {
 		statusIcon = "[STREAMING]"
 		statusStyle = styleStatusPlaying
 		volume := m.tr.Tf("station.volume", m.player.GetVolume())
-		statusText = fmt.Sprintf("%s %s - %s", statusIcon, currentStation.Name, volume)
+		statusText = fmt.Sprintf("%s %s - %s", statusIcon, m.renderNowPlaying(currentStation), volume)
+	} else if currentStation != nil && playerState == player.StatePaused {
+		statusIcon = "[PAUSED]"
+		statusStyle = styleStatusPaused
+		volume := m.tr.Tf("station.volume", m.player.GetVolume())
+		statusText = fmt.Sprintf("%s %s - %s", statusIcon, m.renderNowPlaying(currentStation), volume)
+	} else if currentStation != nil && playerState == player.StateBuffering {
+		statusIcon = "[BUFFERING]"
+		statusStyle = styleStatusBuffering
+		statusText = fmt.Sprintf("%s %s", statusIcon, m.tr.Tf("station.reconnect", currentStation.Name))
 	} else if m.loading {
 		statusIcon = "[BUFFERING]"
 		statusStyle = styleStatusBuffering
@@ -367,7 +677,15 @@ func (m Model) renderStatusBar() string {
 		statusText = fmt.Sprintf("%s %s", statusIcon, m.tr.T("station.stopped"))
 	}
 
-	return styleStatusBar.Width(m.width - 2).Render(statusStyle.Render(statusText))
+	return styleStatusBar.Width(m.width - 2).Render(statusStyle.Render(sanitize(statusText)))
+}
+
+// renderNowPlaying formats the station name with the current track, if known.
+func (m Model) renderNowPlaying(station *radiobrowser.Station) string {
+	if m.nowPlaying == "" {
+		return station.Name
+	}
+	return fmt.Sprintf("%s ♪ %s", station.Name, m.nowPlaying)
 }
 
 // viewHelp renders the help screen with all keyboard shortcuts.
@@ -385,10 +703,15 @@ func (m Model) viewHelp() string {
 		{"↓ / j", "Move cursor down"},
 		{"Enter / Space", "Play selected station"},
 		{"s", "Stop playback"},
+		{"p", "Pause/Resume playback"},
 		{"+ / -", "Volume up/down"},
 		{"a", "Add/Remove bookmark"},
 		{"b", "Toggle bookmarks view"},
+		{"r", "Recently played stations"},
+		{"c", "Browse by country and tag"},
 		{"f", "Find/Search stations"},
+		{"F", "Filter the stations listed"},
+		{"o", "Sort by the next column"},
 		{"h", "Show this help"},
 		{"i", "About Terminal.FM"},
 		{"q / Ctrl+C", "Quit application"},